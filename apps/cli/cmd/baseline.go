@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/fatih/color"
+)
+
+// baselineMinDeltaMs is the smallest slowdown reported as a regression, so
+// jitter on very fast requests doesn't fail the run
+const baselineMinDeltaMs = 5
+
+// Baseline holds per-test durations committed to the repository
+type Baseline struct {
+	Tests map[string]BaselineEntry `json:"tests"`
+}
+
+// BaselineEntry is the recorded duration of a single test in milliseconds
+type BaselineEntry struct {
+	Duration float64 `json:"duration"`
+}
+
+// BaselineRegression is a test that got slower than its baseline allows
+type BaselineRegression struct {
+	Key      string
+	Baseline float64
+	Actual   float64
+	Change   float64 // percentage
+}
+
+// testKey identifies a test across runs, as "file::name"
+func testKey(file, name string) string {
+	return file + "::" + name
+}
+
+// buildBaseline records the duration of every passed test
+func buildBaseline(results []*runner.RunResult) *Baseline {
+	b := &Baseline{Tests: make(map[string]BaselineEntry)}
+	for _, result := range results {
+		for _, r := range result.Results {
+			if r.Skipped || !r.Passed || r.Error != nil {
+				continue
+			}
+			b.Tests[testKey(result.File, r.Name)] = BaselineEntry{
+				Duration: float64(r.Duration.Milliseconds()),
+			}
+		}
+	}
+	return b
+}
+
+func loadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid baseline file %s: %w", path, err)
+	}
+	return &b, nil
+}
+
+func writeBaseline(path string, b *Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// compareBaseline returns the passed tests whose duration exceeds the
+// baseline by more than tolerance percent. Tests missing from the baseline are
+// ignored.
+func compareBaseline(b *Baseline, results []*runner.RunResult, tolerance float64) []BaselineRegression {
+	var regressions []BaselineRegression
+	for _, result := range results {
+		for _, r := range result.Results {
+			if r.Skipped || !r.Passed || r.Error != nil {
+				continue
+			}
+			key := testKey(result.File, r.Name)
+			entry, ok := b.Tests[key]
+			if !ok {
+				continue
+			}
+			actual := float64(r.Duration.Milliseconds())
+			limit := entry.Duration * (1 + tolerance/100)
+			if actual > limit && actual-entry.Duration >= baselineMinDeltaMs {
+				change := 100.0
+				if entry.Duration > 0 {
+					change = (actual - entry.Duration) / entry.Duration * 100
+				}
+				regressions = append(regressions, BaselineRegression{
+					Key:      key,
+					Baseline: entry.Duration,
+					Actual:   actual,
+					Change:   change,
+				})
+			}
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		return regressions[i].Key < regressions[j].Key
+	})
+	return regressions
+}
+
+func printBaselineRegressions(w io.Writer, regressions []BaselineRegression, tolerance float64) {
+	red := color.New(color.FgRed).SprintFunc()
+	fmt.Fprintf(w, "\n%s\n", red(fmt.Sprintf("Baseline regressions (tolerance %.0f%%):", tolerance)))
+	for _, reg := range regressions {
+		fmt.Fprintf(w, "  %s %s  %.0fms → %.0fms (+%.1f%%)\n", red("✗"), reg.Key, reg.Baseline, reg.Actual, reg.Change)
+	}
+}
+
+// parseTolerance parses a percentage such as "20%" or "20"
+func parseTolerance(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid baseline tolerance %q (use a percentage like 20%%)", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
+)
+
+// changedFiles lists the files changed since the merge base of base and
+// HEAD, including uncommitted and untracked files, relative to the
+// repository root
+func changedFiles(base string) ([]string, error) {
+	mergeBase, err := git("merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := git("diff", "--name-only", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(diff+untracked, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// git runs a git command and returns its output
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// tagsFromChanged returns the tags that the config's tag map assigns to the
+// files changed since base
+func tagsFromChanged(cfg *config.Config, base string) ([]string, error) {
+	if len(cfg.TagMap) == 0 {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("--tags-from-changed requires a tagMap in the config file"))
+	}
+	files, err := changedFiles(base)
+	if err != nil {
+		return nil, fmt.Errorf("listing changed files: %w", err)
+	}
+	return cfg.TagsForPaths(files), nil
+}
@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/spf13/cobra"
 )
 
@@ -66,3 +69,59 @@ PowerShell:
 func init() {
 	rootCmd.AddCommand(completionCmd)
 }
+
+// completionRequests returns the requests of the files named in args, or of
+// the working directory when there are none. Files that don't parse are
+// skipped, since completion should never fail.
+func completionRequests(args []string) []*parser.Request {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files, err := collectFiles(args)
+	if err != nil {
+		return nil
+	}
+	var requests []*parser.Request
+	for _, file := range files {
+		f, err := parser.ParseFile(file)
+		if err != nil {
+			continue
+		}
+		requests = append(requests, f.Requests...)
+	}
+	return requests
+}
+
+// completeTags suggests the tags used in the files being run for --tags.
+// Tags already in the comma-separated list aren't suggested again.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix, current := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, current = toComplete[:i+1], toComplete[i+1:]
+	}
+	chosen := strings.Split(prefix, ",")
+
+	var candidates []string
+	for _, req := range completionRequests(args) {
+		for _, tag := range req.Tags {
+			if strings.HasPrefix(tag, current) && !slices.Contains(chosen, tag) && !slices.Contains(candidates, prefix+tag) {
+				candidates = append(candidates, prefix+tag)
+			}
+		}
+	}
+	slices.Sort(candidates)
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNames suggests the names of the requests in the files being run for
+// --name
+func completeNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var candidates []string
+	for _, req := range completionRequests(args) {
+		if req.Name != "" && strings.HasPrefix(req.Name, toComplete) && !slices.Contains(candidates, req.Name) {
+			candidates = append(candidates, req.Name)
+		}
+	}
+	slices.Sort(candidates)
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/output"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -38,27 +39,13 @@ func init() {
 	diffCmd.Flags().StringVar(&diffThresholdFlag, "threshold", "", "Fail if any test is slower by this percentage (e.g., 10%)")
 }
 
-// DiffJSONOutput represents the JSON structure of test results for comparison
-type DiffJSONOutput struct {
-	Summary  DiffJSONSummary `json:"summary"`
-	Tests    []DiffJSONTest  `json:"tests"`
-	Duration float64         `json:"duration"`
-}
+// DiffJSONOutput is the structure written by `hitspec run --output json`, so
+// result files can be compared without reshaping
+type DiffJSONOutput = output.JSONOutput
 
-type DiffJSONSummary struct {
-	Total   int `json:"total"`
-	Passed  int `json:"passed"`
-	Failed  int `json:"failed"`
-	Skipped int `json:"skipped"`
-}
+type DiffJSONSummary = output.JSONSummary
 
-type DiffJSONTest struct {
-	Name     string  `json:"name"`
-	File     string  `json:"file"`
-	Passed   bool    `json:"passed"`
-	Skipped  bool    `json:"skipped,omitempty"`
-	Duration float64 `json:"duration"`
-}
+type DiffJSONTest = output.JSONTest
 
 // DiffResult holds the comparison result
 type DiffResult struct {
@@ -141,6 +128,14 @@ func loadResultsFile(path string) (*DiffJSONOutput, error) {
 		return nil, err
 	}
 
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a JSON results file (create one with hitspec run --output json): %w", err)
+	}
+	if _, ok := raw["tests"]; !ok {
+		return nil, fmt.Errorf("missing \"tests\" field (create results with hitspec run --output json)")
+	}
+
 	var results DiffJSONOutput
 	if err := json.Unmarshal(data, &results); err != nil {
 		return nil, err
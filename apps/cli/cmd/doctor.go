@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [file|directory]...",
+	Short: "Diagnose configuration and environment problems",
+	Long: `Check that hitspec can run the given files (default: the current directory).
+
+doctor loads the config file, resolves the environment, parses the files,
+lists @require variables that aren't set, pings each base URL, and shows
+which optional integrations are configured. It exits with an error when a
+check fails.
+
+Examples:
+  hitspec doctor
+  hitspec doctor ./tests/ --env staging`,
+	RunE:         doctorCommand,
+	SilenceUsage: true, // Failed checks are already reported
+}
+
+var (
+	doctorEnvFlag     string
+	doctorEnvFileFlag string
+	doctorConfigFlag  string
+	doctorTimeoutFlag time.Duration
+)
+
+// baseURLNames are the variable names doctor pings, in order of preference
+var baseURLNames = []string{"baseUrl", "baseURL", "base_url"}
+
+// integrations are the optional services configured through the environment
+var integrations = []struct {
+	name string
+	env  string
+}{
+	{"Slack notifications", "SLACK_WEBHOOK"},
+	{"Teams notifications", "TEAMS_WEBHOOK"},
+	{"DataDog metrics", "DD_API_KEY"},
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorEnvFlag, "env", "e", getEnvString("HITSPEC_ENV", "dev"), "Environment to check (env: HITSPEC_ENV)")
+	doctorCmd.Flags().StringVar(&doctorEnvFileFlag, "env-file", getEnvString("HITSPEC_ENV_FILE", ""), "Path to .env file for variable interpolation (env: HITSPEC_ENV_FILE)")
+	doctorCmd.Flags().StringVar(&doctorConfigFlag, "config", getEnvString("HITSPEC_CONFIG", ""), "Path to config file (env: HITSPEC_CONFIG)")
+	doctorCmd.Flags().DurationVar(&doctorTimeoutFlag, "timeout", 5*time.Second, "Timeout for pinging base URLs")
+}
+
+// doctorReport prints check results and counts failures
+type doctorReport struct {
+	w        io.Writer
+	failures int
+}
+
+func (d *doctorReport) ok(check, format string, args ...any) {
+	d.print(color.New(color.FgGreen).Sprint("✓"), check, format, args...)
+}
+
+func (d *doctorReport) warn(check, format string, args ...any) {
+	d.print(color.New(color.FgYellow).Sprint("!"), check, format, args...)
+}
+
+func (d *doctorReport) fail(check, format string, args ...any) {
+	d.failures++
+	d.print(color.New(color.FgRed).Sprint("✗"), check, format, args...)
+}
+
+func (d *doctorReport) info(check, format string, args ...any) {
+	d.print("-", check, format, args...)
+}
+
+func (d *doctorReport) print(symbol, check, format string, args ...any) {
+	fmt.Fprintf(d.w, "  %s %s: %s\n", symbol, check, fmt.Sprintf(format, args...))
+}
+
+func doctorCommand(cmd *cobra.Command, args []string) error {
+	report := &doctorReport{w: cmd.OutOrStdout()}
+
+	cfg := doctorConfig(report)
+	doctorEnvironment(report, cfg)
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files, err := collectFiles(args)
+	if err != nil {
+		report.fail("files", "%v", err)
+	}
+	var parsed []*parser.File
+	for _, path := range files {
+		f, err := parser.ParseFile(path)
+		if err != nil {
+			report.fail("files", "%v", err)
+			continue
+		}
+		parsed = append(parsed, f)
+	}
+	switch {
+	case err == nil && len(files) == 0:
+		report.warn("files", "no .http or .hitspec files found in %s", strings.Join(args, ", "))
+	case len(parsed) > 0:
+		report.ok("files", "%d of %d parsed", len(parsed), len(files))
+	}
+
+	baseURLs := doctorVariables(report, cfg, parsed)
+	doctorPing(report, cfg, baseURLs)
+
+	for _, in := range integrations {
+		if os.Getenv(in.env) != "" {
+			report.ok(in.name, "configured")
+		} else {
+			report.info(in.name, "not configured (set %s)", in.env)
+		}
+	}
+
+	if report.failures > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", report.failures)
+	}
+	return nil
+}
+
+// doctorConfig loads the config file, falling back to the defaults
+func doctorConfig(report *doctorReport) *config.Config {
+	path := doctorConfigFlag
+	if path == "" {
+		for _, name := range config.ConfigFilenames {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	if path == "" {
+		report.info("config", "no %s found, using defaults", config.ConfigFilenames[0])
+		return config.DefaultConfig()
+	}
+
+	cfg, err := config.LoadConfig(path)
+	var cfgErr *config.ConfigError
+	if errors.As(err, &cfgErr) {
+		for _, problem := range cfgErr.Problems {
+			report.fail("config", "%s: %s", path, problem)
+		}
+		return cfg
+	}
+	if err != nil {
+		report.fail("config", "%s: %v", path, err)
+		return config.DefaultConfig()
+	}
+	report.ok("config", "loaded %s", path)
+	return cfg
+}
+
+// doctorEnvironment reports where the selected environment comes from: the
+// config file and the dotenv files in the current directory
+func doctorEnvironment(report *doctorReport, cfg *config.Config) {
+	if _, err := env.LoadEnvironment(".", doctorEnvFlag, cfg.Environments); err != nil {
+		report.fail("environment", "%v", err)
+		return
+	}
+
+	var sources []string
+	if _, ok := cfg.Environments[doctorEnvFlag]; ok {
+		sources = append(sources, "config")
+	}
+	for _, name := range []string{".env", ".env." + doctorEnvFlag, ".env.local"} {
+		if _, err := os.Stat(name); err == nil {
+			sources = append(sources, name)
+		}
+	}
+
+	switch {
+	case len(sources) > 0:
+		report.ok("environment", "%s (from %s)", doctorEnvFlag, strings.Join(sources, ", "))
+	case len(cfg.Environments) > 0:
+		names := make([]string, 0, len(cfg.Environments))
+		for name := range cfg.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		report.warn("environment", "%q isn't defined; the config has %s", doctorEnvFlag, strings.Join(names, ", "))
+	default:
+		report.info("environment", "%s has no config section or .env files", doctorEnvFlag)
+	}
+}
+
+// doctorVariables reports unset @require variables and returns the distinct
+// resolved base URLs of the files. Variables are resolved the way run does,
+// with the environment loaded from each file's directory.
+func doctorVariables(report *doctorReport, cfg *config.Config, files []*parser.File) []string {
+	base := env.NewResolver()
+	base.SetWarnFunc(func(string, ...any) {})
+	if doctorEnvFileFlag != "" {
+		if err := base.LoadDotEnv(doctorEnvFileFlag); err != nil {
+			report.fail("env file", "%v", err)
+		}
+	}
+
+	var baseURLs []string
+	seen := make(map[string]bool)
+	missingAny := false
+	for _, f := range files {
+		resolver := base.Clone()
+		environment, err := env.LoadEnvironment(filepath.Dir(f.Path), doctorEnvFlag, cfg.Environments)
+		if err != nil {
+			report.fail("environment", "%s: %v", f.Path, err)
+			continue
+		}
+		resolver.SetDotEnvLayers(environment.DotEnv)
+		resolver.SetVariables(environment.Variables)
+		for _, v := range f.Variables {
+			resolver.SetVariable(v.Name, v.Value)
+		}
+
+		if missing := runner.MissingRequired(f, resolver.IsDefined); len(missing) > 0 {
+			report.fail("variables", "%s requires %s", f.Path, strings.Join(missing, ", "))
+			missingAny = true
+		}
+
+		for _, name := range baseURLNames {
+			if !resolver.IsDefined(name) {
+				continue
+			}
+			u := resolver.Resolve("{{" + name + "}}")
+			if !seen[u] {
+				seen[u] = true
+				baseURLs = append(baseURLs, u)
+			}
+			break
+		}
+	}
+	if !missingAny && len(files) > 0 {
+		report.ok("variables", "all @require variables are set")
+	}
+	return baseURLs
+}
+
+// doctorPing sends a GET to each base URL; any response counts as reachable
+func doctorPing(report *doctorReport, cfg *config.Config, baseURLs []string) {
+	if len(baseURLs) == 0 {
+		report.info("base URL", "no %s variable found", strings.Join(baseURLNames, ", "))
+		return
+	}
+
+	opts := []http.ClientOption{
+		http.WithTimeout(doctorTimeoutFlag),
+		http.WithValidateSSL(cfg.GetValidateSSL()),
+	}
+	if cfg.Proxy != "" {
+		opts = append(opts, http.WithProxy(cfg.Proxy))
+	}
+	client := http.NewClient(opts...)
+
+	for _, u := range baseURLs {
+		if strings.Contains(u, "{{") {
+			report.fail("base URL", "%s has unresolved variables", u)
+			continue
+		}
+		start := time.Now()
+		resp, err := client.Get(u, cfg.Headers)
+		if err != nil {
+			report.fail("base URL", "%s is unreachable: %v", u, err)
+			continue
+		}
+		report.ok("base URL", "%s responded %d in %dms", u, resp.StatusCode, time.Since(start).Milliseconds())
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"gopkg.in/yaml.v3"
+)
+
+// effectiveConfig is the configuration a run uses once the config file,
+// environment variables and flags are merged, as printed by --dump-config.
+// Values of secret-looking names are masked.
+type effectiveConfig struct {
+	ConfigFile      string                       `json:"configFile" yaml:"configFile"` // "" when no config file was found
+	Environments    []string                     `json:"environments" yaml:"environments"`
+	EnvFile         string                       `json:"envFile,omitempty" yaml:"envFile,omitempty"`
+	NoEnv           bool                         `json:"noEnv" yaml:"noEnv"`
+	Timeout         string                       `json:"timeout" yaml:"timeout"`
+	FollowRedirects bool                         `json:"followRedirects" yaml:"followRedirects"`
+	MaxRedirects    int                          `json:"maxRedirects" yaml:"maxRedirects"`
+	ValidateSSL     bool                         `json:"validateSSL" yaml:"validateSSL"`
+	InsecureHosts   []string                     `json:"insecureHosts,omitempty" yaml:"insecureHosts,omitempty"`
+	Proxy           string                       `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	UserAgent       *string                      `json:"userAgent,omitempty" yaml:"userAgent,omitempty"` // Omitted when Go's default is sent
+	Parallel        bool                         `json:"parallel" yaml:"parallel"`
+	Concurrency     int                          `json:"concurrency" yaml:"concurrency"`
+	Bail            bool                         `json:"bail" yaml:"bail"`
+	RetryBudget     int                          `json:"retryBudget,omitempty" yaml:"retryBudget,omitempty"`
+	Headers         map[string]string            `json:"headers,omitempty" yaml:"headers,omitempty"`
+	HeadersByHost   map[string]map[string]string `json:"headersByHost,omitempty" yaml:"headersByHost,omitempty"`
+	SecureHeaders   map[string]string            `json:"secureHeaders,omitempty" yaml:"secureHeaders,omitempty"`
+	Variables       map[string]string            `json:"variables,omitempty" yaml:"variables,omitempty"` // From --var
+	EnvironmentVars map[string]map[string]string `json:"environmentVariables,omitempty" yaml:"environmentVariables,omitempty"`
+}
+
+// buildEffectiveConfig describes the runner configuration cfg that the run
+// built from the config file and the flags
+func buildEffectiveConfig(cfg *runner.Config, environments []string) *effectiveConfig {
+	maxRedirects := http.DefaultMaxRedirects
+	if cfg.MaxRedirects != nil {
+		maxRedirects = *cfg.MaxRedirects
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = runner.DefaultConcurrency
+	}
+
+	effective := &effectiveConfig{
+		ConfigFile:      configFilePath(configFlag),
+		Environments:    environments,
+		EnvFile:         cfg.EnvFile,
+		NoEnv:           cfg.NoEnv,
+		Timeout:         cfg.Timeout.String(),
+		FollowRedirects: cfg.FollowRedirect,
+		MaxRedirects:    maxRedirects,
+		ValidateSSL:     cfg.ValidateSSL,
+		InsecureHosts:   cfg.InsecureHosts,
+		Proxy:           cfg.Proxy,
+		UserAgent:       cfg.UserAgent,
+		Parallel:        cfg.Parallel,
+		Concurrency:     concurrency,
+		Bail:            cfg.Bail,
+		RetryBudget:     retryBudgetFlag,
+		Headers:         maskedMap(cfg.DefaultHeaders),
+		SecureHeaders:   cfg.SecureHeaders,
+		Variables:       maskedMap(cfg.Variables),
+	}
+	if len(cfg.HostHeaders) > 0 {
+		effective.HeadersByHost = make(map[string]map[string]string, len(cfg.HostHeaders))
+		for host, headers := range cfg.HostHeaders {
+			effective.HeadersByHost[host] = maskedMap(headers)
+		}
+	}
+	if len(cfg.ConfigEnvironments) > 0 {
+		effective.EnvironmentVars = make(map[string]map[string]string, len(cfg.ConfigEnvironments))
+		for name, vars := range cfg.ConfigEnvironments {
+			masked := make(map[string]string, len(vars))
+			for k, v := range vars {
+				masked[k] = env.MaskValue(k, v)
+			}
+			effective.EnvironmentVars[name] = masked
+		}
+	}
+	return effective
+}
+
+// writeEffectiveConfig writes the configuration as JSON when format is json,
+// and as YAML otherwise
+func writeEffectiveConfig(w io.Writer, effective *effectiveConfig, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(effective, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(effective); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// configFilePath returns the config file a run loads: the --config path, or
+// the first config file found in the current directory
+func configFilePath(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	for _, name := range config.ConfigFilenames {
+		if _, err := os.Stat(name); err == nil {
+			return filepath.Clean(name)
+		}
+	}
+	return ""
+}
+
+func maskedMap(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(values))
+	for k, v := range values {
+		masked[k] = env.MaskValue(k, v)
+	}
+	return masked
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
+)
+
+// runEnvironments returns the environments a run goes through: every
+// environment of the config file with --env-all, otherwise those listed in
+// --env, which may be a comma-separated list
+func runEnvironments(fileConfig *config.Config, all bool, env string) ([]string, error) {
+	if all {
+		names := make([]string, 0, len(fileConfig.Environments))
+		for name := range fileConfig.Environments {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("--env-all needs environments in the config file")
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(env, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		// An empty --env selects no environment, as it always has
+		names = []string{""}
+	}
+	return names, nil
+}
+
+// envSummary holds the totals of one environment of a multi-environment run
+type envSummary struct {
+	Name     string
+	Passed   int
+	Failed   int
+	Skipped  int
+	Duration time.Duration
+	Bailed   bool // The run stopped before this environment finished
+}
+
+// printEnvironmentSummary writes the totals of each environment of a run
+func printEnvironmentSummary(w io.Writer, summaries []envSummary) {
+	fmt.Fprintln(w, "\nEnvironments:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range summaries {
+		status := "PASS"
+		if s.Failed > 0 {
+			status = "FAIL"
+		}
+		note := ""
+		if s.Bailed {
+			note = "\t(stopped by --bail)"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d passed, %d failed, %d skipped\t%s%s\n",
+			s.Name, status, s.Passed, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond), note)
+	}
+	tw.Flush()
+}
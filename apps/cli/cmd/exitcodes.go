@@ -1,5 +1,14 @@
 package cmd
 
+import (
+	"errors"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+)
+
 // Exit codes for hitspec CLI
 const (
 	// ExitSuccess indicates all tests passed
@@ -17,6 +26,90 @@ const (
 	// ExitNetworkError indicates a network/connection error
 	ExitNetworkError = 4
 
+	// ExitThresholdFailure indicates exceeded stress thresholds or a
+	// performance regression against the baseline
+	ExitThresholdFailure = 5
+
 	// ExitUsageError indicates invalid CLI usage
 	ExitUsageError = 64
 )
+
+// exitError is an error that ends the process with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode makes err exit the process with code
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var exitErr *exitError
+	var parseErr *parser.ParseError
+	var cfgErr *config.ConfigError
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &parseErr):
+		return ExitParseError
+	case errors.As(err, &cfgErr):
+		return ExitConfigError
+	default:
+		return ExitTestFailure
+	}
+}
+
+// runStatus records what went wrong in a run, to pick its exit code
+type runStatus struct {
+	parseError   bool
+	networkError bool
+	testFailure  bool
+}
+
+// record notes the outcome of one file
+func (s *runStatus) record(result *runner.RunResult, err error) {
+	var parseErr *parser.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		s.parseError = true
+	case err != nil:
+		s.testFailure = true
+	default:
+		for _, r := range result.Results {
+			if !r.Passed && !r.Skipped && r.Error != nil && http.IsNetworkError(r.Error) {
+				s.networkError = true
+			}
+		}
+		if result.Failed > 0 {
+			s.testFailure = true
+		}
+	}
+}
+
+// code returns the exit code of the run, or ExitSuccess when nothing failed.
+// A file that doesn't parse outranks an unreachable server, which outranks
+// failed assertions; onFailure is the code for failed assertions.
+func (s *runStatus) code(onFailure int) int {
+	switch {
+	case s.parseError:
+		return ExitParseError
+	case s.networkError:
+		return ExitNetworkError
+	case s.testFailure:
+		return onFailure
+	default:
+		return ExitSuccess
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+)
+
+// FailureList is the JSON form of a failure list written by --list-failures
+type FailureList struct {
+	Tests []string `json:"tests"`
+}
+
+// collectFailures returns the keys of the tests that failed, in run order.
+// Soft failures and skipped tests are not included.
+func collectFailures(results []*runner.RunResult) []string {
+	var failures []string
+	for _, result := range results {
+		for _, r := range result.Results {
+			if r.Passed || r.SoftFailed || r.Skipped {
+				continue
+			}
+			failures = append(failures, testKey(result.File, r.Name))
+		}
+	}
+	return failures
+}
+
+// writeFailureList writes failures as JSON when path ends in .json, and as
+// one file::name per line otherwise
+func writeFailureList(path string, failures []string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if failures == nil {
+			failures = []string{}
+		}
+		encoded, err := json.MarshalIndent(FailureList{Tests: failures}, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(encoded, '\n')
+	} else {
+		for _, key := range failures {
+			data = append(data, key+"\n"...)
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadFailureList reads a failure list in either format written by
+// writeFailureList. Blank lines and # comments are ignored in the text format.
+func loadFailureList(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string]bool)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var list FailureList
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("invalid failure list %s: %w", path, err)
+		}
+		for _, key := range list.Tests {
+			failures[key] = true
+		}
+		return failures, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.Contains(line, "::") {
+			return nil, fmt.Errorf("invalid failure list %s: expected file::name, got %q", path, line)
+		}
+		failures[line] = true
+	}
+	return failures, scanner.Err()
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/output"
+)
+
+// writeHARFile writes the requests of a run accepted by filter to path
+func writeHARFile(path string, results []*runner.RunResult, filter output.HARFilter) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteHAR(f, results, filter, version); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
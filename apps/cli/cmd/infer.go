@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+)
+
+// printInferredAssertions writes an assertion block, ready to paste into the
+// test, for each request of result that got a response
+func printInferredAssertions(w io.Writer, result *runner.RunResult) {
+	for _, r := range result.Results {
+		if r.Response == nil {
+			continue
+		}
+		name := r.Name
+		if name == "" && r.Request != nil {
+			name = r.Request.Method + " " + r.Request.URL
+		}
+		fmt.Fprintf(w, "\n# Suggested assertions for %s (%s)\n", name, result.File)
+		fmt.Fprintf(w, ">>>\n%s\n<<<\n", strings.Join(assertions.Infer(r.Response), "\n"))
+	}
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
 	"github.com/spf13/cobra"
 )
 
@@ -14,11 +17,18 @@ var listCmd = &cobra.Command{
 
 Examples:
   hitspec list api.http
-  hitspec list ./tests/`,
+  hitspec list ./tests/
+  hitspec list ./tests/ --tree`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: listCommand,
 }
 
+var listTreeFlag bool
+
+func init() {
+	listCmd.Flags().BoolVar(&listTreeFlag, "tree", false, "Show requests as a tree of @depends relationships, flagging cycles")
+}
+
 func listCommand(cmd *cobra.Command, args []string) error {
 	files, err := collectFiles(args)
 	if err != nil {
@@ -37,11 +47,12 @@ func listCommand(cmd *cobra.Command, args []string) error {
 		}
 
 		fmt.Fprintf(cmd.OutOrStdout(), "\n%s:\n", file)
+		if listTreeFlag {
+			printDependencyTree(cmd.OutOrStdout(), f.Requests)
+			continue
+		}
 		for _, req := range f.Requests {
-			name := req.Name
-			if name == "" {
-				name = fmt.Sprintf("%s %s", req.Method, req.URL)
-			}
+			name := displayName(req)
 			fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", name)
 			if len(req.Tags) > 0 {
 				fmt.Fprintf(cmd.OutOrStdout(), "    tags: %v\n", req.Tags)
@@ -51,3 +62,59 @@ func listCommand(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// displayName names a request for listing
+func displayName(req *parser.Request) string {
+	if req.Name == "" {
+		return fmt.Sprintf("%s %s", req.Method, req.URL)
+	}
+	return req.Name
+}
+
+// printDependencyTree prints requests indented under the requests they
+// @depend on. A request depending on several others is printed in full under
+// the first and referenced under the rest; requests in a cycle are flagged.
+func printDependencyTree(w io.Writer, requests []*parser.Request) {
+	graph := runner.NewDependencyGraph(requests)
+	printed := make(map[string]bool)
+
+	var printNode func(key string, depth int, path []string)
+	printNode = func(key string, depth int, path []string) {
+		indent := strings.Repeat("  ", depth+1)
+		req := graph.Requests[key]
+		for i, p := range path {
+			if p == key {
+				cycle := append(append([]string{}, path[i:]...), key)
+				fmt.Fprintf(w, "%s- %s (cycle: %s)\n", indent, displayName(req), strings.Join(cycle, " -> "))
+				return
+			}
+		}
+		if printed[key] {
+			fmt.Fprintf(w, "%s- %s (see above)\n", indent, displayName(req))
+			return
+		}
+		printed[key] = true
+
+		line := indent + "- " + displayName(req)
+		if missing := graph.Missing[key]; len(missing) > 0 {
+			line += fmt.Sprintf(" (depends on %s, not in this file)", strings.Join(missing, ", "))
+		}
+		fmt.Fprintln(w, line)
+		for _, dependent := range graph.Dependents[key] {
+			printNode(dependent, depth+1, append(path, key))
+		}
+	}
+
+	for _, req := range requests {
+		if key := runner.RequestKey(req); graph.InDegree[key] == 0 && !printed[key] {
+			printNode(key, 0, nil)
+		}
+	}
+
+	// Requests left are only reachable through a cycle
+	for _, req := range requests {
+		if key := runner.RequestKey(req); !printed[key] {
+			printNode(key, 0, nil)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// newLogger returns the logger for --log-level, writing the decisions of the
+// runner (environment, retries, redirects, hooks) to w as key=value lines.
+// An empty level or "off" returns nil, which logs nothing.
+func newLogger(w io.Writer, level string) (*slog.Logger, error) {
+	switch strings.ToLower(level) {
+	case "", "off":
+		return nil, nil
+	case "warning":
+		level = "warn"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q (use debug, info, warn or error)", level)
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: l})), nil
+}
@@ -16,10 +16,12 @@ var (
 	mockPortFlag    int
 	mockDelayFlag   string
 	mockVerboseFlag bool
+	mockOpenAPIFlag string
+	mockJournalFlag int
 )
 
 var mockCmd = &cobra.Command{
-	Use:   "mock <file|directory>",
+	Use:   "mock [file|directory]",
 	Short: "Start a mock server based on hitspec files",
 	Long: `Start an HTTP mock server that responds based on the requests and assertions
 defined in your hitspec files.
@@ -30,12 +32,26 @@ The mock server:
 - Supports path parameters (e.g., /users/{{id}})
 - Can add artificial delays to simulate network latency
 
+With --from-openapi, the routes of an OpenAPI spec are served too, answering
+with the spec's examples or with responses generated from its schemas. Routes
+from hitspec files take precedence over those from the spec.
+
+The server records the requests it receives. GET /__hitspec/verify?method=POST&path=/payments
+returns how many matched, GET /__hitspec/requests lists them and
+DELETE /__hitspec/requests clears them.
+
 Examples:
   hitspec mock api.http
   hitspec mock api.http --port 3000
   hitspec mock api.http --port 3000 --delay 100ms
-  hitspec mock ./tests/ --verbose`,
-	Args: cobra.MinimumNArgs(1),
+  hitspec mock ./tests/ --verbose
+  hitspec mock --from-openapi openapi.yaml`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if mockOpenAPIFlag == "" {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return nil
+	},
 	RunE: mockCommand,
 }
 
@@ -43,6 +59,8 @@ func init() {
 	mockCmd.Flags().IntVarP(&mockPortFlag, "port", "p", 3000, "Port to run the mock server on")
 	mockCmd.Flags().StringVarP(&mockDelayFlag, "delay", "d", "0", "Delay to add to all responses (e.g., 100ms, 1s)")
 	mockCmd.Flags().BoolVarP(&mockVerboseFlag, "verbose", "v", false, "Enable verbose logging")
+	mockCmd.Flags().StringVar(&mockOpenAPIFlag, "from-openapi", "", "Serve the operations of this OpenAPI spec (file or URL) with example responses")
+	mockCmd.Flags().IntVar(&mockJournalFlag, "journal-size", mock.DefaultJournalSize, "Number of received requests kept for /__hitspec/verify; older ones are dropped (0 keeps all)")
 }
 
 func mockCommand(cmd *cobra.Command, args []string) error {
@@ -56,13 +74,17 @@ func mockCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if mockJournalFlag < 0 {
+		return fmt.Errorf("invalid journal size %d: must be 0 or more", mockJournalFlag)
+	}
+
 	// Collect files
 	files, err := collectFiles(args)
 	if err != nil {
 		return err
 	}
 
-	if len(files) == 0 {
+	if len(files) == 0 && mockOpenAPIFlag == "" {
 		return fmt.Errorf("no .http or .hitspec files found")
 	}
 
@@ -71,6 +93,7 @@ func mockCommand(cmd *cobra.Command, args []string) error {
 		mock.WithPort(mockPortFlag),
 		mock.WithDelay(delay),
 		mock.WithVerbose(mockVerboseFlag),
+		mock.WithJournalSize(mockJournalFlag),
 	)
 
 	// Load files
@@ -78,12 +101,24 @@ func mockCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load files: %w", err)
 	}
 
+	fileRoutes := len(server.GetRoutes())
+	if mockOpenAPIFlag != "" {
+		if err := server.LoadOpenAPI(mockOpenAPIFlag); err != nil {
+			return err
+		}
+	}
+
 	routes := server.GetRoutes()
 	if len(routes) == 0 {
 		return fmt.Errorf("no routes found in the provided files")
 	}
 
-	fmt.Printf("Loaded %d routes from %d files\n", len(routes), len(files))
+	if len(files) > 0 {
+		fmt.Printf("Loaded %d routes from %d files\n", fileRoutes, len(files))
+	}
+	if mockOpenAPIFlag != "" {
+		fmt.Printf("Loaded %d routes from %s\n", len(routes)-fileRoutes, mockOpenAPIFlag)
+	}
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
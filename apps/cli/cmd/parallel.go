@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+)
+
+// runFilesParallel runs files on up to workers goroutines. Each file gets its
+// own runner, so variables and captures never leak between files. handle is
+// called with each file's outcome in file order, as soon as that file and all
+// files before it have finished; when it returns false, files that haven't
+// started yet are not run.
+func runFilesParallel(cfg *runner.Config, files []string, workers int, handle func(*runner.RunResult, error) bool) {
+	type fileRun struct {
+		result *runner.RunResult
+		err    error
+	}
+
+	runs := make([]chan fileRun, len(files))
+	for i := range runs {
+		runs[i] = make(chan fileRun, 1)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		for i := range files {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	var stopped atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if stopped.Load() {
+					runs[i] <- fileRun{}
+					continue
+				}
+				result, err := runner.NewRunner(cfg).RunFile(files[i])
+				runs[i] <- fileRun{result: result, err: err}
+			}
+		}()
+	}
+
+	for i := range files {
+		run := <-runs[i]
+		if !handle(run.result, run.err) {
+			stopped.Store(true)
+			break
+		}
+	}
+	wg.Wait()
+}
+
+// serializePrompt makes prompt safe to call from concurrent file runs, so
+// prompts for missing variables don't interleave on the terminal
+func serializePrompt(prompt func(string) (string, error)) func(string) (string, error) {
+	var mu sync.Mutex
+	return func(name string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return prompt(name)
+	}
+}
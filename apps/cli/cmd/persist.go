@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+)
+
+// writePersistedCaptures merges the @persist captures of a run into the
+// .env file at path and reports their names, never their values, to w
+func writePersistedCaptures(w io.Writer, path string, results []*runner.RunResult) error {
+	captures := runner.PersistedCaptures(results)
+	if len(captures) == 0 {
+		fmt.Fprintf(w, "warning: no @persist captures to write to %s\n", path)
+		return nil
+	}
+
+	vars := make(map[string]string, len(captures))
+	names := make([]string, 0, len(captures))
+	for name, value := range captures {
+		s, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("capture %s: %w", name, err)
+			}
+			s = string(data)
+		}
+		vars[name] = s
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := env.WriteDotEnv(path, vars); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Persisted %s to %s\n", strings.Join(names, ", "), path)
+	return nil
+}
@@ -1,18 +1,24 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
 	"github.com/abdul-hamid-achik/hitspec/packages/export/metrics"
 	"github.com/abdul-hamid-achik/hitspec/packages/http"
@@ -40,8 +46,16 @@ Stress Testing Mode:
   hitspec run api.http users.http --stress -d 1m -r 100
   hitspec run api.http --stress --vus 50 --think-time 1s
   hitspec run api.http --stress -d 1m -r 100 --threshold "p95<200ms,errors<0.1%"
-  hitspec run api.http --stress --profile load --env staging`,
-	Args: cobra.MinimumNArgs(1),
+  hitspec run api.http --stress --profile load --env staging
+
+Show the configuration a run would use:
+  hitspec run --dump-config --env staging`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if dumpConfigFlag {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: runCommand,
 }
 
@@ -53,22 +67,47 @@ const (
 var (
 	envFlag         string
 	envFileFlag     string
+	envAllFlag      bool
+	varFlags        []string
+	noEnvFlag       bool
+	interactiveFlag bool
+	stdinBodyFlag   bool
+	inferFlag       bool
 	nameFlag        string
 	tagsFlag        string
+	tagsChangedFlag string
+	sinceFlag       string
 	verboseFlag     int // 0=off, 1=-v, 2=-vv, 3=-vvv
 	quietFlag       bool
+	summaryFlag     bool
+	onlyFailedFlag  bool
 	bailFlag        bool
+	teardownFlag    string
 	timeoutFlag     string
+	maxRedirects    int
 	noColorFlag     bool
 	dryRunFlag      bool
 	outputFlag      string
 	outputFileFlag  string
+	junitDirFlag    string
+	junitSingleFlag bool
+	recordDirFlag   string
+	replayDirFlag   string
+	fixtureIgnore   []string
 	parallelFlag    bool
 	concurrencyFlag int
+	parallelFiles   int
+	retryBudgetFlag int
 	watchFlag       bool
+	watchClearFlag  bool
 	proxyFlag       string
+	userAgentFlag   string
 	insecureFlag    bool
+	insecureHosts   []string
 	configFlag      string
+	strictConfig    bool
+	dumpConfigFlag  bool
+	failureExitCode int
 
 	// Stress testing flags
 	stressFlag           bool
@@ -82,6 +121,10 @@ var (
 	stressProfileFlag    string
 	stressNoProgressFlag bool
 	stressJSONFlag       bool
+	stressSeriesFlag     bool
+	stressShowEnvFlag    bool
+	stressPprofFlag      string
+	stressSampleFlag     float64
 
 	// Metrics flags
 	metricsFlag        string
@@ -100,34 +143,80 @@ var (
 
 	// Snapshot testing flags
 	updateSnapshotsFlag bool
+
+	// Baseline flags
+	baselineFlag          string
+	updateBaselineFlag    bool
+	baselineToleranceFlag string
+
+	// Failure list flags
+	listFailuresFlag string
+	retryFailedFlag  string
+
+	// Capture persistence flags
+	persistCapturesFlag string
+
+	// HAR export flags
+	harFlag       string
+	harFilterFlag string
+
+	// Logging flags
+	logLevelFlag string
 )
 
 func init() {
 	// Core flags
-	runCmd.Flags().StringVarP(&envFlag, "env", "e", getEnvString("HITSPEC_ENV", "dev"), "Environment to use (env: HITSPEC_ENV)")
+	runCmd.Flags().StringVarP(&envFlag, "env", "e", getEnvString("HITSPEC_ENV", "dev"), "Environment to use, or a comma-separated list to run against each in turn (env: HITSPEC_ENV)")
+	runCmd.Flags().BoolVar(&envAllFlag, "env-all", getEnvBool("HITSPEC_ENV_ALL", false), "Run the files against every environment of the config file in turn (env: HITSPEC_ENV_ALL)")
 	runCmd.Flags().StringVar(&envFileFlag, "env-file", getEnvString("HITSPEC_ENV_FILE", ""), "Path to .env file for variable interpolation (env: HITSPEC_ENV_FILE)")
+	runCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a variable, overriding file and environment values (repeatable, e.g. --var baseUrl=http://localhost:9000)")
+	runCmd.Flags().BoolVar(&noEnvFlag, "no-env", getEnvBool("HITSPEC_NO_ENV", false), "Ignore environments, .env files and the process environment; only file variables and --var apply (env: HITSPEC_NO_ENV)")
+	runCmd.Flags().BoolVar(&interactiveFlag, "interactive", false, "Prompt for missing @require variables (default when stdin is a terminal)")
+	runCmd.Flags().BoolVar(&stdinBodyFlag, "stdin-body", false, "Send stdin as the body of the request written with < - (only one request may use it)")
 	runCmd.Flags().StringVar(&configFlag, "config", getEnvString("HITSPEC_CONFIG", ""), "Path to config file (env: HITSPEC_CONFIG)")
+	runCmd.Flags().BoolVar(&dumpConfigFlag, "dump-config", false, "Print the configuration in effect after merging the config file, environment variables and flags (YAML, or JSON with -o json), then exit")
+	runCmd.Flags().BoolVar(&strictConfig, "strict-config", getEnvBool("HITSPEC_STRICT_CONFIG", false), "Fail on unknown keys or mistyped values in the config file instead of warning (env: HITSPEC_STRICT_CONFIG)")
+	runCmd.Flags().IntVar(&failureExitCode, "exit-code-on-failure", getEnvInt("HITSPEC_EXIT_CODE_ON_FAILURE", ExitTestFailure), "Exit code when tests fail; parse, network and threshold failures keep their own codes (env: HITSPEC_EXIT_CODE_ON_FAILURE)")
 	runCmd.Flags().StringVarP(&nameFlag, "name", "n", "", "Run only tests matching name pattern")
 	runCmd.Flags().StringVarP(&tagsFlag, "tags", "t", getEnvString("HITSPEC_TAGS", ""), "Run only tests with specified tags (comma-separated) (env: HITSPEC_TAGS)")
+	_ = runCmd.RegisterFlagCompletionFunc("name", completeNames)
+	_ = runCmd.RegisterFlagCompletionFunc("tags", completeTags)
+	runCmd.Flags().StringVar(&sinceFlag, "since", getEnvString("HITSPEC_SINCE", ""), "Run only files modified within this duration (e.g. 1h, 7d) or after this date (e.g. 2024-01-01), plus the files importing them (env: HITSPEC_SINCE)")
+	runCmd.Flags().StringVar(&tagsChangedFlag, "tags-from-changed", getEnvString("HITSPEC_TAGS_FROM_CHANGED", ""), "Run only tests with the tags that tagMap in the config assigns to files changed since this git ref (e.g. origin/main) (env: HITSPEC_TAGS_FROM_CHANGED)")
 
 	// Output flags
 	runCmd.Flags().CountVarP(&verboseFlag, "verbose", "v", "Verbose output (-v, -vv, -vvv for more detail)")
-	runCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", getEnvBool("HITSPEC_QUIET", false), "Suppress all output except errors (env: HITSPEC_QUIET)")
+	runCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", getEnvBool("HITSPEC_QUIET", false), "Suppress all output except errors and a one-line summary (env: HITSPEC_QUIET)")
+	runCmd.Flags().BoolVar(&summaryFlag, "summary", getEnvBool("HITSPEC_SUMMARY", false), "Print only failures and the final summary (console output) (env: HITSPEC_SUMMARY)")
+	runCmd.Flags().BoolVar(&onlyFailedFlag, "only-failures", getEnvBool("HITSPEC_ONLY_FAILURES", false), "Print only failed tests, with their details, and the final summary (console output) (env: HITSPEC_ONLY_FAILURES)")
 	runCmd.Flags().BoolVar(&noColorFlag, "no-color", getEnvBool("HITSPEC_NO_COLOR", false), "Disable colored output (env: HITSPEC_NO_COLOR)")
-	runCmd.Flags().StringVarP(&outputFlag, "output", "o", getEnvString("HITSPEC_OUTPUT", "console"), "Output format: console, json, junit, tap, html (env: HITSPEC_OUTPUT)")
+	runCmd.Flags().StringVarP(&outputFlag, "output", "o", getEnvString("HITSPEC_OUTPUT", "console"), "Output format: console, json, junit, tap, tap14, html (env: HITSPEC_OUTPUT)")
+	runCmd.Flags().BoolVar(&inferFlag, "infer-assertions", false, "Print suggested assertions (status, content type, field types) for each response")
 	runCmd.Flags().StringVar(&outputFileFlag, "output-file", getEnvString("HITSPEC_OUTPUT_FILE", ""), "Write output to file (default: stdout) (env: HITSPEC_OUTPUT_FILE)")
+	runCmd.Flags().StringVar(&junitDirFlag, "junit-dir", getEnvString("HITSPEC_JUNIT_DIR", ""), "Write a JUnit XML file per test file into this directory; implies --output junit (env: HITSPEC_JUNIT_DIR)")
+	runCmd.Flags().BoolVar(&junitSingleFlag, "junit-single-suite", getEnvBool("HITSPEC_JUNIT_SINGLE_SUITE", false), "Merge every file into a single JUnit <testsuite> instead of one per file (env: HITSPEC_JUNIT_SINGLE_SUITE)")
 
 	// Execution flags
 	runCmd.Flags().BoolVar(&bailFlag, "bail", getEnvBool("HITSPEC_BAIL", false), "Stop on first failure (env: HITSPEC_BAIL)")
+	runCmd.Flags().StringVar(&teardownFlag, "teardown", getEnvString("HITSPEC_TEARDOWN", ""), "Run this file after all others, even when --bail stops the run early (env: HITSPEC_TEARDOWN)")
 	runCmd.Flags().StringVar(&timeoutFlag, "timeout", getEnvString("HITSPEC_TIMEOUT", "30s"), "Request timeout (e.g., 30s, 1m) (env: HITSPEC_TIMEOUT)")
+	runCmd.Flags().IntVar(&maxRedirects, "max-redirects", getEnvInt("HITSPEC_MAX_REDIRECTS", 0), "Maximum redirects to follow before returning the redirect response; 0 follows none (default from config, or 10) (env: HITSPEC_MAX_REDIRECTS)")
 	runCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Parse and show what would run without executing")
 	runCmd.Flags().BoolVarP(&parallelFlag, "parallel", "p", getEnvBool("HITSPEC_PARALLEL", false), "Run requests in parallel (when no dependencies) (env: HITSPEC_PARALLEL)")
 	runCmd.Flags().IntVar(&concurrencyFlag, "concurrency", getEnvInt("HITSPEC_CONCURRENCY", 5), "Number of concurrent requests when running in parallel (env: HITSPEC_CONCURRENCY)")
+	runCmd.Flags().IntVar(&parallelFiles, "parallel-files", getEnvInt("HITSPEC_PARALLEL_FILES", 0), "Number of files to run concurrently, each with its own variables and captures (env: HITSPEC_PARALLEL_FILES)")
+	runCmd.Flags().IntVar(&retryBudgetFlag, "retry-budget", getEnvInt("HITSPEC_RETRY_BUDGET", 0), "Total retries allowed across all requests of the run; once spent, failures aren't retried (0 for no limit) (env: HITSPEC_RETRY_BUDGET)")
+	runCmd.Flags().StringVar(&recordDirFlag, "record", getEnvString("HITSPEC_RECORD", ""), "Record every response to this fixtures directory for --replay (env: HITSPEC_RECORD)")
+	runCmd.Flags().StringVar(&replayDirFlag, "replay", getEnvString("HITSPEC_REPLAY", ""), "Serve responses from a --record fixtures directory instead of the network (env: HITSPEC_REPLAY)")
+	runCmd.Flags().StringArrayVar(&fixtureIgnore, "fixture-ignore", nil, "Leave a request part out of --record/--replay fixture keys: body, body.<path> or query.<name> (repeatable)")
 	runCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "Watch files for changes and re-run tests")
+	runCmd.Flags().BoolVar(&watchClearFlag, "watch-clear", false, "Clear the terminal before each watch re-run and show a status header")
 
 	// Network flags
 	runCmd.Flags().StringVar(&proxyFlag, "proxy", getEnvString("HITSPEC_PROXY", ""), "Proxy URL for HTTP requests (env: HITSPEC_PROXY)")
+	runCmd.Flags().StringVar(&userAgentFlag, "user-agent", getEnvString("HITSPEC_USER_AGENT", ""), "User-Agent for all requests instead of Go's default; an empty value sends none (env: HITSPEC_USER_AGENT)")
 	runCmd.Flags().BoolVarP(&insecureFlag, "insecure", "k", getEnvBool("HITSPEC_INSECURE", false), "Disable SSL certificate validation (env: HITSPEC_INSECURE)")
+	runCmd.Flags().StringArrayVar(&insecureHosts, "insecure-host", nil, "Disable SSL certificate validation for this host only (repeatable)")
 
 	// Stress testing flags
 	runCmd.Flags().BoolVar(&stressFlag, "stress", false, "Enable stress testing mode")
@@ -141,6 +230,11 @@ func init() {
 	runCmd.Flags().StringVar(&stressProfileFlag, "profile", "", "Load stress profile from config")
 	runCmd.Flags().BoolVar(&stressNoProgressFlag, "no-progress", false, "Disable real-time progress display")
 	runCmd.Flags().BoolVar(&stressJSONFlag, "stress-json", false, "Output stress results as JSON")
+	runCmd.Flags().BoolVar(&stressSeriesFlag, "stress-timeseries", false, "Include the time series in --stress-json output")
+	runCmd.Flags().BoolVar(&stressShowEnvFlag, "show-env", false, "Show the environment and its variables (secrets masked) in the stress header")
+	runCmd.Flags().Float64Var(&stressSampleFlag, "assert-sample", 0, "Fraction of stress responses whose assertions are evaluated, counting failures as errors (e.g. 0.01)")
+	runCmd.Flags().StringVar(&stressPprofFlag, "pprof", "", "Serve Go pprof profiles of hitspec itself on this address during a stress test (e.g. :6060)")
+	_ = runCmd.Flags().MarkHidden("pprof")
 
 	// Metrics flags
 	runCmd.Flags().StringVar(&metricsFlag, "metrics", getEnvString("HITSPEC_METRICS", ""), "Metrics export format: prometheus, datadog, json (env: HITSPEC_METRICS)")
@@ -159,6 +253,183 @@ func init() {
 
 	// Snapshot testing flags
 	runCmd.Flags().BoolVar(&updateSnapshotsFlag, "update-snapshots", false, "Update snapshot files instead of comparing")
+
+	// Baseline flags
+	runCmd.Flags().StringVar(&baselineFlag, "baseline", getEnvString("HITSPEC_BASELINE", ""), "Fail tests slower than their duration in this baseline file (env: HITSPEC_BASELINE)")
+	runCmd.Flags().BoolVar(&updateBaselineFlag, "update-baseline", false, "Write test durations to the --baseline file instead of comparing")
+
+	// Failure list flags
+	runCmd.Flags().StringVar(&listFailuresFlag, "list-failures", "", "Write failed tests to this file as file::name lines (JSON when it ends in .json)")
+	runCmd.Flags().StringVar(&retryFailedFlag, "retry-failed", "", "Run only the tests listed in a --list-failures file, plus their dependencies")
+	runCmd.Flags().StringVar(&harFlag, "har", getEnvString("HITSPEC_HAR", ""), "Write the requests and responses of the run to this HAR file (env: HITSPEC_HAR)")
+	runCmd.Flags().StringVar(&harFilterFlag, "har-filter", getEnvString("HITSPEC_HAR_FILTER", ""), "Write only the requests matching any of these comma-separated conditions to --har: failure, success, duration>N (ms), status>=N (env: HITSPEC_HAR_FILTER)")
+	runCmd.Flags().StringVar(&logLevelFlag, "log-level", getEnvString("HITSPEC_LOG", ""), "Log the runner's decisions (environment, retries, redirects, hooks) to stderr at this level: debug, info, warn, error (env: HITSPEC_LOG)")
+	runCmd.Flags().StringVar(&persistCapturesFlag, "persist-captures", getEnvString("HITSPEC_PERSIST_CAPTURES", ""), "Write the captures of @persist requests to this .env file, for a later run's --env-file (env: HITSPEC_PERSIST_CAPTURES)")
+	runCmd.Flags().StringVar(&baselineToleranceFlag, "baseline-tolerance", getEnvString("HITSPEC_BASELINE_TOLERANCE", "20%"), "Allowed slowdown over the baseline duration (env: HITSPEC_BASELINE_TOLERANCE)")
+}
+
+// parseVarFlags parses repeated --var key=value flags
+func parseVarFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, found := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --var %q (use format key=value)", v)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// newFormatter creates the formatter selected by --output, writing to
+// outWriter, or stdout when it's nil. Watch mode calls it again for each run.
+func newFormatter(outWriter *os.File) Formatter {
+	switch strings.ToLower(outputFlag) {
+	case "json":
+		opts := []output.JSONOption{}
+		if outWriter != nil {
+			opts = append(opts, output.JSONWithWriter(outWriter))
+		}
+		return output.NewJSONFormatter(opts...)
+	case "junit":
+		opts := []output.JUnitOption{}
+		if outWriter != nil {
+			opts = append(opts, output.JUnitWithWriter(outWriter))
+		}
+		if junitSingleFlag {
+			opts = append(opts, output.JUnitWithSingleSuite())
+		}
+		if junitDirFlag != "" {
+			opts = append(opts, output.JUnitWithDir(junitDirFlag))
+		}
+		return output.NewJUnitFormatter(opts...)
+	case "tap", "tap14":
+		opts := []output.TAPOption{}
+		if outWriter != nil {
+			opts = append(opts, output.TAPWithWriter(outWriter))
+		}
+		if strings.ToLower(outputFlag) == "tap14" {
+			opts = append(opts, output.TAPWithVersion(14))
+		}
+		return output.NewTAPFormatter(opts...)
+	case "html":
+		opts := []output.HTMLOption{}
+		if outWriter != nil {
+			opts = append(opts, output.HTMLWithWriter(outWriter))
+		}
+		return output.NewHTMLFormatter(opts...)
+	default: // "console"
+		consoleOpts := []output.ConsoleOption{
+			output.WithVerbose(verboseFlag > 0),
+			output.WithNoColor(noColorFlag || quietFlag),
+			output.WithSummaryOnly(summaryFlag),
+			output.WithOnlyFailures(onlyFailedFlag),
+			output.WithQuiet(quietFlag),
+		}
+		if outWriter != nil {
+			consoleOpts = append(consoleOpts, output.WithWriter(outWriter))
+		}
+		return output.NewConsoleFormatter(consoleOpts...)
+	}
+}
+
+// maxRedirectsSetting returns the redirect limit from --max-redirects, falling
+// back to the config file's maxRedirects. nil keeps the client default; an
+// explicit --max-redirects 0 (or HITSPEC_MAX_REDIRECTS=0) follows none.
+func maxRedirectsSetting(cmd *cobra.Command, fileConfig *config.Config) *int {
+	if cmd.Flags().Changed("max-redirects") || os.Getenv("HITSPEC_MAX_REDIRECTS") != "" {
+		return &maxRedirects
+	}
+	if fileConfig != nil && fileConfig.MaxRedirects > 0 {
+		return &fileConfig.MaxRedirects
+	}
+	return nil
+}
+
+// userAgentSetting returns the User-Agent from --user-agent, falling back to
+// the config file's userAgent. nil keeps Go's default; an explicitly empty
+// --user-agent "" sends none.
+func userAgentSetting(cmd *cobra.Command, fileConfig *config.Config) *string {
+	if userAgentFlag != "" || cmd.Flags().Changed("user-agent") {
+		return &userAgentFlag
+	}
+	if fileConfig != nil {
+		return fileConfig.UserAgent
+	}
+	return nil
+}
+
+// loadConfig loads the config file. Unknown keys and mistyped values are
+// warnings unless strict is set; a file that isn't valid YAML is an error.
+func loadConfig(path string, strict bool) (*config.Config, error) {
+	cfg, err := config.LoadConfig(path)
+	var cfgErr *config.ConfigError
+	if errors.As(err, &cfgErr) && !strict {
+		for _, problem := range cfgErr.Problems {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", cfgErr.Path, problem)
+		}
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, withExitCode(ExitConfigError, fmt.Errorf("loading config: %w", err))
+	}
+	return cfg, nil
+}
+
+// checkStdinBody fails when more than one request of files reads its body
+// from stdin, before any request is sent. Files that don't parse are left
+// for the runner to report.
+func checkStdinBody(files []string) error {
+	var readers []string
+	for _, path := range files {
+		f, err := parser.ParseFile(path)
+		if err != nil {
+			continue
+		}
+		for _, req := range f.Requests {
+			if req.Body != nil && req.Body.ContentType == parser.BodyStdin {
+				readers = append(readers, fmt.Sprintf("%s:%d", path, req.Line))
+			}
+		}
+	}
+	if len(readers) > 1 {
+		return fmt.Errorf("only one request can read its body from stdin (< -), found %d: %s", len(readers), strings.Join(readers, ", "))
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptVariable returns a callback that asks for a missing required variable on stderr
+func promptVariable(reader *bufio.Reader) func(name string) (string, error) {
+	return func(name string) (string, error) {
+		fmt.Fprintf(os.Stderr, "Enter value for %s: ", name)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+}
+
+// printRetryBudget reports how much of the --retry-budget a run spent
+func printRetryBudget(w io.Writer, budget *runner.RetryBudget) {
+	fmt.Fprintf(w, "Retry budget: %d of %d retries used", budget.Used(), budget.Limit())
+	if refused := budget.Refused(); refused > 0 {
+		fmt.Fprintf(w, "; budget exhausted, %d failed requests were not retried", refused)
+	}
+	fmt.Fprintln(w)
 }
 
 // Environment variable helpers
@@ -198,9 +469,53 @@ type Flushable interface {
 }
 
 func runCommand(cmd *cobra.Command, args []string) error {
+	if junitDirFlag != "" {
+		if !strings.EqualFold(outputFlag, "junit") && cmd.Flags().Changed("output") {
+			return withExitCode(ExitUsageError, fmt.Errorf("--junit-dir writes JUnit XML and can't be combined with --output %s", outputFlag))
+		}
+		outputFlag = "junit"
+	}
+	if junitSingleFlag && junitDirFlag != "" {
+		return withExitCode(ExitUsageError, fmt.Errorf("--junit-single-suite can't be combined with --junit-dir, which writes a report per file"))
+	}
+	if recordDirFlag != "" && replayDirFlag != "" {
+		return withExitCode(ExitUsageError, fmt.Errorf("--record and --replay can't be combined"))
+	}
+	if (recordDirFlag != "" || replayDirFlag != "") && stressFlag {
+		return withExitCode(ExitUsageError, fmt.Errorf("--record and --replay can't be used with --stress"))
+	}
+	for _, field := range fixtureIgnore {
+		if !http.ValidFixtureIgnore(field) {
+			return withExitCode(ExitUsageError, fmt.Errorf("invalid --fixture-ignore %q: use body, body.<path> or query.<name>", field))
+		}
+	}
+	var harFilter output.HARFilter
+	if harFilterFlag != "" {
+		if harFlag == "" {
+			return withExitCode(ExitUsageError, fmt.Errorf("--har-filter needs --har"))
+		}
+		filter, err := output.ParseHARFilter(harFilterFlag)
+		if err != nil {
+			return withExitCode(ExitUsageError, err)
+		}
+		harFilter = filter
+	}
+	if harFlag != "" && stressFlag {
+		return withExitCode(ExitUsageError, fmt.Errorf("--har can't be used with --stress"))
+	}
+	logger, err := newLogger(os.Stderr, logLevelFlag)
+	if err != nil {
+		return withExitCode(ExitUsageError, err)
+	}
+	if envAllFlag && cmd.Flags().Changed("env") {
+		return withExitCode(ExitUsageError, fmt.Errorf("--env-all and --env can't be combined"))
+	}
+	if retryBudgetFlag < 0 {
+		return withExitCode(ExitUsageError, fmt.Errorf("--retry-budget must be 0 or more, got %d", retryBudgetFlag))
+	}
+
 	// Setup output writer
 	var outWriter *os.File
-	var err error
 	if outputFileFlag != "" {
 		outWriter, err = os.Create(outputFileFlag)
 		if err != nil {
@@ -210,44 +525,11 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create formatter based on output flag
-	var formatter Formatter
-	switch strings.ToLower(outputFlag) {
-	case "json":
-		opts := []output.JSONOption{}
-		if outWriter != nil {
-			opts = append(opts, output.JSONWithWriter(outWriter))
-		}
-		formatter = output.NewJSONFormatter(opts...)
-	case "junit":
-		opts := []output.JUnitOption{}
-		if outWriter != nil {
-			opts = append(opts, output.JUnitWithWriter(outWriter))
-		}
-		formatter = output.NewJUnitFormatter(opts...)
-	case "tap":
-		opts := []output.TAPOption{}
-		if outWriter != nil {
-			opts = append(opts, output.TAPWithWriter(outWriter))
-		}
-		formatter = output.NewTAPFormatter(opts...)
-	case "html":
-		opts := []output.HTMLOption{}
-		if outWriter != nil {
-			opts = append(opts, output.HTMLWithWriter(outWriter))
-		}
-		formatter = output.NewHTMLFormatter(opts...)
-	default: // "console"
-		consoleOpts := []output.ConsoleOption{
-			output.WithVerbose(verboseFlag > 0),
-			output.WithNoColor(noColorFlag || quietFlag),
-		}
-		if outWriter != nil {
-			consoleOpts = append(consoleOpts, output.WithWriter(outWriter))
-		}
-		formatter = output.NewConsoleFormatter(consoleOpts...)
-	}
+	formatter := newFormatter(outWriter)
 
-	formatter.FormatHeader(version)
+	if !dumpConfigFlag {
+		formatter.FormatHeader(version)
+	}
 
 	// Set up notification manager
 	var notifyManager *notify.Manager
@@ -286,12 +568,33 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		formatter.FormatError(err)
 		return err
 	}
+	if teardownFlag != "" {
+		if files, err = withoutTeardown(files, teardownFlag); err != nil {
+			formatter.FormatError(err)
+			return err
+		}
+	}
 
-	if len(files) == 0 {
+	if len(files) == 0 && !dumpConfigFlag {
 		formatter.FormatError(fmt.Errorf("no .http or .hitspec files found"))
 		return fmt.Errorf("no files found")
 	}
 
+	if sinceFlag != "" {
+		since, err := parseSince(sinceFlag, time.Now())
+		if err != nil {
+			return withExitCode(ExitUsageError, err)
+		}
+		if files, err = modifiedSince(files, since); err != nil {
+			formatter.FormatError(err)
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Fprintf(os.Stderr, "No files modified since %s; no tests selected\n", since.Format("2006-01-02 15:04:05"))
+			return nil
+		}
+	}
+
 	var tagsFilter []string
 	if tagsFlag != "" {
 		for _, t := range strings.Split(tagsFlag, ",") {
@@ -302,12 +605,41 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	variables, err := parseVarFlags(varFlags)
+	if err != nil {
+		return err
+	}
+
 	// Load config from file (if present) and apply CLI overrides
-	fileConfig, _ := config.LoadConfig(configFlag)
+	fileConfig, err := loadConfig(configFlag, strictConfig)
+	if err != nil {
+		return err
+	}
+
+	if tagsChangedFlag != "" {
+		changedTags, err := tagsFromChanged(fileConfig, tagsChangedFlag)
+		if err != nil {
+			return err
+		}
+		if len(changedTags) == 0 {
+			fmt.Fprintf(os.Stderr, "No files changed since %s match tagMap; no tests selected\n", tagsChangedFlag)
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Tags from files changed since %s: %s\n", tagsChangedFlag, strings.Join(changedTags, ", "))
+		tagsFilter = append(tagsFilter, changedTags...)
+	}
+
+	environments, err := runEnvironments(fileConfig, envAllFlag, envFlag)
+	if err != nil {
+		return withExitCode(ExitUsageError, err)
+	}
+	if len(environments) > 1 && stressFlag {
+		return withExitCode(ExitUsageError, fmt.Errorf("--stress runs against a single environment"))
+	}
 
 	// If stress mode is enabled, delegate to stress runner
-	if stressFlag {
-		return runStressMode(cmd, files, fileConfig)
+	if stressFlag && !dumpConfigFlag {
+		return runStressMode(cmd, files, fileConfig, variables)
 	}
 
 	// Determine proxy and validateSSL from config file, allowing CLI flags to override
@@ -321,6 +653,14 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		validateSSL = false
 	}
 
+	var fixtureMode http.FixtureMode
+	fixtureDir := recordDirFlag
+	if recordDirFlag != "" {
+		fixtureMode = http.FixtureRecord
+	} else if replayDirFlag != "" {
+		fixtureMode, fixtureDir = http.FixtureReplay, replayDirFlag
+	}
+
 	// Parse timeout duration string
 	timeout, err := time.ParseDuration(timeoutFlag)
 	if err != nil {
@@ -328,62 +668,199 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	cfg := &runner.Config{
-		Environment:        envFlag,
+		Environment:        environments[0],
 		EnvFile:            envFileFlag,
 		Verbose:            verboseFlag > 0,
 		Timeout:            timeout,
 		FollowRedirect:     fileConfig.GetFollowRedirects(),
+		MaxRedirects:       maxRedirectsSetting(cmd, fileConfig),
 		Bail:               bailFlag,
 		NameFilter:         nameFlag,
 		TagsFilter:         tagsFilter,
 		Parallel:           parallelFlag,
 		Concurrency:        concurrencyFlag,
 		ValidateSSL:        validateSSL,
+		InsecureHosts:      insecureHosts,
 		Proxy:              proxy,
+		UserAgent:          userAgentSetting(cmd, fileConfig),
+		FixtureMode:        fixtureMode,
+		FixtureDir:         fixtureDir,
+		FixtureIgnore:      fixtureIgnore,
 		DefaultHeaders:     fileConfig.Headers,
+		HostHeaders:        fileConfig.HeadersByHost,
+		SecureHeaders:      fileConfig.SecureHeaders,
 		ConfigEnvironments: fileConfig.Environments,
 		UpdateSnapshots:    updateSnapshotsFlag,
+		Variables:          variables,
+		NoEnv:              noEnvFlag,
+		Logger:             logger,
+	}
+	if dumpConfigFlag {
+		return writeEffectiveConfig(cmd.OutOrStdout(), buildEffectiveConfig(cfg, environments), strings.ToLower(outputFlag))
+	}
+	if stdinBodyFlag {
+		if err := checkStdinBody(files); err != nil {
+			return err
+		}
+		cfg.Stdin = runner.NewStdinBody(os.Stdin)
+	} else if interactiveFlag || stdinIsTerminal() {
+		cfg.PromptVariable = promptVariable(bufio.NewReader(os.Stdin))
+		if parallelFiles > 1 {
+			cfg.PromptVariable = serializePrompt(cfg.PromptVariable)
+		}
+	}
+	if retryFailedFlag != "" {
+		failures, err := loadFailureList(retryFailedFlag)
+		if err != nil {
+			return fmt.Errorf("loading failure list: %w", err)
+		}
+		if len(failures) == 0 {
+			fmt.Fprintf(os.Stderr, "No failed tests in %s\n", retryFailedFlag)
+			return nil
+		}
+		cfg.RequestFilter = func(file, name string) bool {
+			return failures[testKey(file, name)]
+		}
 	}
 
 	r := runner.NewRunner(cfg)
 
-	// Create a function to run all tests
-	runTests := func() (int, int, int, time.Duration) {
+	var baselineTolerance float64
+	if baselineFlag != "" {
+		if baselineTolerance, err = parseTolerance(baselineToleranceFlag); err != nil {
+			return err
+		}
+	} else if updateBaselineFlag {
+		return fmt.Errorf("--update-baseline requires --baseline <file>")
+	}
+
+	// Results of the latest run, for baseline comparison
+	var runResults []*runner.RunResult
+	// What failed in the latest run, for the exit code
+	var status runStatus
+
+	// Suggested assertions would corrupt structured output on stdout
+	inferWriter := io.Writer(os.Stdout)
+	if strings.ToLower(outputFlag) != "console" && outputFileFlag == "" {
+		inferWriter = os.Stderr
+	}
+
+	// Create a function to run the tests of the given files
+	runTests := func(targets []string) (int, int, int, time.Duration) {
 		totalPassed := 0
 		totalFailed := 0
 		totalSkipped := 0
 		startTime := time.Now()
-
-		for _, file := range files {
-			if dryRunFlag {
-				fmt.Fprintf(cmd.OutOrStdout(), "Would run: %s\n", file)
-				continue
+		runResults = nil
+		status = runStatus{}
+
+		// Each run, including watch re-runs, gets a fresh retry budget
+		if retryBudgetFlag > 0 && !dryRunFlag {
+			cfg.RetryBudget = runner.NewRetryBudget(retryBudgetFlag)
+			if !quietFlag {
+				defer printRetryBudget(os.Stderr, cfg.RetryBudget)
 			}
+		}
 
-			result, err := r.RunFile(file)
+		// Handle the outcome of one file; returns false to stop the run
+		stopped := false
+		handle := func(result *runner.RunResult, err error) bool {
+			status.record(result, err)
 			if err != nil {
 				formatter.FormatError(err)
-				if bailFlag {
-					break
-				}
-				continue
+				totalFailed++
+				stopped = stopped || bailFlag
+				return !bailFlag
 			}
 
+			if len(environments) > 1 {
+				result.Environment = cfg.Environment
+			}
 			formatter.FormatResult(result)
+			if inferFlag {
+				printInferredAssertions(inferWriter, result)
+			}
+			runResults = append(runResults, result)
 			totalPassed += result.Passed
 			totalFailed += result.Failed
 			totalSkipped += result.Skipped
 
 			if bailFlag && result.Failed > 0 {
-				break
+				stopped = true
 			}
+			return !stopped
 		}
 
+		// The teardown file runs last, whether or not the run bailed, and
+		// runs all of its requests since each may clean up something
+		runTeardown := func() {
+			if teardownFlag == "" {
+				return
+			}
+			if dryRunFlag {
+				fmt.Fprintf(cmd.OutOrStdout(), "Would run: %s (teardown)\n", teardownFlag)
+				return
+			}
+			teardownCfg := *cfg
+			teardownCfg.Bail = false
+			handle(runner.NewRunner(&teardownCfg).RunFile(teardownFlag))
+		}
+
+		runFiles := func() {
+			if parallelFiles > 1 && !dryRunFlag {
+				runFilesParallel(cfg, targets, parallelFiles, handle)
+				return
+			}
+
+			for _, file := range targets {
+				if dryRunFlag {
+					fmt.Fprintf(cmd.OutOrStdout(), "Would run: %s\n", file)
+					continue
+				}
+
+				if !handle(r.RunFile(file)) {
+					break
+				}
+			}
+		}
+
+		if len(environments) == 1 {
+			runFiles()
+			runTeardown()
+			return totalPassed, totalFailed, totalSkipped, time.Since(startTime)
+		}
+
+		// With several environments, the files and the teardown file run
+		// against each in turn, until one bails
+		var summaries []envSummary
+		for _, name := range environments {
+			if stopped {
+				break
+			}
+			cfg.Environment = name
+			if !quietFlag {
+				fmt.Fprintf(os.Stderr, "\n==> Environment: %s\n", name)
+			}
+			passed, failed, skipped, envStart := totalPassed, totalFailed, totalSkipped, time.Now()
+			runFiles()
+			runTeardown()
+			summaries = append(summaries, envSummary{
+				Name:     name,
+				Passed:   totalPassed - passed,
+				Failed:   totalFailed - failed,
+				Skipped:  totalSkipped - skipped,
+				Duration: time.Since(envStart),
+				Bailed:   stopped,
+			})
+		}
+		if !quietFlag && !dryRunFlag {
+			printEnvironmentSummary(os.Stderr, summaries)
+		}
 		return totalPassed, totalFailed, totalSkipped, time.Since(startTime)
 	}
 
 	// Run tests once
-	totalPassed, totalFailed, totalSkipped, totalDuration := runTests()
+	totalPassed, totalFailed, totalSkipped, totalDuration := runTests(files)
 
 	// Flush output for formatters that accumulate results
 	if flushable, ok := formatter.(Flushable); ok {
@@ -392,6 +869,47 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Compare against or update the baseline
+	baselineFailed := false
+	if baselineFlag != "" && !dryRunFlag {
+		if updateBaselineFlag {
+			if err := writeBaseline(baselineFlag, buildBaseline(runResults)); err != nil {
+				return fmt.Errorf("writing baseline: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Baseline written to %s\n", baselineFlag)
+		} else {
+			baseline, err := loadBaseline(baselineFlag)
+			if err != nil {
+				return fmt.Errorf("loading baseline: %w", err)
+			}
+			if regressions := compareBaseline(baseline, runResults, baselineTolerance); len(regressions) > 0 {
+				printBaselineRegressions(os.Stderr, regressions, baselineTolerance)
+				baselineFailed = true
+			}
+		}
+	}
+
+	// Record failures for --retry-failed
+	if listFailuresFlag != "" && !dryRunFlag {
+		if err := writeFailureList(listFailuresFlag, collectFailures(runResults)); err != nil {
+			return fmt.Errorf("writing failure list: %w", err)
+		}
+	}
+
+	// Export the requests of the run for investigation
+	if harFlag != "" && !dryRunFlag {
+		if err := writeHARFile(harFlag, runResults, harFilter); err != nil {
+			return fmt.Errorf("writing HAR: %w", err)
+		}
+	}
+
+	// Save @persist captures for later runs
+	if persistCapturesFlag != "" && !dryRunFlag {
+		if err := writePersistedCaptures(os.Stderr, persistCapturesFlag, runResults); err != nil {
+			return fmt.Errorf("persisting captures: %w", err)
+		}
+	}
+
 	// Send notifications if configured
 	if notifyManager != nil {
 		summary := &notify.RunSummary{
@@ -401,7 +919,7 @@ func runCommand(cmd *cobra.Command, args []string) error {
 			FailedTests:  totalFailed,
 			SkippedTests: totalSkipped,
 			Duration:     totalDuration,
-			Environment:  envFlag,
+			Environment:  strings.Join(environments, ","),
 		}
 		if err := notifyManager.Notify(summary); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: failed to send notification: %v\n", err)
@@ -410,8 +928,12 @@ func runCommand(cmd *cobra.Command, args []string) error {
 
 	// If watch mode is not enabled, exit normally
 	if !watchFlag {
-		if totalFailed > 0 {
-			os.Exit(1)
+		code := status.code(failureExitCode)
+		if code == ExitSuccess && baselineFailed {
+			code = ExitThresholdFailure
+		}
+		if code != ExitSuccess {
+			os.Exit(code)
 		}
 		return nil
 	}
@@ -452,10 +974,19 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if watchClearFlag {
+		printWatchStatus(cmd.OutOrStdout(), totalPassed, totalFailed, totalSkipped, totalDuration)
+	}
 	fmt.Fprintf(cmd.OutOrStdout(), "\nWatching for changes... (press Ctrl+C to stop)\n\n")
 
-	// Debounce timer for rapid file changes
+	// Number of watch runs, for the --watch-clear header
+	watchRuns := 1
+
+	// Debounce timer for rapid file changes, and the files changed since the
+	// last run
 	var debounceTimer *time.Timer
+	var changedMu sync.Mutex
+	changed := make(map[string]bool)
 
 	for {
 		select {
@@ -466,38 +997,54 @@ func runCommand(cmd *cobra.Command, args []string) error {
 
 			// Only react to write events on hitspec files
 			if event.Has(fsnotify.Write) && isHitspecFile(event.Name) {
+				changedMu.Lock()
+				changed[event.Name] = true
+				changedMu.Unlock()
+
 				// Debounce: reset timer on each event
 				if debounceTimer != nil {
 					debounceTimer.Stop()
 				}
 				debounceTimer = time.AfterFunc(WatchDebounceDelay, func() {
-					fmt.Fprintf(cmd.OutOrStdout(), "\n\nFile changed: %s\nRe-running tests...\n\n", event.Name)
+					changedMu.Lock()
+					pending := changed
+					changed = make(map[string]bool)
+					changedMu.Unlock()
+
+					// Only re-run the changed files and the files importing them,
+					// falling back to everything for files outside the run
+					targets := affectedFiles(files, pending)
+					if len(targets) == 0 {
+						targets = files
+					}
+					names := make([]string, 0, len(pending))
+					for name := range pending {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					watchRuns++
+					if watchClearFlag {
+						clearScreen(cmd.OutOrStdout())
+						printWatchHeader(cmd.OutOrStdout(), watchRuns, time.Now(), names)
+					} else {
+						fmt.Fprintf(cmd.OutOrStdout(), "\n\nFile changed: %s\nRe-running %d file(s)...\n\n", strings.Join(names, ", "), len(targets))
+					}
 
 					// Re-create formatter for new output (for JSON/JUnit, need fresh state)
-					switch strings.ToLower(outputFlag) {
-					case "json":
-						formatter = output.NewJSONFormatter()
-					case "junit":
-						formatter = output.NewJUnitFormatter()
-					case "tap":
-						formatter = output.NewTAPFormatter()
-					case "html":
-						formatter = output.NewHTMLFormatter()
-					default:
-						formatter = output.NewConsoleFormatter(
-							output.WithVerbose(verboseFlag > 0),
-							output.WithNoColor(noColorFlag),
-						)
-					}
+					formatter = newFormatter(outWriter)
 
 					// Re-run tests
-					_, _, _, duration := runTests()
+					passed, failed, skipped, duration := runTests(targets)
 
 					// Flush output
 					if flushable, ok := formatter.(Flushable); ok {
 						_ = flushable.Flush(duration)
 					}
 
+					if watchClearFlag {
+						printWatchStatus(cmd.OutOrStdout(), passed, failed, skipped, duration)
+					}
+
 					fmt.Fprintf(cmd.OutOrStdout(), "\nWatching for changes... (press Ctrl+C to stop)\n")
 				})
 			}
@@ -549,13 +1096,21 @@ func isHitspecFile(path string) bool {
 }
 
 // runStressMode executes stress tests using the stress runner
-func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config) error {
+func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config, variables map[string]string) error {
 	// Build stress config
 	cfg, err := buildStressConfig(fileConfig)
 	if err != nil {
 		return err
 	}
 
+	if stressPprofFlag != "" {
+		stopPprof, err := startPprof(stressPprofFlag)
+		if err != nil {
+			return err
+		}
+		defer stopPprof()
+	}
+
 	// Set up metrics exporters
 	var metricsExporters []metrics.Exporter
 	var metricsCollector *metrics.Collector
@@ -619,9 +1174,15 @@ func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config
 			proxyFlag = fileConfig.Proxy
 		}
 	}
+	if max := maxRedirectsSetting(cmd, fileConfig); max != nil {
+		clientOpts = append(clientOpts, http.WithMaxRedirects(*max))
+	}
 	if proxyFlag != "" {
 		clientOpts = append(clientOpts, http.WithProxy(proxyFlag))
 	}
+	if userAgent := userAgentSetting(cmd, fileConfig); userAgent != nil {
+		clientOpts = append(clientOpts, http.WithUserAgent(*userAgent))
+	}
 	validateSSL := true
 	if fileConfig != nil {
 		validateSSL = fileConfig.GetValidateSSL()
@@ -630,6 +1191,9 @@ func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config
 		validateSSL = false
 	}
 	clientOpts = append(clientOpts, http.WithValidateSSL(validateSSL))
+	if len(insecureHosts) > 0 {
+		clientOpts = append(clientOpts, http.WithInsecureHosts(insecureHosts))
+	}
 	client := http.NewClient(clientOpts...)
 
 	// Create resolver
@@ -643,6 +1207,7 @@ func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config
 		stress.WithNoColor(noColorFlag),
 		stress.WithNoProgress(stressNoProgressFlag),
 		stress.WithVerbose(verboseFlag > 0),
+		stress.WithShowEnvironment(stressShowEnvFlag),
 	)
 
 	// Create runner with config environments for proper variable resolution
@@ -657,6 +1222,12 @@ func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config
 	if envFileFlag != "" {
 		runnerOpts = append(runnerOpts, stress.WithEnvFile(envFileFlag))
 	}
+	if len(variables) > 0 {
+		runnerOpts = append(runnerOpts, stress.WithVariables(variables))
+	}
+	if noEnvFlag {
+		runnerOpts = append(runnerOpts, stress.WithNoEnv(true))
+	}
 	// Pass config environments for proper variable resolution
 	if fileConfig != nil && fileConfig.Environments != nil {
 		runnerOpts = append(runnerOpts, stress.WithConfigEnvironments(fileConfig.Environments))
@@ -697,12 +1268,14 @@ func runStressMode(cmd *cobra.Command, files []string, fileConfig *config.Config
 
 	// Output JSON if requested
 	if stressJSONFlag {
-		return reporter.JSONSummary(result.Summary, result.Thresholds)
+		if err := reporter.JSONSummary(result.Summary, result.Thresholds, stressSeriesFlag); err != nil {
+			return err
+		}
 	}
 
 	// Exit with error code if thresholds failed
 	if result.HasThresholdFailures() {
-		os.Exit(1)
+		os.Exit(ExitThresholdFailure)
 	}
 
 	return nil
@@ -805,6 +1378,8 @@ func buildStressConfig(fileConfig *config.Config) (*stress.Config, error) {
 		cfg.Thresholds = t
 	}
 
+	cfg.AssertSample = stressSampleFlag
+
 	return cfg, nil
 }
 
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sinceLayouts are the timestamps accepted by --since, in local time unless
+// they carry a zone
+var sinceLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseSince parses --since: a duration before now such as 90m, 1h or 7d, or
+// a timestamp such as 2024-01-01 or 2024-01-01T09:30:00Z
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use a duration like 1h or 7d, or a date like 2024-01-01)", value)
+}
+
+// modifiedSince returns the files modified after since, plus the files that
+// import them, keeping their order
+func modifiedSince(files []string, since time.Time) ([]string, error) {
+	modified := make(map[string]bool)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access %s: %w", file, err)
+		}
+		if info.ModTime().After(since) {
+			modified[file] = true
+		}
+	}
+	if len(modified) == 0 {
+		return nil, nil
+	}
+	return affectedFiles(files, modified), nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// withoutTeardown removes the --teardown file from files, so a teardown
+// file inside a tested directory only runs once, at the end
+func withoutTeardown(files []string, teardown string) ([]string, error) {
+	if _, err := os.Stat(teardown); err != nil {
+		return nil, fmt.Errorf("teardown file: %w", err)
+	}
+	teardownAbs, err := filepath.Abs(teardown)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(files))
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil && abs == teardownAbs {
+			continue
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
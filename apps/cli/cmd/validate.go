@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/config"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/spf13/cobra"
 )
@@ -10,15 +13,34 @@ import (
 var validateCmd = &cobra.Command{
 	Use:   "validate <file|directory>",
 	Short: "Validate hitspec files for syntax errors",
-	Long: `Validate hitspec files for syntax errors without executing them.
+	Long: `Validate hitspec files for syntax errors without executing them. The config
+file (hitspec.yaml, or --config) is also checked for unknown keys and
+mistyped values.
+
+With --strict, valid files are also checked for suspicious patterns:
+requests without assertions, unused variables, @depends on a request
+defined later in the file, and hardcoded localhost URLs.
 
 Examples:
   hitspec validate api.http
-  hitspec validate ./tests/`,
+  hitspec validate ./tests/
+  hitspec validate ./tests/ --strict --error-on-warn`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: validateCommand,
 }
 
+var (
+	validateStrictFlag      bool
+	validateErrorOnWarnFlag bool
+	validateConfigFlag      string
+)
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateStrictFlag, "strict", false, "Also warn about suspicious patterns in valid files")
+	validateCmd.Flags().BoolVar(&validateErrorOnWarnFlag, "error-on-warn", false, "Fail validation when --strict reports warnings")
+	validateCmd.Flags().StringVar(&validateConfigFlag, "config", getEnvString("HITSPEC_CONFIG", ""), "Path to config file (env: HITSPEC_CONFIG)")
+}
+
 func validateCommand(cmd *cobra.Command, args []string) error {
 	files, err := collectFiles(args)
 	if err != nil {
@@ -29,19 +51,52 @@ func validateCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no .http or .hitspec files found")
 	}
 
-	hasErrors := false
+	configFailed := false
+	filesFailed := false
+	warnings := 0
+
+	if _, err := config.LoadConfig(validateConfigFlag); err != nil {
+		var cfgErr *config.ConfigError
+		if errors.As(err, &cfgErr) {
+			for _, problem := range cfgErr.Problems {
+				fmt.Fprintf(cmd.OutOrStderr(), "Error in %s: %s\n", cfgErr.Path, problem)
+			}
+		} else {
+			fmt.Fprintf(cmd.OutOrStderr(), "Error in config: %v\n", err)
+		}
+		configFailed = true
+	}
 	for _, file := range files {
-		_, err := parser.ParseFile(file)
+		content, err := os.ReadFile(file)
 		if err != nil {
 			fmt.Fprintf(cmd.OutOrStderr(), "Error in %s: %v\n", file, err)
-			hasErrors = true
-		} else {
-			fmt.Fprintf(cmd.OutOrStdout(), "Valid: %s\n", file)
+			filesFailed = true
+			continue
+		}
+		f, err := parser.Parse(string(content), file)
+		if err != nil {
+			fmt.Fprintf(cmd.OutOrStderr(), "Error in %s: %v\n", file, err)
+			filesFailed = true
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Valid: %s\n", file)
+
+		if validateStrictFlag {
+			for _, w := range parser.Lint(f, string(content)) {
+				fmt.Fprintf(cmd.OutOrStderr(), "Warning in %s: %s\n", file, w)
+				warnings++
+			}
 		}
 	}
 
-	if hasErrors {
-		return fmt.Errorf("validation failed")
+	if filesFailed {
+		return withExitCode(ExitParseError, fmt.Errorf("validation failed"))
+	}
+	if configFailed {
+		return withExitCode(ExitConfigError, fmt.Errorf("validation failed"))
+	}
+	if warnings > 0 && validateErrorOnWarnFlag {
+		return fmt.Errorf("validation failed: %d warning(s)", warnings)
 	}
 
 	return nil
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/fatih/color"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+func clearScreen(w io.Writer) {
+	fmt.Fprint(w, "\033[H\033[2J")
+}
+
+// printWatchHeader prints the status header shown above each --watch-clear run
+func printWatchHeader(w io.Writer, run int, at time.Time, changed []string) {
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Fprintf(w, "%s  run #%d at %s\n", bold("hitspec watch"), run, at.Format("15:04:05"))
+	if len(changed) > 0 {
+		fmt.Fprintf(w, "Changed: %s\n", strings.Join(changed, ", "))
+	}
+}
+
+// printWatchStatus prints the pass/fail status line of the latest watch run
+func printWatchStatus(w io.Writer, passed, failed, skipped int, duration time.Duration) {
+	status := color.New(color.FgGreen, color.Bold).Sprint("PASS")
+	if failed > 0 {
+		status = color.New(color.FgRed, color.Bold).Sprint("FAIL")
+	}
+	fmt.Fprintf(w, "\n%s  %d passed, %d failed, %d skipped in %s\n", status, passed, failed, skipped, duration.Round(time.Millisecond))
+}
+
+// affectedFiles returns the files to re-run after the changed files were
+// written: each changed file that is part of the run, plus every file that
+// imports a changed file directly or through other imports. Files keep
+// their original order.
+func affectedFiles(files []string, changed map[string]bool) []string {
+	// Map each imported file to the files importing it
+	importers := make(map[string][]string)
+	for _, file := range files {
+		f, err := parser.ParseFile(file)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			path := imp
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(file), path)
+			}
+			importers[absPath(path)] = append(importers[absPath(path)], file)
+		}
+	}
+
+	affected := make(map[string]bool)
+	var queue []string
+	for path := range changed {
+		queue = append(queue, absPath(path))
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if affected[path] {
+			continue
+		}
+		affected[path] = true
+		for _, importer := range importers[path] {
+			queue = append(queue, absPath(importer))
+		}
+	}
+
+	var result []string
+	for _, file := range files {
+		if affected[absPath(file)] {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
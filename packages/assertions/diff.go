@@ -0,0 +1,238 @@
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffResult represents a single difference between expected and actual values.
+type DiffResult struct {
+	Path     string
+	Expected any
+	Actual   any
+	Type     DiffType
+}
+
+// DiffType represents the type of difference.
+type DiffType int
+
+const (
+	DiffTypeChanged DiffType = iota
+	DiffTypeAdded
+	DiffTypeRemoved
+)
+
+// ComputeJSONDiff compares two values and returns a list of differences.
+// It only returns differences, not the full structure.
+func ComputeJSONDiff(expected, actual any, path string) []DiffResult {
+	return ComputeJSONDiffByKey(expected, actual, path, "")
+}
+
+// ComputeJSONDiffByKey is like ComputeJSONDiff, but matches the elements of
+// arrays of objects by their key field instead of by position, so reordered
+// elements aren't reported as differences. Arrays whose elements don't all
+// have the key are compared by position.
+func ComputeJSONDiffByKey(expected, actual any, path, key string) []DiffResult {
+	var diffs []DiffResult
+
+	// Handle nil cases
+	if expected == nil && actual == nil {
+		return diffs
+	}
+	if expected == nil {
+		return []DiffResult{{Path: path, Expected: nil, Actual: actual, Type: DiffTypeAdded}}
+	}
+	if actual == nil {
+		return []DiffResult{{Path: path, Expected: expected, Actual: nil, Type: DiffTypeRemoved}}
+	}
+
+	// Check types
+	expectedType := reflect.TypeOf(expected)
+	actualType := reflect.TypeOf(actual)
+
+	// Type mismatch
+	if expectedType != actualType {
+		return []DiffResult{{Path: path, Expected: expected, Actual: actual, Type: DiffTypeChanged}}
+	}
+
+	switch e := expected.(type) {
+	case map[string]any:
+		a := actual.(map[string]any)
+		diffs = append(diffs, compareObjects(e, a, path, key)...)
+	case []any:
+		a := actual.([]any)
+		if keyed(e, key) && keyed(a, key) {
+			diffs = append(diffs, compareArraysByKey(e, a, path, key)...)
+		} else {
+			diffs = append(diffs, compareArrays(e, a, path, key)...)
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			diffs = append(diffs, DiffResult{Path: path, Expected: expected, Actual: actual, Type: DiffTypeChanged})
+		}
+	}
+
+	return diffs
+}
+
+func compareObjects(expected, actual map[string]any, path, key string) []DiffResult {
+	var diffs []DiffResult
+
+	// Collect all keys
+	allKeys := make(map[string]bool)
+	for k := range expected {
+		allKeys[k] = true
+	}
+	for k := range actual {
+		allKeys[k] = true
+	}
+
+	for key := range allKeys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		expectedVal, expectedExists := expected[key]
+		actualVal, actualExists := actual[key]
+
+		if !expectedExists {
+			diffs = append(diffs, DiffResult{Path: keyPath, Expected: nil, Actual: actualVal, Type: DiffTypeAdded})
+		} else if !actualExists {
+			diffs = append(diffs, DiffResult{Path: keyPath, Expected: expectedVal, Actual: nil, Type: DiffTypeRemoved})
+		} else {
+			diffs = append(diffs, ComputeJSONDiffByKey(expectedVal, actualVal, keyPath, key)...)
+		}
+	}
+
+	return diffs
+}
+
+func compareArrays(expected, actual []any, path, key string) []DiffResult {
+	var diffs []DiffResult
+
+	maxLen := len(expected)
+	if len(actual) > maxLen {
+		maxLen = len(actual)
+	}
+
+	for i := 0; i < maxLen; i++ {
+		indexPath := fmt.Sprintf("%s[%d]", path, i)
+
+		if i >= len(expected) {
+			diffs = append(diffs, DiffResult{Path: indexPath, Expected: nil, Actual: actual[i], Type: DiffTypeAdded})
+		} else if i >= len(actual) {
+			diffs = append(diffs, DiffResult{Path: indexPath, Expected: expected[i], Actual: nil, Type: DiffTypeRemoved})
+		} else {
+			diffs = append(diffs, ComputeJSONDiffByKey(expected[i], actual[i], indexPath, key)...)
+		}
+	}
+
+	return diffs
+}
+
+// keyed reports whether every element of arr is an object with the key field
+func keyed(arr []any, key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, item := range arr {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return false
+		}
+		if _, ok := obj[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareArraysByKey matches elements with the same key value. Elements are
+// addressed as path[key=value] in the results.
+func compareArraysByKey(expected, actual []any, path, key string) []DiffResult {
+	var diffs []DiffResult
+
+	keyOf := func(item any) string {
+		return fmt.Sprintf("%v", item.(map[string]any)[key])
+	}
+	actualByKey := make(map[string]any, len(actual))
+	for _, item := range actual {
+		actualByKey[keyOf(item)] = item
+	}
+
+	matched := make(map[string]bool, len(expected))
+	for _, item := range expected {
+		k := keyOf(item)
+		matched[k] = true
+		itemPath := fmt.Sprintf("%s[%s=%s]", path, key, k)
+		if other, ok := actualByKey[k]; ok {
+			diffs = append(diffs, ComputeJSONDiffByKey(item, other, itemPath, key)...)
+		} else {
+			diffs = append(diffs, DiffResult{Path: itemPath, Expected: item, Actual: nil, Type: DiffTypeRemoved})
+		}
+	}
+	for _, item := range actual {
+		if k := keyOf(item); !matched[k] {
+			diffs = append(diffs, DiffResult{Path: fmt.Sprintf("%s[%s=%s]", path, key, k), Expected: nil, Actual: item, Type: DiffTypeAdded})
+		}
+	}
+
+	return diffs
+}
+
+// maxMessageDiffs limits how many differences are listed in an assertion message
+const maxMessageDiffs = 5
+
+// deepEquals compares an expected object or array literal against the actual
+// value and describes the differences on failure. Arrays of objects are
+// matched by the key field when key is set.
+func deepEquals(actual, expected any, key string) (bool, string) {
+	// Normalize numbers so 30 in the expected literal matches 30.0 from the body
+	normalized := expected
+	if data, err := json.Marshal(expected); err == nil {
+		_ = json.Unmarshal(data, &normalized)
+	}
+
+	diffs := ComputeJSONDiffByKey(normalized, actual, "", key)
+	if len(diffs) == 0 {
+		return true, ""
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	parts := make([]string, 0, maxMessageDiffs)
+	for i, d := range diffs {
+		if i >= maxMessageDiffs {
+			parts = append(parts, fmt.Sprintf("and %d more", len(diffs)-maxMessageDiffs))
+			break
+		}
+		path := d.Path
+		if path == "" {
+			path = "(root)"
+		}
+		switch d.Type {
+		case DiffTypeAdded:
+			parts = append(parts, fmt.Sprintf("%s: unexpected %v", path, d.Actual))
+		case DiffTypeRemoved:
+			parts = append(parts, fmt.Sprintf("%s: missing, expected %v", path, d.Expected))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: expected %v, got %v", path, d.Expected, d.Actual))
+		}
+	}
+
+	noun := "differences"
+	if len(diffs) == 1 {
+		noun = "difference"
+	}
+	kind := "object"
+	if _, ok := expected.([]any); ok {
+		kind = "array"
+	}
+	return false, fmt.Sprintf("%s mismatch, %d %s: %s", kind, len(diffs), noun, strings.Join(parts, "; "))
+}
@@ -3,6 +3,7 @@ package assertions
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -24,6 +25,7 @@ type Result struct {
 	Actual   any
 	Subject  string
 	Operator string
+	ByKey    string // Key field used to match array elements when diffing
 }
 
 type Evaluator struct {
@@ -32,6 +34,10 @@ type Evaluator struct {
 	baseDir     string // Base directory for resolving schema file paths
 	testFile    string // Path to the test file (for snapshots)
 	requestName string // Name of the current request (for snapshots)
+	snapshots   *snapshot.Manager
+	arrayKey    string // byKey option of the assertion being evaluated
+	// Headers checked by expect secure-headers
+	secureHeaders map[string]string
 }
 
 // EvaluatorOption is a functional option for configuring an Evaluator.
@@ -44,6 +50,14 @@ func WithTestFile(path string) EvaluatorOption {
 	}
 }
 
+// WithSnapshotManager sets the snapshot manager used instead of the global one,
+// so files running concurrently don't share it.
+func WithSnapshotManager(m *snapshot.Manager) EvaluatorOption {
+	return func(e *Evaluator) {
+		e.snapshots = m
+	}
+}
+
 // WithRequestName sets the request name for snapshot testing.
 func WithRequestName(name string) EvaluatorOption {
 	return func(e *Evaluator) {
@@ -57,11 +71,12 @@ func NewEvaluator(resp *http.Response) *Evaluator {
 
 func NewEvaluatorWithBaseDir(resp *http.Response, baseDir string, opts ...EvaluatorOption) *Evaluator {
 	e := &Evaluator{
-		response: resp,
-		baseDir:  baseDir,
+		response:      resp,
+		baseDir:       baseDir,
+		secureHeaders: DefaultSecureHeaders,
 	}
-	if resp.IsJSON() {
-		e.bodyJSON = gjson.ParseBytes(resp.Body)
+	if resp.IsJSON() || resp.IsNDJSON() {
+		e.bodyJSON = gjson.ParseBytes(resp.JSONBody())
 	}
 	for _, opt := range opts {
 		opt(e)
@@ -70,26 +85,66 @@ func NewEvaluatorWithBaseDir(resp *http.Response, baseDir string, opts ...Evalua
 }
 
 func (e *Evaluator) Evaluate(assertion *parser.Assertion) *Result {
+	result := e.evaluate(assertion)
+	// A custom message explains a failure better than the generated one
+	if !result.Passed && assertion.Message != "" {
+		result.Message = assertion.Message
+	}
+	return result
+}
+
+func (e *Evaluator) evaluate(assertion *parser.Assertion) *Result {
 	result := &Result{
 		Subject:  assertion.Subject,
 		Operator: assertion.Operator.String(),
 		Expected: assertion.Expected,
+		ByKey:    assertion.ByKey,
 	}
-
-	actual, err := e.getActualValue(assertion.Subject)
-	if err != nil {
-		result.Passed = false
-		result.Message = err.Error()
-		return result
+	e.arrayKey = assertion.ByKey
+	if assertion.Negate {
+		result.Operator = "not " + result.Operator
+	}
+	if assertion.Subject == SecureHeadersSubject {
+		return e.checkSecureHeaders(result)
 	}
-	result.Actual = actual
 
-	passed, msg := e.compare(actual, assertion.Operator, assertion.Expected)
+	var actual any
+	var passed bool
+	var msg string
+	arr, streamed := e.largeArray(assertion)
+	if streamed {
+		// Large arrays are iterated in place rather than decoded, so only
+		// their length is reported
+		var n int
+		n, passed, msg = e.compareArray(arr, assertion.Operator, assertion.Expected)
+		actual = fmt.Sprintf("[array with %d items]", n)
+		result.Actual = actual
+		if assertion.Operator == parser.OpLength {
+			result.Actual = n
+		}
+	} else {
+		var err error
+		actual, err = e.getActualValue(assertion.Subject)
+		if err != nil {
+			result.Passed = false
+			result.Message = err.Error()
+			return result
+		}
+		result.Actual = actual
+		passed, msg = e.compare(actual, assertion.Operator, assertion.Expected)
+	}
+	if assertion.Negate {
+		passed = !passed
+		msg = ""
+		if !passed {
+			msg = fmt.Sprintf("expected %v not %s %v", actual, assertion.Operator, assertion.Expected)
+		}
+	}
 	result.Passed = passed
 	result.Message = msg
 
 	// For length operator, show the computed length as the actual value
-	if assertion.Operator == parser.OpLength {
+	if assertion.Operator == parser.OpLength && !streamed {
 		result.Actual = computeLength(actual)
 	}
 
@@ -102,6 +157,18 @@ func (e *Evaluator) getActualValue(subject string) (any, error) {
 		return e.response.StatusCode, nil
 	case subject == "duration":
 		return e.response.DurationMs(), nil
+	case subject == "redirects":
+		return len(e.response.Redirects), nil
+	case strings.EqualFold(subject, "finalUrl"):
+		return e.response.FinalURL, nil
+	case subject == "raw":
+		return rawBody(e.response.Raw()), nil
+	case subject == "encoding":
+		return e.response.Encoding, nil
+	case strings.EqualFold(subject, "compressionRatio"):
+		return e.response.CompressionRatio(), nil
+	case strings.EqualFold(subject, "contentType"):
+		return responseMediaType(e.response.Header("Content-Type")), nil
 	// Percentile assertions - for single requests, all percentiles equal duration
 	// In stress testing mode, these would be calculated from aggregated metrics
 	case subject == "p50", subject == "p95", subject == "p99":
@@ -112,7 +179,11 @@ func (e *Evaluator) getActualValue(subject string) (any, error) {
 		if headerName == "" {
 			return e.response.Headers, nil
 		}
-		return e.response.Header(headerName), nil
+		// A missing header is nil, so exists and !exists can check for it
+		if v, ok := e.response.LookupHeader(headerName); ok {
+			return v, nil
+		}
+		return nil, nil
 	case strings.HasPrefix(subject, "body"):
 		return e.getBodyValue(subject)
 	case strings.HasPrefix(subject, "jsonpath"):
@@ -124,11 +195,13 @@ func (e *Evaluator) getActualValue(subject string) (any, error) {
 	}
 }
 
+var bracketIndex = regexp.MustCompile(`\[(\d+)\]`)
+
 // convertBracketNotation converts array bracket notation to gjson dot notation
 // e.g., "[0].id" -> "0.id", "items[0].tags[1]" -> "items.0.tags.1"
 func convertBracketNotation(path string) string {
 	// Replace [N] with .N
-	result := regexp.MustCompile(`\[(\d+)\]`).ReplaceAllString(path, ".$1")
+	result := bracketIndex.ReplaceAllString(path, ".$1")
 	// Remove leading dot if present (from converting [0] at start)
 	result = strings.TrimPrefix(result, ".")
 	return result
@@ -218,6 +291,12 @@ func (e *Evaluator) compare(actual any, op parser.AssertionOperator, expected an
 			return false, fmt.Sprintf("expected not to include %v", expected)
 		}
 		return true, ""
+	case parser.OpIncludesAll:
+		return e.includesAll(actual, expected)
+	case parser.OpIncludesAny:
+		return e.includesAny(actual, expected)
+	case parser.OpMonotonic:
+		return e.monotonic(actual, expected)
 	case parser.OpIn:
 		return e.in(actual, expected)
 	case parser.OpNotIn:
@@ -244,6 +323,27 @@ func (e *Evaluator) equals(actual, expected any) (bool, string) {
 		return true, ""
 	}
 
+	if raw, ok := actual.(rawBody); ok {
+		if string(raw) == fmt.Sprintf("%v", expected) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("expected raw body %q, got %q", fmt.Sprintf("%v", expected), string(raw))
+	}
+
+	if mt, ok := actual.(mediaType); ok {
+		if mt.is(fmt.Sprintf("%v", expected)) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("expected content type %v, got %v", expected, actual)
+	}
+
+	if _, ok := expected.(map[string]any); ok {
+		return deepEquals(actual, expected, e.arrayKey)
+	}
+	if _, ok := expected.([]any); ok && e.arrayKey != "" {
+		return deepEquals(actual, expected, e.arrayKey)
+	}
+
 	actualNum, aOk := toFloat64(actual)
 	expectedNum, eOk := toFloat64(expected)
 	if aOk && eOk && actualNum == expectedNum {
@@ -259,6 +359,40 @@ func (e *Evaluator) equals(actual, expected any) (bool, string) {
 	return false, fmt.Sprintf("expected %v, got %v", expected, actual)
 }
 
+// rawBody is the response body exactly as received. It only equals the
+// expected value byte for byte, without the numeric and trimmed comparisons
+// applied to other values.
+type rawBody string
+
+// mediaType is the Content-Type of a response without parameters such as
+// charset, lowercased (application/json)
+type mediaType string
+
+// responseMediaType extracts the media type from a Content-Type header, or
+// returns nil when the response has none
+func responseMediaType(header string) any {
+	if header == "" {
+		return nil
+	}
+	mt, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mt, _, _ = strings.Cut(header, ";")
+	}
+	return mediaType(strings.ToLower(strings.TrimSpace(mt)))
+}
+
+// is reports whether the media type matches want: a full type such as
+// "image/png", or a short form such as "json" that matches a subtype
+// (application/json) or structured syntax suffix (application/problem+json)
+func (m mediaType) is(want string) bool {
+	want = strings.ToLower(strings.TrimSpace(want))
+	if strings.Contains(want, "/") {
+		return string(m) == want
+	}
+	_, subtype, _ := strings.Cut(string(m), "/")
+	return subtype == want || strings.HasSuffix(subtype, "+"+want)
+}
+
 func (e *Evaluator) compareNumeric(actual, expected any, op string) (bool, string) {
 	actualNum, aOk := toFloat64(actual)
 	expectedNum, eOk := toFloat64(expected)
@@ -319,7 +453,7 @@ func (e *Evaluator) matches(actual, expected any) (bool, string) {
 	pattern = strings.TrimPrefix(pattern, "/")
 	pattern = strings.TrimSuffix(pattern, "/")
 
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegex(pattern)
 	if err != nil {
 		return false, fmt.Sprintf("invalid regex pattern: %v", err)
 	}
@@ -388,6 +522,56 @@ func (e *Evaluator) includes(actual, expected any) (bool, string) {
 	return false, fmt.Sprintf("expected array to include %v", expected)
 }
 
+func (e *Evaluator) includesAll(actual, expected any) (bool, string) {
+	arr, ok := actual.([]any)
+	if !ok {
+		return false, fmt.Sprintf("expected array, got %T", actual)
+	}
+	want, ok := expected.([]any)
+	if !ok {
+		return false, fmt.Sprintf("expected array for 'includesAll' operator, got %T", expected)
+	}
+
+	var missing []any
+	for _, w := range want {
+		found := false
+		for _, item := range arr {
+			if passed, _ := e.equals(item, w); passed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+
+	if len(missing) == 0 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("expected array to include all of %v, missing %v", expected, missing)
+}
+
+func (e *Evaluator) includesAny(actual, expected any) (bool, string) {
+	arr, ok := actual.([]any)
+	if !ok {
+		return false, fmt.Sprintf("expected array, got %T", actual)
+	}
+	want, ok := expected.([]any)
+	if !ok {
+		return false, fmt.Sprintf("expected array for 'includesAny' operator, got %T", expected)
+	}
+
+	for _, w := range want {
+		for _, item := range arr {
+			if passed, _ := e.equals(item, w); passed {
+				return true, ""
+			}
+		}
+	}
+	return false, fmt.Sprintf("expected array to include any of %v", expected)
+}
+
 func (e *Evaluator) in(actual, expected any) (bool, string) {
 	arr, ok := expected.([]any)
 	if !ok {
@@ -404,6 +588,9 @@ func (e *Evaluator) in(actual, expected any) (bool, string) {
 
 func (e *Evaluator) typeCheck(actual, expected any) (bool, string) {
 	expectedType := fmt.Sprintf("%v", expected)
+	if expected == nil {
+		expectedType = "null" // "type null" parses as a null literal
+	}
 	var actualType string
 
 	switch actual.(type) {
@@ -475,9 +662,19 @@ func EvaluateAll(resp *http.Response, assertions []*parser.Assertion) []*Result
 
 func EvaluateAllWithBaseDir(resp *http.Response, assertions []*parser.Assertion, baseDir string, opts ...EvaluatorOption) []*Result {
 	evaluator := NewEvaluatorWithBaseDir(resp, baseDir, opts...)
-	results := make([]*Result, len(assertions))
-	for i, a := range assertions {
-		results[i] = evaluator.Evaluate(a)
+	results := make([]*Result, 0, len(assertions))
+	for _, a := range assertions {
+		// expect secure-headers reports each header on its own
+		if a.Subject == SecureHeadersSubject {
+			for _, r := range evaluator.secureHeaderResults() {
+				if !r.Passed && a.Message != "" {
+					r.Message = a.Message
+				}
+				results = append(results, r)
+			}
+			continue
+		}
+		results = append(results, evaluator.Evaluate(a))
 	}
 	return results
 }
@@ -509,42 +706,49 @@ func validatePathWithinBase(path, baseDir string) error {
 }
 
 func (e *Evaluator) schema(actual, expected any) (bool, string) {
-	schemaPath := fmt.Sprintf("%v", expected)
+	// Convert actual value to JSON
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		return false, fmt.Sprintf("failed to marshal actual value: %v", err)
+	}
+
+	if err := ValidateSchema(actualJSON, fmt.Sprintf("%v", expected), e.baseDir); err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
 
+// ValidateSchema validates a JSON document against the JSON Schema file at
+// schemaPath, which is resolved relative to baseDir and may not escape it.
+func ValidateSchema(document []byte, schemaPath, baseDir string) error {
 	// Resolve schema path relative to base directory
-	if !filepath.IsAbs(schemaPath) && e.baseDir != "" {
-		schemaPath = filepath.Join(e.baseDir, schemaPath)
+	if !filepath.IsAbs(schemaPath) && baseDir != "" {
+		schemaPath = filepath.Join(baseDir, schemaPath)
 	}
 
 	// Validate path doesn't escape base directory (prevent path traversal)
-	if err := validatePathWithinBase(schemaPath, e.baseDir); err != nil {
-		return false, err.Error()
+	if err := validatePathWithinBase(schemaPath, baseDir); err != nil {
+		return err
 	}
 
 	// Read schema file
 	schemaData, err := os.ReadFile(schemaPath)
 	if err != nil {
-		return false, fmt.Sprintf("failed to read schema file: %v", err)
-	}
-
-	// Convert actual value to JSON
-	actualJSON, err := json.Marshal(actual)
-	if err != nil {
-		return false, fmt.Sprintf("failed to marshal actual value: %v", err)
+		return fmt.Errorf("failed to read schema file: %v", err)
 	}
 
 	// Create schema and document loaders
 	schemaLoader := gojsonschema.NewBytesLoader(schemaData)
-	documentLoader := gojsonschema.NewBytesLoader(actualJSON)
+	documentLoader := gojsonschema.NewBytesLoader(document)
 
 	// Validate
 	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
-		return false, fmt.Sprintf("schema validation error: %v", err)
+		return fmt.Errorf("schema validation error: %v", err)
 	}
 
 	if result.Valid() {
-		return true, ""
+		return nil
 	}
 
 	// Collect validation errors
@@ -552,7 +756,7 @@ func (e *Evaluator) schema(actual, expected any) (bool, string) {
 	for _, desc := range result.Errors() {
 		errors = append(errors, desc.String())
 	}
-	return false, fmt.Sprintf("schema validation failed: %s", strings.Join(errors, "; "))
+	return fmt.Errorf("schema validation failed: %s", strings.Join(errors, "; "))
 }
 
 func (e *Evaluator) each(actual, expected any) (bool, string) {
@@ -569,27 +773,8 @@ func (e *Evaluator) each(actual, expected any) (bool, string) {
 	// For simplicity, we check if each element equals the expected value
 	// or if expected is a map with operator/value, we apply it to each element
 
-	expectedMap, isMap := expected.(map[string]any)
-	if isMap {
-		// Check if it has operator and value fields
-		op, hasOp := expectedMap["operator"]
-		val, hasVal := expectedMap["value"]
-
-		if hasOp && hasVal {
-			opStr := fmt.Sprintf("%v", op)
-			for i, item := range arr {
-				passed, msg := e.applyOperator(item, opStr, val)
-				if !passed {
-					return false, fmt.Sprintf("item[%d]: %s", i, msg)
-				}
-			}
-			return true, ""
-		}
-	}
-
-	// Default: check each element equals expected
 	for i, item := range arr {
-		passed, msg := e.equals(item, expected)
+		passed, msg := e.eachItem(item, expected)
 		if !passed {
 			return false, fmt.Sprintf("item[%d]: %s", i, msg)
 		}
@@ -597,6 +782,20 @@ func (e *Evaluator) each(actual, expected any) (bool, string) {
 	return true, ""
 }
 
+// eachItem checks one element for the each operator: with a map holding
+// operator and value fields, the operator is applied to the element;
+// otherwise the element must equal expected
+func (e *Evaluator) eachItem(item, expected any) (bool, string) {
+	if expectedMap, isMap := expected.(map[string]any); isMap {
+		op, hasOp := expectedMap["operator"]
+		val, hasVal := expectedMap["value"]
+		if hasOp && hasVal {
+			return e.applyOperator(item, fmt.Sprintf("%v", op), val)
+		}
+	}
+	return e.equals(item, expected)
+}
+
 func (e *Evaluator) snapshot(actual, expected any) (bool, string) {
 	// Get snapshot name from expected value (optional)
 	snapshotName := ""
@@ -604,8 +803,10 @@ func (e *Evaluator) snapshot(actual, expected any) (bool, string) {
 		snapshotName = fmt.Sprintf("%v", expected)
 	}
 
-	// Use global snapshot manager
-	manager := snapshot.GetGlobalManager()
+	manager := e.snapshots
+	if manager == nil {
+		manager = snapshot.GetGlobalManager()
+	}
 	if manager == nil {
 		return false, "snapshot manager not initialized"
 	}
@@ -3,6 +3,8 @@ package assertions
 import (
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -237,6 +239,39 @@ func TestEvaluator_Matches(t *testing.T) {
 	assert.True(t, result.Passed)
 }
 
+func TestCompileRegex_Cache(t *testing.T) {
+	first, err := compileRegex(`^user-\d+$`)
+	require.NoError(t, err)
+	second, err := compileRegex(`^user-\d+$`)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	_, err = compileRegex(`(unclosed`)
+	assert.Error(t, err)
+	_, cached := regexCache.get(`(unclosed`)
+	assert.False(t, cached, "invalid patterns aren't cached")
+}
+
+func TestRegexLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newRegexLRU(2)
+	a := regexp.MustCompile(`a`)
+	cache.add("a", a)
+	cache.add("b", regexp.MustCompile(`b`))
+
+	got, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Same(t, a, got)
+
+	cache.add("c", regexp.MustCompile(`c`))
+	assert.Equal(t, 2, cache.len())
+	_, ok = cache.get("b")
+	assert.False(t, ok, "least recently used pattern is evicted")
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+}
+
 func TestEvaluator_Type(t *testing.T) {
 	resp := createResponse(200, `{
 		"string": "hello",
@@ -296,6 +331,204 @@ func TestEvaluator_In(t *testing.T) {
 	})
 }
 
+func TestEvaluator_IncludesAllAny(t *testing.T) {
+	resp := createResponse(200, `{"roles": ["admin", "user", "viewer"], "ids": [1, 2, 3]}`, nil)
+	e := NewEvaluator(resp)
+
+	t.Run("includesAll - match", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.roles",
+			Operator: parser.OpIncludesAll,
+			Expected: []any{"admin", "user"},
+		})
+		assert.True(t, result.Passed, "Message: %s", result.Message)
+	})
+
+	t.Run("includesAll - reports missing", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.roles",
+			Operator: parser.OpIncludesAll,
+			Expected: []any{"admin", "owner", "root"},
+		})
+		assert.False(t, result.Passed)
+		assert.Contains(t, result.Message, "missing [owner root]")
+	})
+
+	t.Run("includesAll - numeric", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.ids",
+			Operator: parser.OpIncludesAll,
+			Expected: []any{1, 3},
+		})
+		assert.True(t, result.Passed, "Message: %s", result.Message)
+	})
+
+	t.Run("includesAny - match", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.roles",
+			Operator: parser.OpIncludesAny,
+			Expected: []any{"owner", "viewer"},
+		})
+		assert.True(t, result.Passed)
+	})
+
+	t.Run("includesAny - no match", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.roles",
+			Operator: parser.OpIncludesAny,
+			Expected: []any{"owner", "root"},
+		})
+		assert.False(t, result.Passed)
+	})
+}
+
+func TestEvaluator_Monotonic(t *testing.T) {
+	resp := createResponse(200, `{
+		"events": [
+			{"id": 1, "at": "2024-01-01T10:00:00Z"},
+			{"id": 2, "at": "2024-01-01T10:00:00Z"},
+			{"id": 3, "at": "2024-01-01T11:30:00+01:00"},
+			{"id": 4, "at": "2024-01-02T00:00:00Z"}
+		],
+		"epochs": [1700000300, 1700000200, 1700000100],
+		"millis": [1700000000000, 1700000000500],
+		"bad": [{"at": "2024-01-01"}, {"at": "yesterday"}]
+	}`, nil)
+	e := NewEvaluator(resp)
+
+	tests := []struct {
+		name    string
+		subject string
+		order   string
+		passed  bool
+		message string
+	}{
+		{"ascending by field", "body.events", "asc by at", true, ""},
+		{"wrong direction reports first violation", "body.events", "desc by at", false, "item[2]"},
+		{"unix seconds descending", "body.epochs", "desc", true, ""},
+		{"unix seconds not ascending", "body.epochs", "asc", false, "item[1] (1.7000002e+09) is earlier than item[0]"},
+		{"unix milliseconds", "body.millis", "asc", true, ""},
+		{"unparseable timestamp", "body.bad", "asc by at", false, `item[1].at: "yesterday" is not a timestamp`},
+		{"missing field", "body.events", "asc by missing", false, "item[0].missing: missing timestamp"},
+		{"not an array", "body.events.0.at", "asc", false, "expected array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(&parser.Assertion{
+				Subject:  tt.subject,
+				Operator: parser.OpMonotonic,
+				Expected: tt.order,
+			})
+			assert.Equal(t, tt.passed, result.Passed, "Message: %s", result.Message)
+			assert.Contains(t, result.Message, tt.message)
+		})
+	}
+}
+
+func TestEvaluator_Negate(t *testing.T) {
+	resp := createResponse(200, `{"url": "https://example.com", "id": 42}`, nil)
+	e := NewEvaluator(resp)
+
+	t.Run("negated match passes", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.url",
+			Operator: parser.OpMatches,
+			Negate:   true,
+			Expected: "/^http:/",
+		})
+		assert.True(t, result.Passed, "Message: %s", result.Message)
+		assert.Equal(t, "not matches", result.Operator)
+	})
+
+	t.Run("negated type fails", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.id",
+			Operator: parser.OpType,
+			Negate:   true,
+			Expected: "number",
+		})
+		assert.False(t, result.Passed)
+		assert.Equal(t, "expected 42 not type number", result.Message)
+	})
+}
+
+func TestEvaluator_ObjectEquals(t *testing.T) {
+	resp := createResponse(200, `{"user": {"name": "John", "age": 30, "address": {"city": "Paris"}}}`, nil)
+	e := NewEvaluator(resp)
+
+	t.Run("equal", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.user",
+			Operator: parser.OpEquals,
+			Expected: map[string]any{"name": "John", "age": 30, "address": map[string]any{"city": "Paris"}},
+		})
+		assert.True(t, result.Passed, "Message: %s", result.Message)
+	})
+
+	t.Run("reports differences", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.user",
+			Operator: parser.OpEquals,
+			Expected: map[string]any{"name": "Jane", "address": map[string]any{"city": "Paris"}},
+		})
+		assert.False(t, result.Passed)
+		assert.Equal(t, "object mismatch, 2 differences: age: unexpected 30; name: expected Jane, got John", result.Message)
+	})
+
+	t.Run("not equal", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.user",
+			Operator: parser.OpNotEquals,
+			Expected: map[string]any{"name": "Jane"},
+		})
+		assert.True(t, result.Passed)
+	})
+}
+
+func TestEvaluator_ArrayEqualsByKey(t *testing.T) {
+	resp := createResponse(200, `{"items": [{"id": 2, "name": "b"}, {"id": 1, "name": "a"}, {"id": 4, "name": "d"}]}`, nil)
+	e := NewEvaluator(resp)
+
+	t.Run("reordered elements match", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.items",
+			Operator: parser.OpEquals,
+			Expected: []any{
+				map[string]any{"id": 1, "name": "a"},
+				map[string]any{"id": 4, "name": "d"},
+				map[string]any{"id": 2, "name": "b"},
+			},
+			ByKey: "id",
+		})
+		assert.True(t, result.Passed, "Message: %s", result.Message)
+	})
+
+	t.Run("reports genuine differences", func(t *testing.T) {
+		result := e.Evaluate(&parser.Assertion{
+			Subject:  "body.items",
+			Operator: parser.OpEquals,
+			Expected: []any{
+				map[string]any{"id": 1, "name": "a"},
+				map[string]any{"id": 2, "name": "x"},
+				map[string]any{"id": 3, "name": "c"},
+			},
+			ByKey: "id",
+		})
+		assert.False(t, result.Passed)
+		assert.Equal(t, "array mismatch, 3 differences: [id=2].name: expected x, got b; [id=3]: missing, expected map[id:3 name:c]; [id=4]: unexpected map[id:4 name:d]", result.Message)
+	})
+
+	t.Run("positional without key", func(t *testing.T) {
+		diffs := ComputeJSONDiff(
+			[]any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}},
+			[]any{map[string]any{"id": 2.0}, map[string]any{"id": 1.0}}, "")
+		assert.Len(t, diffs, 2)
+		assert.Empty(t, ComputeJSONDiffByKey(
+			[]any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}},
+			[]any{map[string]any{"id": 2.0}, map[string]any{"id": 1.0}}, "", "id"))
+	})
+}
+
 func TestEvaluator_Duration(t *testing.T) {
 	resp := createResponse(200, `{}`, nil)
 	resp.Duration = 50 * time.Millisecond
@@ -309,6 +542,27 @@ func TestEvaluator_Duration(t *testing.T) {
 	assert.True(t, result.Passed)
 }
 
+func TestEvaluator_Redirects(t *testing.T) {
+	resp := createResponse(200, `{}`, nil)
+	resp.Redirects = []string{"https://example.com/account"}
+	resp.FinalURL = "https://example.com/login?next=/account"
+	e := NewEvaluator(resp)
+
+	result := e.Evaluate(&parser.Assertion{
+		Subject:  "redirects",
+		Operator: parser.OpEquals,
+		Expected: 1,
+	})
+	assert.True(t, result.Passed)
+
+	result = e.Evaluate(&parser.Assertion{
+		Subject:  "finalUrl",
+		Operator: parser.OpContains,
+		Expected: "/login",
+	})
+	assert.True(t, result.Passed)
+}
+
 func TestEvaluator_Header(t *testing.T) {
 	resp := createResponse(200, `{}`, map[string]string{
 		"Content-Type":  "application/json",
@@ -336,6 +590,142 @@ func TestEvaluator_Header(t *testing.T) {
 	})
 }
 
+func TestEvaluator_HeaderAbsent(t *testing.T) {
+	resp := createResponse(200, `{}`, map[string]string{
+		"X-Empty": "",
+	})
+	e := NewEvaluator(resp)
+
+	tests := []struct {
+		subject string
+		op      parser.AssertionOperator
+		passed  bool
+	}{
+		{"header X-Powered-By", parser.OpNotExists, true},
+		{"header X-Powered-By", parser.OpExists, false},
+		{"header x-empty", parser.OpExists, true},
+		{"header X-Empty", parser.OpNotExists, false},
+	}
+	for _, tt := range tests {
+		result := e.Evaluate(&parser.Assertion{Subject: tt.subject, Operator: tt.op})
+		assert.Equal(t, tt.passed, result.Passed, "%s %s", tt.subject, tt.op)
+	}
+
+	result := e.Evaluate(&parser.Assertion{
+		Subject:  "header X-Powered-By",
+		Operator: parser.OpEquals,
+		Expected: "",
+	})
+	assert.False(t, result.Passed, "a missing header doesn't equal an empty one")
+}
+
+func TestEvaluator_CustomMessage(t *testing.T) {
+	e := NewEvaluator(createResponse(200, `{"balance": -5}`, nil))
+
+	failed := e.Evaluate(&parser.Assertion{
+		Subject:  "body.balance",
+		Operator: parser.OpGreaterOrEqual,
+		Expected: 0,
+		Message:  "balance must never be negative",
+	})
+	assert.False(t, failed.Passed)
+	assert.Equal(t, "balance must never be negative", failed.Message)
+	assert.Equal(t, float64(-5), failed.Actual)
+
+	passed := e.Evaluate(&parser.Assertion{
+		Subject:  "body.balance",
+		Operator: parser.OpLessThan,
+		Expected: 0,
+		Message:  "balance must be negative",
+	})
+	assert.True(t, passed.Passed)
+	assert.Empty(t, passed.Message)
+}
+
+func TestEvaluator_SecureHeaders(t *testing.T) {
+	secure := map[string]string{
+		"Strict-Transport-Security": "max-age=63072000",
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Content-Security-Policy":   "default-src 'self'",
+	}
+	assertion := &parser.Assertion{Subject: SecureHeadersSubject, Operator: parser.OpExists}
+
+	result := NewEvaluator(createResponse(200, `{}`, secure)).Evaluate(assertion)
+	assert.True(t, result.Passed, result.Message)
+
+	resp := createResponse(200, `{}`, map[string]string{
+		"X-Content-Type-Options": "sniff",
+		"X-Frame-Options":        "DENY",
+	})
+	result = NewEvaluator(resp).Evaluate(assertion)
+	assert.False(t, result.Passed)
+	assert.Equal(t, []string{"Content-Security-Policy", "Strict-Transport-Security", "X-Content-Type-Options"}, result.Actual)
+	assert.Contains(t, result.Message, "missing Strict-Transport-Security")
+	assert.Contains(t, result.Message, `X-Content-Type-Options is "sniff", expected "nosniff"`)
+
+	// A configured baseline replaces the default one
+	result = NewEvaluatorWithBaseDir(resp, "", WithSecureHeaders(map[string]string{"X-Frame-Options": "deny"})).Evaluate(assertion)
+	assert.True(t, result.Passed, result.Message)
+
+	// Evaluated with the others, each header gets its own result
+	results := EvaluateAll(resp, []*parser.Assertion{assertion, {Subject: "status", Operator: parser.OpEquals, Expected: 200}})
+	require.Len(t, results, 5)
+	byHeader := make(map[string]*Result)
+	for _, r := range results[:4] {
+		byHeader[r.Subject] = r
+	}
+	assert.False(t, byHeader["header Content-Security-Policy"].Passed)
+	assert.Equal(t, "missing Content-Security-Policy", byHeader["header Content-Security-Policy"].Message)
+	assert.False(t, byHeader["header X-Content-Type-Options"].Passed)
+	assert.Equal(t, "==", byHeader["header X-Content-Type-Options"].Operator)
+	assert.Equal(t, "nosniff", byHeader["header X-Content-Type-Options"].Expected)
+	assert.Equal(t, "sniff", byHeader["header X-Content-Type-Options"].Actual)
+	assert.True(t, byHeader["header X-Frame-Options"].Passed)
+	assert.Equal(t, "exists", byHeader["header X-Frame-Options"].Operator)
+	assert.Equal(t, "status", results[4].Subject)
+
+	assertion.Message = "security baseline"
+	results = EvaluateAll(resp, []*parser.Assertion{assertion})
+	assert.Equal(t, "security baseline", results[0].Message)
+}
+
+func TestEvaluator_ContentType(t *testing.T) {
+	tests := []struct {
+		header   string
+		op       parser.AssertionOperator
+		expected string
+		passed   bool
+	}{
+		{"application/json; charset=utf-8", parser.OpEquals, "application/json", true},
+		{"application/json; charset=utf-8", parser.OpEquals, "json", true},
+		{"application/problem+json", parser.OpEquals, "json", true},
+		{"Image/PNG", parser.OpEquals, "image/png", true},
+		{"text/html; charset=utf-8", parser.OpEquals, "json", false},
+		{"text/html; charset=utf-8", parser.OpNotEquals, "json", true},
+		{"application/vnd.api+json", parser.OpContains, "vnd.api", true},
+		{"text/plain; charset=utf-8", parser.OpContains, "charset", false},
+	}
+
+	for _, tt := range tests {
+		resp := createResponse(200, `{}`, map[string]string{"Content-Type": tt.header})
+		result := NewEvaluator(resp).Evaluate(&parser.Assertion{
+			Subject:  "contentType",
+			Operator: tt.op,
+			Expected: tt.expected,
+		})
+		assert.Equal(t, tt.passed, result.Passed, "%s %s %s", tt.header, tt.op, tt.expected)
+	}
+
+	resp := createResponse(204, ``, nil)
+	delete(resp.Headers, "Content-Type")
+	result := NewEvaluator(resp).Evaluate(&parser.Assertion{
+		Subject:  "contentType",
+		Operator: parser.OpExists,
+	})
+	assert.False(t, result.Passed)
+}
+
 func TestEvaluator_Schema(t *testing.T) {
 	// Create a temporary schema file
 	tmpDir := t.TempDir()
@@ -394,6 +784,124 @@ func TestEvaluator_Each(t *testing.T) {
 	})
 }
 
+func TestEvaluator_LargeArrays(t *testing.T) {
+	// Stream every array so the small body below takes the large-array path
+	defer func(threshold int) { streamThreshold = threshold }(streamThreshold)
+	streamThreshold = 0
+
+	resp := createResponse(200, `{"ids": [1, 2, 3], "users": [{"active": true}, {"active": false}]}`, nil)
+	e := NewEvaluator(resp)
+
+	tests := []struct {
+		name      string
+		assertion *parser.Assertion
+		passed    bool
+		actual    any
+		message   string
+	}{
+		{"length", &parser.Assertion{Subject: "body.ids", Operator: parser.OpLength, Expected: 3}, true, 3, ""},
+		{"length mismatch", &parser.Assertion{Subject: "body.ids", Operator: parser.OpLength, Expected: 4}, false, 3, "expected length 4, got 3"},
+		{"includes", &parser.Assertion{Subject: "body.ids", Operator: parser.OpIncludes, Expected: 2}, true, "[array with 3 items]", ""},
+		{"not includes", &parser.Assertion{Subject: "body.ids", Operator: parser.OpIncludes, Expected: 9, Negate: true}, true, "[array with 3 items]", ""},
+		{"each", &parser.Assertion{Subject: "body.ids", Operator: parser.OpEach, Expected: map[string]any{"operator": ">", "value": 0}}, true, "[array with 3 items]", ""},
+		{"each failure", &parser.Assertion{Subject: "body.users", Operator: parser.OpEach, Expected: map[string]any{"operator": "==", "value": map[string]any{"active": true}}}, false, "[array with 2 items]", "item[1]: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(tt.assertion)
+			assert.Equal(t, tt.passed, result.Passed, "Message: %s", result.Message)
+			assert.Equal(t, tt.actual, result.Actual)
+			if tt.message != "" {
+				assert.Contains(t, result.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestEvaluator_NDJSON(t *testing.T) {
+	resp := createResponse(200, "{\"id\":1,\"type\":\"start\"}\n{\"id\":2,\"type\":\"data\"}\n{\"id\":3,\"type\":\"end\"}\n",
+		map[string]string{"Content-Type": "application/x-ndjson"})
+
+	results := EvaluateAll(resp, []*parser.Assertion{
+		{Subject: "body", Operator: parser.OpLength, Expected: 3},
+		{Subject: "body[0].type", Operator: parser.OpEquals, Expected: "start"},
+		{Subject: "body[2].id", Operator: parser.OpEquals, Expected: 3},
+		{Subject: "body", Operator: parser.OpEach, Expected: map[string]any{"operator": "exists", "value": "id"}},
+	})
+	for _, r := range results {
+		assert.True(t, r.Passed, "Failed: %s - %s", r.Subject, r.Message)
+	}
+
+	// Marked @ndjson, a body served as text/plain parses the same way
+	plain := createResponse(200, "{\"id\":1}\n{\"id\":2}", map[string]string{"Content-Type": "text/plain"})
+	plain.NDJSON = true
+	result := NewEvaluator(plain).Evaluate(&parser.Assertion{Subject: "body[1].id", Operator: parser.OpEquals, Expected: 2})
+	assert.True(t, result.Passed, result.Message)
+}
+
+func TestEvaluator_Raw(t *testing.T) {
+	resp := createResponse(200, "  42\r\n", map[string]string{"Content-Type": "text/plain"})
+	e := NewEvaluator(resp)
+
+	tests := []struct {
+		name      string
+		assertion *parser.Assertion
+		passed    bool
+	}{
+		{"exact bytes", &parser.Assertion{Subject: "raw", Operator: parser.OpEquals, Expected: "  42\r\n"}, true},
+		{"trimmed", &parser.Assertion{Subject: "raw", Operator: parser.OpEquals, Expected: "42"}, false},
+		{"number", &parser.Assertion{Subject: "raw", Operator: parser.OpEquals, Expected: 42}, false},
+		{"not equals", &parser.Assertion{Subject: "raw", Operator: parser.OpNotEquals, Expected: "42\n"}, true},
+		{"ends with", &parser.Assertion{Subject: "raw", Operator: parser.OpEndsWith, Expected: "\r\n"}, true},
+		{"length in bytes", &parser.Assertion{Subject: "raw", Operator: parser.OpLength, Expected: 6}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(tt.assertion)
+			assert.Equal(t, tt.passed, result.Passed, "Message: %s", result.Message)
+		})
+	}
+
+	result := e.Evaluate(&parser.Assertion{Subject: "raw", Operator: parser.OpEquals, Expected: "42"})
+	assert.Equal(t, `expected raw body "42", got "  42\r\n"`, result.Message)
+
+	// Bytes received in another charset are compared before decoding
+	latin1 := createResponse(200, "", map[string]string{"Content-Type": "text/plain; charset=ISO-8859-1"})
+	latin1.Body = []byte("café")
+	latin1.RawBody = []byte{'c', 'a', 'f', 0xe9}
+	result = NewEvaluator(latin1).Evaluate(&parser.Assertion{Subject: "raw", Operator: parser.OpEquals, Expected: "caf\xe9"})
+	assert.True(t, result.Passed, result.Message)
+}
+
+func TestEvaluator_Compression(t *testing.T) {
+	resp := createResponse(200, strings.Repeat("a", 1000), map[string]string{"Content-Encoding": "gzip"})
+	resp.Encoding = "gzip"
+	resp.WireSize = 200
+	e := NewEvaluator(resp)
+
+	tests := []struct {
+		name      string
+		assertion *parser.Assertion
+		passed    bool
+	}{
+		{"encoding", &parser.Assertion{Subject: "encoding", Operator: parser.OpEquals, Expected: "gzip"}, true},
+		{"other encoding", &parser.Assertion{Subject: "encoding", Operator: parser.OpEquals, Expected: "br"}, false},
+		{"ratio below", &parser.Assertion{Subject: "compressionRatio", Operator: parser.OpLessThan, Expected: 0.3}, true},
+		{"ratio above", &parser.Assertion{Subject: "compressionRatio", Operator: parser.OpLessThan, Expected: 0.1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(tt.assertion)
+			assert.Equal(t, tt.passed, result.Passed, "Message: %s", result.Message)
+		})
+	}
+
+	// Uncompressed responses have no encoding and a ratio of 1
+	plain := NewEvaluator(createResponse(200, "ok", nil))
+	assert.True(t, plain.Evaluate(&parser.Assertion{Subject: "encoding", Operator: parser.OpEquals, Expected: ""}).Passed)
+	assert.True(t, plain.Evaluate(&parser.Assertion{Subject: "compressionRatio", Operator: parser.OpEquals, Expected: 1}).Passed)
+}
+
 func TestEvaluateAll(t *testing.T) {
 	resp := createResponse(200, `{"status": "ok", "count": 5}`, nil)
 
@@ -449,3 +957,43 @@ func TestValidatePathWithinBase(t *testing.T) {
 		})
 	}
 }
+
+func TestInfer(t *testing.T) {
+	resp := createResponse(201, `{"id": 7, "name": "Jane", "active": true, "tags": ["a"], "address": {"city": "Lima"}, "deletedAt": null, "a.b": 1}`,
+		map[string]string{"Content-Type": "application/json; charset=utf-8"})
+
+	lines := Infer(resp)
+	assert.Equal(t, []string{
+		"expect status 201",
+		"expect contentType application/json",
+		"expect body.id type number",
+		"expect body.name type string",
+		"expect body.active type boolean",
+		"expect body.tags type array",
+		"expect body.address type object",
+		"expect body.deletedAt type null",
+	}, lines)
+
+	// The suggestions parse and pass against the response they came from
+	input := "GET https://api.example.com/users/7\n\n>>>\n" + strings.Join(lines, "\n") + "\n<<<"
+	file, err := parser.Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests[0].Assertions, len(lines))
+	for _, r := range EvaluateAll(resp, file.Requests[0].Assertions) {
+		assert.True(t, r.Passed, "%s %s: %s", r.Subject, r.Operator, r.Message)
+	}
+}
+
+func TestInfer_Array(t *testing.T) {
+	lines := Infer(createResponse(200, `[{"id": 1}, {"id": 2}]`, nil))
+	assert.Equal(t, []string{
+		"expect status 200",
+		"expect contentType application/json",
+		"expect body type array",
+		"expect body[0].id type number",
+	}, lines)
+
+	resp := createResponse(204, "", nil)
+	delete(resp.Headers, "Content-Type")
+	assert.Equal(t, []string{"expect status 204"}, Infer(resp))
+}
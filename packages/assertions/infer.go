@@ -0,0 +1,81 @@
+package assertions
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"github.com/tidwall/gjson"
+)
+
+// plainKey matches JSON keys usable as is in a body.<key> subject
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Infer suggests assertions describing resp: its status, media type, and the
+// type of each top-level field of a JSON body (or of the first element's
+// fields when the body is an array). They record what the response looks
+// like now, as a starting point for a test rather than a finished one.
+func Infer(resp *http.Response) []string {
+	lines := []string{fmt.Sprintf("expect status %d", resp.StatusCode)}
+
+	if ct := resp.Header("Content-Type"); ct != "" {
+		if mediaType, _, err := mime.ParseMediaType(ct); err == nil {
+			lines = append(lines, "expect contentType "+mediaType)
+		}
+	}
+
+	if !resp.IsJSON() && !resp.IsNDJSON() {
+		return lines
+	}
+	data := resp.JSONBody()
+	if !gjson.ValidBytes(data) {
+		return lines
+	}
+	body := gjson.ParseBytes(data)
+
+	switch {
+	case body.IsObject():
+		lines = append(lines, inferFields("body", body)...)
+	case body.IsArray():
+		lines = append(lines, "expect body type array")
+		if first := body.Get("0"); first.IsObject() {
+			lines = append(lines, inferFields("body[0]", first)...)
+		}
+	}
+	return lines
+}
+
+// inferFields returns a type assertion for each field of obj, in body order.
+// Keys that can't be written as a subject, such as ones with dots or spaces,
+// are skipped.
+func inferFields(prefix string, obj gjson.Result) []string {
+	var lines []string
+	obj.ForEach(func(key, value gjson.Result) bool {
+		if plainKey.MatchString(key.String()) {
+			lines = append(lines, fmt.Sprintf("expect %s.%s type %s", prefix, key.String(), jsonType(value)))
+		}
+		return true
+	})
+	return lines
+}
+
+// jsonType names the type of value as the type operator does
+func jsonType(value gjson.Result) string {
+	switch {
+	case value.IsObject():
+		return "object"
+	case value.IsArray():
+		return "array"
+	case value.IsBool():
+		return "boolean"
+	}
+	switch value.Type {
+	case gjson.Number:
+		return "number"
+	case gjson.Null:
+		return "null"
+	default:
+		return "string"
+	}
+}
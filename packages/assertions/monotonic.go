@@ -0,0 +1,102 @@
+package assertions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// timeLayouts are the layouts timestamps are parsed with, in order
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02",
+}
+
+// monotonic checks that the elements of an array, or the given field of
+// each, are timestamps in order. expected is "asc" or "desc", optionally
+// followed by "by <field>". Equal neighbours are allowed.
+func (e *Evaluator) monotonic(actual, expected any) (bool, string) {
+	arr, ok := actual.([]any)
+	if !ok {
+		return false, fmt.Sprintf("expected array for 'monotonic' operator, got %T", actual)
+	}
+	words := strings.Fields(fmt.Sprintf("%v", expected))
+	if len(words) == 0 || (words[0] != "asc" && words[0] != "desc") {
+		return false, fmt.Sprintf("invalid order for 'monotonic' operator: %v", expected)
+	}
+	desc := words[0] == "desc"
+	field := ""
+	if len(words) == 3 && words[1] == "by" {
+		field = words[2]
+	}
+
+	var prev time.Time
+	var prevValue any
+	for i, item := range arr {
+		value := item
+		if field != "" {
+			value = elementField(item, field)
+		}
+		t, err := parseTimestamp(value)
+		if err != nil {
+			if field != "" {
+				return false, fmt.Sprintf("item[%d].%s: %v", i, field, err)
+			}
+			return false, fmt.Sprintf("item[%d]: %v", i, err)
+		}
+		if i > 0 && ((!desc && t.Before(prev)) || (desc && t.After(prev))) {
+			direction := "earlier"
+			if desc {
+				direction = "later"
+			}
+			return false, fmt.Sprintf("item[%d] (%v) is %s than item[%d] (%v)", i, value, direction, i-1, prevValue)
+		}
+		prev, prevValue = t, value
+	}
+	return true, ""
+}
+
+// elementField returns the value at path in an array element, or nil
+func elementField(item any, path string) any {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil
+	}
+	result := gjson.GetBytes(data, convertBracketNotation(path))
+	if !result.Exists() {
+		return nil
+	}
+	return result.Value()
+}
+
+// parseTimestamp reads a timestamp: a date string such as RFC 3339, or a
+// Unix time in seconds, or in milliseconds for values too large to be seconds
+func parseTimestamp(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a timestamp", v)
+	case float64:
+		if v > 1e11 || v < -1e11 {
+			return time.UnixMilli(int64(v)), nil
+		}
+		sec := int64(v)
+		return time.Unix(sec, int64((v-float64(sec))*1e9)), nil
+	case nil:
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	default:
+		return time.Time{}, fmt.Errorf("%v (%T) is not a timestamp", v, v)
+	}
+}
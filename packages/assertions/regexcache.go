@@ -0,0 +1,80 @@
+package assertions
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// regexCacheSize bounds regexCache. Expected values can be interpolated from
+// variables and captures, so a long run or stress test may see a new pattern
+// per request; only the most recently used ones are kept.
+const regexCacheSize = 256
+
+// regexCache holds the compiled patterns of matches assertions, shared by
+// every evaluator so repeated runs and stress tests compile each pattern once
+var regexCache = newRegexLRU(regexCacheSize)
+
+// regexLRU is a fixed-size cache of compiled patterns that evicts the least
+// recently used one when full
+type regexLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used first; values are *regexEntry
+	entries map[string]*list.Element
+}
+
+type regexEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexLRU(size int) *regexLRU {
+	return &regexLRU{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *regexLRU) get(pattern string) (*regexp.Regexp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[pattern]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*regexEntry).re, true
+}
+
+func (c *regexLRU) add(pattern string, re *regexp.Regexp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[pattern] = c.order.PushFront(&regexEntry{pattern: pattern, re: re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexEntry).pattern)
+	}
+}
+
+func (c *regexLRU) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// compileRegex returns the compiled pattern, from regexCache when it has been
+// compiled recently. Invalid patterns aren't cached.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.get(pattern); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.add(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,70 @@
+package assertions
+
+import (
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// Resolve returns the assertions with the {{...}} references in
+// their expected values resolved, as in expect body.env == {{env}}. The
+// parsed assertions are left alone, so each run of a request resolves them
+// again.
+func Resolve(list []*parser.Assertion, resolve func(string) string) []*parser.Assertion {
+	resolved := make([]*parser.Assertion, len(list))
+	for i, a := range list {
+		expected, changed := resolveExpected(a.Expected, resolve)
+		if !changed {
+			resolved[i] = a
+			continue
+		}
+		copied := *a
+		copied.Expected = expected
+		resolved[i] = &copied
+	}
+	return resolved
+}
+
+// resolveExpected resolves the references in an expected value, including
+// the strings inside arrays and objects, and reports whether it had any
+func resolveExpected(value any, resolve func(string) string) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, false
+		}
+		return resolve(v), true
+	case []any:
+		var out []any
+		for i, elem := range v {
+			if r, ok := resolveExpected(elem, resolve); ok {
+				if out == nil {
+					out = append([]any(nil), v...)
+				}
+				out[i] = r
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[string]any:
+		var out map[string]any
+		for key, elem := range v {
+			if r, ok := resolveExpected(elem, resolve); ok {
+				if out == nil {
+					out = make(map[string]any, len(v))
+					for k, e := range v {
+						out[k] = e
+					}
+				}
+				out[key] = r
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	}
+	return value, false
+}
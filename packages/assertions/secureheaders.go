@@ -0,0 +1,89 @@
+package assertions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// SecureHeadersSubject is the composite assertion checking a response against
+// the security header baseline: expect secure-headers
+const SecureHeadersSubject = "secure-headers"
+
+// DefaultSecureHeaders is the baseline checked by expect secure-headers. Keys
+// are header names; an empty value only requires the header to be present,
+// otherwise the value must match case-insensitively.
+var DefaultSecureHeaders = map[string]string{
+	"Strict-Transport-Security": "",
+	"X-Content-Type-Options":    "nosniff",
+	"X-Frame-Options":           "",
+	"Content-Security-Policy":   "",
+}
+
+// WithSecureHeaders replaces the headers checked by expect secure-headers.
+func WithSecureHeaders(headers map[string]string) EvaluatorOption {
+	return func(e *Evaluator) {
+		if len(headers) > 0 {
+			e.secureHeaders = headers
+		}
+	}
+}
+
+// secureHeaderResults expands the composite assertion into a result per
+// header of the baseline, in name order, so reports show which header is
+// missing or wrong
+func (e *Evaluator) secureHeaderResults() []*Result {
+	names := make([]string, 0, len(e.secureHeaders))
+	for name := range e.secureHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]*Result, 0, len(names))
+	for _, name := range names {
+		want := e.secureHeaders[name]
+		got, ok := e.response.LookupHeader(name)
+		result := &Result{Subject: "header " + name, Operator: parser.OpExists.String(), Passed: true}
+		if ok {
+			result.Actual = got
+		}
+		if want != "" {
+			result.Operator = parser.OpEquals.String()
+			result.Expected = want
+		}
+		switch {
+		case !ok:
+			result.Passed = false
+			result.Message = "missing " + name
+		case want != "" && !strings.EqualFold(strings.TrimSpace(got), want):
+			result.Passed = false
+			result.Message = fmt.Sprintf("%s is %q, expected %q", name, got, want)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkSecureHeaders summarizes the per-header checks in a single result,
+// listing the failed headers as its actual value
+func (e *Evaluator) checkSecureHeaders(result *Result) *Result {
+	var expected, failed, problems []string
+	for _, r := range e.secureHeaderResults() {
+		name := strings.TrimPrefix(r.Subject, "header ")
+		expected = append(expected, name)
+		if !r.Passed {
+			failed = append(failed, name)
+			problems = append(problems, r.Message)
+		}
+	}
+
+	result.Expected = expected
+	result.Actual = failed
+	result.Passed = len(problems) == 0
+	if !result.Passed {
+		result.Message = "insecure headers: " + strings.Join(problems, "; ")
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package assertions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/tidwall/gjson"
+)
+
+// streamThreshold is the size in bytes from which a body array is iterated
+// in place by length, includes and each, decoding one element at a time,
+// instead of being decoded as a whole
+var streamThreshold = 1 << 20
+
+// largeArray returns the body array an assertion's subject refers to when the
+// operator can iterate it and it's at least streamThreshold bytes
+func (e *Evaluator) largeArray(assertion *parser.Assertion) (gjson.Result, bool) {
+	switch assertion.Operator {
+	case parser.OpLength, parser.OpIncludes, parser.OpEach:
+	default:
+		return gjson.Result{}, false
+	}
+	value, ok := e.bodyResult(assertion.Subject)
+	if !ok || !value.IsArray() || len(value.Raw) < streamThreshold {
+		return gjson.Result{}, false
+	}
+	return value, true
+}
+
+// bodyResult returns the unparsed JSON value of a body or jsonpath subject
+func (e *Evaluator) bodyResult(subject string) (gjson.Result, bool) {
+	if !e.bodyJSON.Exists() {
+		return gjson.Result{}, false
+	}
+	var path string
+	switch {
+	case strings.HasPrefix(subject, "body"):
+		path = strings.TrimPrefix(strings.TrimPrefix(subject, "body"), ".")
+	case strings.HasPrefix(subject, "jsonpath"):
+		path = strings.TrimSpace(strings.TrimPrefix(subject, "jsonpath"))
+	default:
+		return gjson.Result{}, false
+	}
+	if path == "" {
+		return e.bodyJSON, true
+	}
+	value := e.bodyJSON.Get(convertBracketNotation(path))
+	return value, value.Exists()
+}
+
+// compareArray applies length, includes or each to an array without decoding
+// it as a whole, returning the array's length with the outcome. Elements after
+// the first includes match or each failure are counted but not decoded.
+func (e *Evaluator) compareArray(arr gjson.Result, op parser.AssertionOperator, expected any) (int, bool, string) {
+	n := 0
+	passed := true
+	msg := ""
+	switch op {
+	case parser.OpLength:
+		expectedLen, ok := toInt(expected)
+		if !ok {
+			return 0, false, fmt.Sprintf("expected length must be a number, got %v", expected)
+		}
+		arr.ForEach(func(_, _ gjson.Result) bool {
+			n++
+			return true
+		})
+		if n != expectedLen {
+			passed, msg = false, fmt.Sprintf("expected length %d, got %d", expectedLen, n)
+		}
+	case parser.OpIncludes:
+		passed = false
+		arr.ForEach(func(_, item gjson.Result) bool {
+			if !passed {
+				passed, _ = e.equals(item.Value(), expected)
+			}
+			n++
+			return true
+		})
+		if !passed {
+			msg = fmt.Sprintf("expected array to include %v", expected)
+		}
+	case parser.OpEach:
+		arr.ForEach(func(_, item gjson.Result) bool {
+			if passed {
+				if passed, msg = e.eachItem(item.Value(), expected); !passed {
+					msg = fmt.Sprintf("item[%d]: %s", n, msg)
+				}
+			}
+			n++
+			return true
+		})
+	}
+	return n, passed, msg
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/url"
@@ -20,12 +21,14 @@ import (
 type Func func(args []string) any
 
 type Registry struct {
-	funcs map[string]Func
+	funcs   map[string]Func
+	rawArgs map[string]bool // Functions that get their argument text unsplit
 }
 
 func NewRegistry() *Registry {
 	r := &Registry{
-		funcs: make(map[string]Func),
+		funcs:   make(map[string]Func),
+		rawArgs: map[string]bool{"json": true},
 	}
 	r.registerDefaults()
 	return r
@@ -42,10 +45,13 @@ func (r *Registry) registerDefaults() {
 	r.funcs["randomAlphanumeric"] = funcRandomAlphanumeric
 	r.funcs["base64"] = funcBase64
 	r.funcs["base64Decode"] = funcBase64Decode
+	r.funcs["base64decode"] = funcBase64Decode
 	r.funcs["md5"] = funcMD5
 	r.funcs["sha256"] = funcSHA256
 	r.funcs["urlEncode"] = funcURLEncode
 	r.funcs["urlDecode"] = funcURLDecode
+	r.funcs["urlencode"] = funcURLEncode
+	r.funcs["urldecode"] = funcURLDecode
 	r.funcs["date"] = funcDate
 	r.funcs["json"] = funcJSON
 	r.funcs["env"] = funcEnv
@@ -57,6 +63,9 @@ func (r *Registry) Register(name string, fn Func) {
 
 var funcCallPattern = regexp.MustCompile(`^(\w+)\((.*)\)$`)
 
+// Call evaluates a function call expression such as "uuid()". The bool
+// reports whether expr named a registered function; a function that fails
+// returns its error as the result, which callers must not render.
 func (r *Registry) Call(expr string) (any, bool) {
 	matches := funcCallPattern.FindStringSubmatch(expr)
 	if matches == nil {
@@ -72,7 +81,9 @@ func (r *Registry) Call(expr string) (any, bool) {
 	}
 
 	var args []string
-	if argsStr != "" {
+	if r.rawArgs[name] {
+		args = []string{argsStr}
+	} else if argsStr != "" {
 		args = parseArgs(argsStr)
 	}
 
@@ -178,15 +189,24 @@ func funcBase64(args []string) any {
 	return base64.StdEncoding.EncodeToString([]byte(args[0]))
 }
 
+// funcBase64Decode decodes standard or URL-safe base64, with or without
+// padding, so JWT segments decode as well as Basic auth credentials
 func funcBase64Decode(args []string) any {
 	if len(args) < 1 {
 		return ""
 	}
-	decoded, err := base64.StdEncoding.DecodeString(args[0])
-	if err != nil {
-		return ""
+	value := strings.TrimSpace(args[0])
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	} {
+		if decoded, err := enc.DecodeString(value); err == nil {
+			return string(decoded)
+		}
 	}
-	return string(decoded)
+	return ""
 }
 
 func funcMD5(args []string) any {
@@ -231,11 +251,26 @@ func funcDate(args []string) any {
 	return time.Now().UTC().Format(format)
 }
 
+// funcJSON re-serializes its argument as canonical JSON: compact, with
+// object keys sorted. Invalid JSON is returned as an error.
 func funcJSON(args []string) any {
-	if len(args) < 1 {
+	if len(args) < 1 || strings.TrimSpace(args[0]) == "" {
 		return ""
 	}
-	return args[0]
+	decoder := json.NewDecoder(strings.NewReader(args[0]))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid JSON: unexpected data after value")
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return string(canonical)
 }
 
 func randomString(length int, charset string) string {
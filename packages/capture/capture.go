@@ -1,6 +1,12 @@
 package capture
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/abdul-hamid-achik/hitspec/packages/http"
 	"github.com/tidwall/gjson"
@@ -15,8 +21,8 @@ func NewExtractor(resp *http.Response) *Extractor {
 	e := &Extractor{
 		response: resp,
 	}
-	if resp.IsJSON() {
-		e.bodyJSON = gjson.ParseBytes(resp.Body)
+	if resp.IsJSON() || resp.IsNDJSON() {
+		e.bodyJSON = gjson.ParseBytes(resp.JSONBody())
 	}
 	return e
 }
@@ -27,6 +33,10 @@ func (e *Extractor) Extract(capture *parser.Capture) (any, bool) {
 		return e.extractFromBody(capture.Path)
 	case parser.CaptureHeader:
 		return e.extractFromHeader(capture.Path)
+	case parser.CaptureHeaderValues:
+		return e.extractHeaderValues(capture.Path, capture.Index)
+	case parser.CaptureCookie:
+		return e.response.Cookie(capture.Path)
 	case parser.CaptureStatus:
 		return e.response.StatusCode, true
 	case parser.CaptureDuration:
@@ -63,15 +73,172 @@ func (e *Extractor) extractFromHeader(name string) (any, bool) {
 	return value, true
 }
 
+// extractHeaderValues returns all values of a multi-value header as an array,
+// or the value at index when index is non-negative
+func (e *Extractor) extractHeaderValues(name string, index int) (any, bool) {
+	values := e.response.HeaderValues(name)
+	if len(values) == 0 {
+		return nil, false
+	}
+	if index >= 0 {
+		if index >= len(values) {
+			return nil, false
+		}
+		return values[index], true
+	}
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result, true
+}
+
+// Response returns the whole response as a structured value with status,
+// headers, body and duration fields. JSON bodies are decoded, other bodies are
+// kept as strings.
+func Response(resp *http.Response) map[string]any {
+	headers := make(map[string]any, len(resp.Headers))
+	for k, v := range resp.Headers {
+		headers[k] = v
+	}
+
+	var body any = resp.BodyString()
+	if resp.IsJSON() || resp.IsNDJSON() {
+		if parsed, err := resp.BodyJSON(); err == nil {
+			body = parsed
+		}
+	}
+
+	return map[string]any{
+		"status":   resp.StatusCode,
+		"headers":  headers,
+		"body":     body,
+		"duration": resp.DurationMs(),
+	}
+}
+
 func ExtractAll(resp *http.Response, captures []*parser.Capture) map[string]any {
 	extractor := NewExtractor(resp)
 	results := make(map[string]any)
 
 	for _, c := range captures {
-		if value, ok := extractor.Extract(c); ok {
+		value, ok := extractor.Extract(c)
+		if ok && len(c.Transforms) > 0 {
+			value, ok = Transform(value, c.Transforms)
+		}
+		if ok && c.Type != "" {
+			value, ok = Convert(value, c.Type)
+		}
+		if ok {
 			results[c.Name] = value
 		}
 	}
 
 	return results
 }
+
+// Transform applies a capture's transforms to value in order. It reports
+// false when one doesn't apply, such as length of a number or division by zero.
+func Transform(value any, transforms []parser.CaptureTransform) (any, bool) {
+	for _, t := range transforms {
+		if t.Op == "length" {
+			switch v := value.(type) {
+			case string:
+				value = len([]rune(v))
+			case []any:
+				value = len(v)
+			case map[string]any:
+				value = len(v)
+			default:
+				return nil, false
+			}
+			continue
+		}
+
+		n, ok := Convert(value, "float")
+		if !ok {
+			return nil, false
+		}
+		f := n.(float64)
+		switch t.Op {
+		case "+":
+			f += t.Operand
+		case "-":
+			f -= t.Operand
+		case "*":
+			f *= t.Operand
+		case "/":
+			if t.Operand == 0 {
+				return nil, false
+			}
+			f /= t.Operand
+		default:
+			return nil, false
+		}
+		// Whole results stay integers, so {{count}} renders as 3 rather than 3.0
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			value = int(f)
+		} else {
+			value = f
+		}
+	}
+	return value, true
+}
+
+// Convert converts a captured value to typ ("int", "float", "string" or
+// "bool"). It reports false when the value can't be represented, such as a
+// non-integral number as int.
+func Convert(value any, typ string) (any, bool) {
+	switch typ {
+	case "int":
+		switch v := value.(type) {
+		case int:
+			return v, true
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, false
+			}
+			return int(v), true
+		case int64:
+			return int(v), true
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			return n, err == nil
+		}
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case int:
+			return float64(v), true
+		case int64:
+			return float64(v), true
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return f, err == nil
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			return b, err == nil
+		}
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case map[string]any, []any:
+			data, err := json.Marshal(v)
+			return string(data), err == nil
+		case nil:
+			return "", true
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+	return nil, false
+}
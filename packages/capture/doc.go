@@ -5,6 +5,9 @@
 //   - Response headers
 //   - Response status code
 //
+// The whole response of every named request is also available as
+// {{requestName.$response.status}}, {{requestName.$response.body.path}} etc.
+//
 // Captured values can be used in later requests via the {{requestName.captureName}} syntax,
 // enabling request chaining and dependent test scenarios.
 package capture
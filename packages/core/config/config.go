@@ -1,8 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,7 +23,9 @@ type Config struct {
 	MaxRedirects       int                          `json:"maxRedirects,omitempty" yaml:"maxRedirects,omitempty"`
 	ValidateSSL        *bool                        `json:"validateSSL,omitempty" yaml:"validateSSL,omitempty"`
 	Proxy              string                       `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	UserAgent          *string                      `json:"userAgent,omitempty" yaml:"userAgent,omitempty"`       // User-Agent for all requests; "" sends none
 	Headers            map[string]string            `json:"headers,omitempty" yaml:"headers,omitempty"`           // Default headers for all requests
+	HeadersByHost      map[string]map[string]string `json:"headersByHost,omitempty" yaml:"headersByHost,omitempty"` // Default headers for hosts matching a glob
 	Reporters          []string                     `json:"reporters,omitempty" yaml:"reporters,omitempty"`       // Output reporters
 	OutputDir          string                       `json:"outputDir,omitempty" yaml:"outputDir,omitempty"`       // Directory for output files
 	Parallel           *bool                        `json:"parallel,omitempty" yaml:"parallel,omitempty"`
@@ -27,6 +35,8 @@ type Config struct {
 	NoColor            *bool                        `json:"noColor,omitempty" yaml:"noColor,omitempty"`
 	Environments       map[string]map[string]any    `json:"environments,omitempty" yaml:"environments,omitempty"` // Inline environments
 	Stress             *StressConfig                `json:"stress,omitempty" yaml:"stress,omitempty"`             // Stress test configuration
+	SecureHeaders      map[string]string            `json:"secureHeaders,omitempty" yaml:"secureHeaders,omitempty"` // Headers checked by expect secure-headers
+	TagMap             map[string][]string          `json:"tagMap,omitempty" yaml:"tagMap,omitempty"`             // Tags selected by --tags-from-changed for changed paths matching a glob
 }
 
 // StressConfig holds stress testing configuration
@@ -122,7 +132,25 @@ func FindAndLoadConfig(dir string) (*Config, error) {
 	return DefaultConfig(), nil
 }
 
-// loadConfigFromFile loads configuration from a specific file
+// ConfigError reports unknown keys and mistyped values in a config file. The
+// rest of the file is still loaded, so callers can warn and carry on.
+type ConfigError struct {
+	Path     string
+	Problems []string // One per problem, prefixed with its line number
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, strings.Join(e.Problems, "; "))
+}
+
+var (
+	unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type \S+`)
+	mistypedPattern     = regexp.MustCompile("cannot unmarshal !!\\w+ `(.*)` into \\*?(\\S+)")
+)
+
+// loadConfigFromFile loads configuration from a specific file. Unknown keys
+// and type mismatches are returned as a *ConfigError along with the config;
+// YAML syntax errors return no config.
 func loadConfigFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -130,10 +158,28 @@ func loadConfigFromFile(path string) (*Config, error) {
 	}
 
 	config := DefaultConfig()
-	if err := yaml.Unmarshal(data, config); err != nil {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(config)
+	if err == io.EOF {
+		return config, nil // Empty file
+	}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		problems := make([]string, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			msg = unknownFieldPattern.ReplaceAllString(msg, "unknown key \"$1\"")
+			problems[i] = mistypedPattern.ReplaceAllString(msg, "invalid value \"$1\", expected $2")
+		}
+		expandConfig(config)
+		return config, &ConfigError{Path: path, Problems: problems}
+	}
+	if err != nil {
 		return nil, err
 	}
 
+	expandConfig(config)
 	return config, nil
 }
 
@@ -163,6 +209,9 @@ func (c *Config) Merge(other *Config) *Config {
 	if other.Proxy != "" {
 		result.Proxy = other.Proxy
 	}
+	if other.UserAgent != nil {
+		result.UserAgent = other.UserAgent
+	}
 	if other.OutputDir != "" {
 		result.OutputDir = other.OutputDir
 	}
@@ -200,6 +249,36 @@ func (c *Config) Merge(other *Config) *Config {
 		}
 	}
 
+	// Merge host-scoped headers
+	if len(other.HeadersByHost) > 0 {
+		if result.HeadersByHost == nil {
+			result.HeadersByHost = make(map[string]map[string]string)
+		}
+		for pattern, headers := range other.HeadersByHost {
+			if result.HeadersByHost[pattern] == nil {
+				result.HeadersByHost[pattern] = make(map[string]string)
+			}
+			for k, v := range headers {
+				result.HeadersByHost[pattern][k] = v
+			}
+		}
+	}
+
+	// The security baseline is replaced rather than merged, so headers can be dropped
+	if len(other.SecureHeaders) > 0 {
+		result.SecureHeaders = other.SecureHeaders
+	}
+
+	// Merge the tag map; a glob's tags are replaced as a whole
+	if len(other.TagMap) > 0 {
+		if result.TagMap == nil {
+			result.TagMap = make(map[string][]string)
+		}
+		for pattern, tags := range other.TagMap {
+			result.TagMap[pattern] = tags
+		}
+	}
+
 	// Merge reporters
 	if len(other.Reporters) > 0 {
 		result.Reporters = other.Reporters
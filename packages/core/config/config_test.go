@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hitspec.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadConfig_Valid(t *testing.T) {
+	path := writeConfig(t, `timeout: 5000
+environments:
+  dev:
+    baseUrl: http://localhost:3000
+`)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, cfg.Timeout)
+	assert.Equal(t, "http://localhost:3000", cfg.Environments["dev"]["baseUrl"])
+}
+
+func TestLoadConfig_Problems(t *testing.T) {
+	path := writeConfig(t, `environmnts:
+  dev: {}
+timeout: soon
+bail: true
+stress:
+  profiles:
+    smoke:
+      rat: 5
+`)
+
+	cfg, err := LoadConfig(path)
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr), "got %v", err)
+	assert.Equal(t, []string{
+		`line 1: unknown key "environmnts"`,
+		`line 3: invalid value "soon", expected int`,
+		`line 8: unknown key "rat"`,
+	}, cfgErr.Problems)
+
+	// The valid keys are still loaded
+	require.NotNil(t, cfg)
+	assert.True(t, cfg.GetBail())
+}
+
+func TestLoadConfig_SyntaxError(t *testing.T) {
+	path := writeConfig(t, "timeout: [\n")
+
+	cfg, err := LoadConfig(path)
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+	var cfgErr *ConfigError
+	assert.False(t, errors.As(err, &cfgErr))
+}
+
+func TestLoadConfig_Empty(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, ""))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+}
+
+func TestLoadConfig_UserAgent(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "userAgent: hitspec-ci\n"))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.UserAgent)
+	assert.Equal(t, "hitspec-ci", *cfg.UserAgent)
+
+	// An empty userAgent is kept, so no User-Agent is sent
+	cfg, err = LoadConfig(writeConfig(t, "userAgent: \"\"\n"))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.UserAgent)
+	assert.Empty(t, *cfg.UserAgent)
+
+	cfg, err = LoadConfig(writeConfig(t, "timeout: 5000\n"))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.UserAgent)
+}
+
+func TestConfig_TagsForPaths(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `tagMap:
+  "services/users/**": [users]
+  "services/billing": [billing, payments]
+  "**/*.proto": [contracts]
+  "docs/*.md": [docs]
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+	}{
+		{"nested file", []string{"services/users/api/handler.go"}, []string{"users"}},
+		{"directory prefix", []string{"services/billing/invoice.go"}, []string{"billing", "payments"}},
+		{"double star at start", []string{"proto/v1/user.proto"}, []string{"contracts"}},
+		{"single star stays in directory", []string{"docs/guides/setup.md"}, []string{}},
+		{"several paths", []string{"./docs/index.md", "services/users/x.go"}, []string{"docs", "users"}},
+		{"no match", []string{"README.md"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cfg.TagsForPaths(tt.paths))
+		})
+	}
+}
+
+func TestLoadConfig_ExpandsEnv(t *testing.T) {
+	t.Setenv("HITSPEC_TEST_PROXY", "http://proxy:3128")
+	t.Setenv("HITSPEC_TEST_TOKEN", "secret")
+	t.Setenv("HITSPEC_TEST_EMPTY", "")
+
+	cfg, err := LoadConfig(writeConfig(t, `proxy: ${HITSPEC_TEST_PROXY}
+outputDir: ${HITSPEC_TEST_UNSET:-reports}/${HITSPEC_TEST_EMPTY:-ci}
+headers:
+  Authorization: Bearer ${HITSPEC_TEST_TOKEN}
+  X-Missing: "${HITSPEC_TEST_UNSET}"
+reporters: ["${HITSPEC_TEST_UNSET:-json}"]
+stress:
+  profiles:
+    load:
+      duration: ${HITSPEC_TEST_UNSET:-1m}
+environments:
+  dev:
+    baseUrl: ${HITSPEC_TEST_HOST:-http://localhost}/v1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", cfg.Proxy)
+	assert.Equal(t, "reports/ci", cfg.OutputDir)
+	assert.Equal(t, "Bearer secret", cfg.Headers["Authorization"])
+	assert.Equal(t, "", cfg.Headers["X-Missing"])
+	assert.Equal(t, []string{"json"}, cfg.Reporters)
+	assert.Equal(t, "1m", cfg.Stress.Profiles["load"].Duration)
+	// Environments are expanded when loaded, after --env-file is read
+	assert.Equal(t, "${HITSPEC_TEST_HOST:-http://localhost}/v1", cfg.Environments["dev"]["baseUrl"])
+}
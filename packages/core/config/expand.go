@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+)
+
+// expandConfig expands ${VAR} and ${VAR:-default} in every string value of c,
+// including values nested in maps and slices; map keys are left as written.
+// Environments are expanded when one is loaded instead, so they can use
+// variables from --env-file, which is read after the config.
+func expandConfig(c *Config) {
+	environments := c.Environments
+	c.Environments = nil
+	expandValue(reflect.ValueOf(c).Elem())
+	c.Environments = environments
+}
+
+func expandValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(env.ExpandEnv(v.String()))
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandValue(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				expandValue(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values aren't addressable, so expand a copy and store it back
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			expandValue(value)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			value := reflect.New(v.Elem().Type()).Elem()
+			value.Set(v.Elem())
+			expandValue(value)
+			v.Set(value)
+		}
+	}
+}
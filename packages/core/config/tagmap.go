@@ -0,0 +1,75 @@
+package config
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// TagsForPaths returns the tags that the tag map assigns to paths, sorted.
+// Paths and globs are slash-separated and relative to the repository root;
+// a glob matches a path or any directory containing it, and ** matches any
+// number of directories.
+func (c *Config) TagsForPaths(paths []string) []string {
+	seen := make(map[string]bool)
+	for pattern, tags := range c.TagMap {
+		for _, p := range paths {
+			if !matchPathGlob(pattern, p) {
+				continue
+			}
+			for _, t := range tags {
+				seen[t] = true
+			}
+			break
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for t := range seen {
+		result = append(result, t)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// matchPathGlob reports whether pattern matches p or one of its parent
+// directories
+func matchPathGlob(pattern, p string) bool {
+	patternParts := splitPath(pattern)
+	pathParts := splitPath(p)
+	for n := len(pathParts); n > 0; n-- {
+		if matchParts(patternParts, pathParts[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+		return false
+	}
+	return matchParts(pattern[1:], parts[1:])
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(path.Clean(strings.TrimPrefix(p, "./")), "/")
+	if p == "." || p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
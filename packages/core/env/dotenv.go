@@ -2,8 +2,10 @@ package env
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -79,3 +81,56 @@ func LoadAndExportDotEnv(path string) (map[string]string, error) {
 
 	return vars, nil
 }
+
+// WriteDotEnv writes vars to a .env file that LoadDotEnv reads back, merging
+// them into the variables already in the file. The file is only readable by
+// its owner since it usually holds tokens. Values containing both quote
+// characters or line breaks can't be represented and are an error.
+func WriteDotEnv(path string, vars map[string]string) error {
+	merged := make(map[string]string)
+	if existing, err := LoadDotEnv(path); err == nil {
+		merged = existing
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		value, err := quoteDotEnv(merged[k])
+		if err != nil {
+			return fmt.Errorf("writing %s to env file: %w", k, err)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", k, value)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// quoteDotEnv quotes a value when LoadDotEnv would otherwise change it
+func quoteDotEnv(value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("value spans lines")
+	}
+	if value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\"'") {
+		return value, nil
+	}
+	if !strings.Contains(value, "\"") {
+		return "\"" + value + "\"", nil
+	}
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'", nil
+	}
+	return "", fmt.Errorf("value contains both quote characters")
+}
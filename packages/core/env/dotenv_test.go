@@ -125,3 +125,139 @@ func TestLoadDotEnvFileNotFound(t *testing.T) {
 		t.Error("LoadDotEnv() expected error for non-existent file")
 	}
 }
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("HITSPEC_TEST_HOST", "api.example.com")
+	t.Setenv("HITSPEC_TEST_EMPTY", "")
+
+	tests := map[string]string{
+		"https://${HITSPEC_TEST_HOST}/v1":          "https://api.example.com/v1",
+		"${HITSPEC_TEST_HOST:-localhost}":          "api.example.com",
+		"${HITSPEC_TEST_UNSET:-localhost:8080}/v1": "localhost:8080/v1",
+		"${HITSPEC_TEST_EMPTY:-fallback}":          "fallback",
+		"${HITSPEC_TEST_UNSET}":                    "",
+		"${HITSPEC_TEST_UNSET:-}":                  "",
+		"no references":                            "no references",
+	}
+	for input, want := range tests {
+		if got := ExpandEnv(input); got != want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env.captures")
+	if err := os.WriteFile(envFile, []byte("KEEP=kept\nTOKEN=old"), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	vars := map[string]string{
+		"TOKEN":  "new",
+		"SPACED": " padded value ",
+		"QUOTED": `say "hi"`,
+		"EMPTY":  "",
+	}
+	if err := WriteDotEnv(envFile, vars); err != nil {
+		t.Fatalf("WriteDotEnv() error = %v", err)
+	}
+
+	result, err := LoadDotEnv(envFile)
+	if err != nil {
+		t.Fatalf("LoadDotEnv() error = %v", err)
+	}
+	vars["KEEP"] = "kept"
+	if len(result) != len(vars) {
+		t.Errorf("LoadDotEnv() returned %d keys, want %d", len(result), len(vars))
+	}
+	for k, v := range vars {
+		if got := result[k]; got != v {
+			t.Errorf("LoadDotEnv()[%q] = %q, want %q", k, got, v)
+		}
+	}
+
+	info, err := os.Stat(envFile)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("env file mode = %v, want 0600", perm)
+	}
+}
+
+func TestWriteDotEnvMultiline(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := WriteDotEnv(envFile, map[string]string{"CERT": "line1\nline2"}); err == nil {
+		t.Error("WriteDotEnv() expected error for a multi-line value")
+	}
+}
+
+func TestLoadDotEnvLayers(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		".env":         "BASE=env\nSHARED=env\nLOCAL=env",
+		".env.staging": "SHARED=staging\nLOCAL=staging",
+		".env.local":   "LOCAL=local",
+		".env.prod":    "SHARED=prod",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	result, err := LoadDotEnvLayers(tmpDir, "staging")
+	if err != nil {
+		t.Fatalf("LoadDotEnvLayers() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"BASE":   "env",
+		"SHARED": "staging",
+		"LOCAL":  "local",
+	}
+	if len(result) != len(expected) {
+		t.Errorf("LoadDotEnvLayers() returned %d keys, want %d", len(result), len(expected))
+	}
+	for k, v := range expected {
+		if got := result[k]; got != v {
+			t.Errorf("LoadDotEnvLayers()[%q] = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestLoadDotEnvLayersMissingFiles(t *testing.T) {
+	result, err := LoadDotEnvLayers(t.TempDir(), "dev")
+	if err != nil {
+		t.Fatalf("LoadDotEnvLayers() error = %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("LoadDotEnvLayers() returned %d keys, want 0", len(result))
+	}
+}
+
+func TestResolverDotEnvPrecedence(t *testing.T) {
+	t.Setenv("HITSPEC_TEST_PROCESS", "process")
+	t.Setenv("HITSPEC_TEST_FILE", "process")
+
+	r := NewResolver()
+	r.SetDotEnvLayers(map[string]string{
+		"HITSPEC_TEST_LAYER":   "layer",
+		"HITSPEC_TEST_PROCESS": "layer",
+		"HITSPEC_TEST_FILE":    "layer",
+	})
+	r.dotenv["HITSPEC_TEST_FILE"] = "env-file"
+
+	tests := map[string]string{
+		"{{HITSPEC_TEST_LAYER}}":    "layer",
+		"{{HITSPEC_TEST_PROCESS}}":  "process",
+		"{{HITSPEC_TEST_FILE}}":     "env-file",
+		"{{$HITSPEC_TEST_LAYER}}":   "layer",
+		"{{$HITSPEC_TEST_PROCESS}}": "process",
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
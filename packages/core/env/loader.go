@@ -1,11 +1,27 @@
 package env
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
 )
 
-var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+var envVarPattern = regexp.MustCompile(`\$\{([^}:]+)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} with the process environment variable VAR, and
+// ${VAR:-default} with default when VAR is unset or empty. Unset variables
+// without a default expand to an empty string.
+func ExpandEnv(s string) string {
+	return envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := envVarPattern.FindStringSubmatch(match)
+		if value := os.Getenv(m[1]); value != "" || m[2] == "" {
+			return value
+		}
+		return m[3]
+	})
+}
 
 // resolveEnvVars replaces ${VAR} patterns with actual environment variable values
 func resolveEnvVars(value any) any {
@@ -13,15 +29,13 @@ func resolveEnvVars(value any) any {
 	if !ok {
 		return value
 	}
-	return envVarPattern.ReplaceAllStringFunc(str, func(match string) string {
-		varName := match[2 : len(match)-1] // Extract VAR from ${VAR}
-		return os.Getenv(varName)
-	})
+	return ExpandEnv(str)
 }
 
 type Environment struct {
 	Name      string
 	Variables map[string]any
+	DotEnv    map[string]string // Merged .env, .env.<name> and .env.local values
 }
 
 func LoadEnvironment(dir, envName string, configEnvs map[string]map[string]any) (*Environment, error) {
@@ -30,6 +44,12 @@ func LoadEnvironment(dir, envName string, configEnvs map[string]map[string]any)
 		Variables: make(map[string]any),
 	}
 
+	dotenv, err := LoadDotEnvLayers(dir, envName)
+	if err != nil {
+		return nil, err
+	}
+	env.DotEnv = dotenv
+
 	// Load from hitspec.yaml environments section
 	if configEnvs != nil {
 		if vars, ok := configEnvs[envName]; ok {
@@ -42,6 +62,33 @@ func LoadEnvironment(dir, envName string, configEnvs map[string]map[string]any)
 	return env, nil
 }
 
+// LoadDotEnvLayers loads the dotenv files found in dir, later files overriding
+// earlier ones: .env < .env.<envName> < .env.local. Missing files are skipped.
+// Process environment variables and an explicit --env-file take precedence
+// over every layer; the resolver applies that part of the ordering.
+func LoadDotEnvLayers(dir, envName string) (map[string]string, error) {
+	names := []string{".env"}
+	if envName != "" && envName != "local" {
+		names = append(names, ".env."+envName)
+	}
+	names = append(names, ".env.local")
+
+	result := make(map[string]string)
+	for _, name := range names {
+		vars, err := LoadDotEnv(filepath.Join(dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for k, v := range vars {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
 func MergeVariables(sources ...map[string]any) map[string]any {
 	result := make(map[string]any)
 	for _, src := range sources {
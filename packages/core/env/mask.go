@@ -0,0 +1,34 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaskedValue replaces the value of sensitive variables in printed output
+const MaskedValue = "****"
+
+// sensitiveNameParts are name fragments that mark a variable as a secret
+var sensitiveNameParts = []string{
+	"token", "secret", "password", "passwd", "pwd", "key",
+	"auth", "cookie", "credential", "session", "private", "signature",
+}
+
+// IsSensitiveName reports whether a variable name looks like it holds a secret
+func IsSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskValue formats a variable value for display, redacting secrets
+func MaskValue(name string, value any) string {
+	if IsSensitiveName(name) {
+		return MaskedValue
+	}
+	return fmt.Sprintf("%v", value)
+}
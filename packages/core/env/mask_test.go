@@ -0,0 +1,27 @@
+package env
+
+import "testing"
+
+func TestMaskValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"baseUrl", "https://staging.example.com", "https://staging.example.com"},
+		{"timeout", 5000, "5000"},
+		{"token", "abc123", MaskedValue},
+		{"API_KEY", "abc123", MaskedValue},
+		{"dbPassword", "hunter2", MaskedValue},
+		{"authHeader", "Bearer x", MaskedValue},
+		{"clientSecret", "s3cr3t", MaskedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskValue(tt.name, tt.value); got != tt.want {
+				t.Errorf("MaskValue(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
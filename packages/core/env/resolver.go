@@ -1,6 +1,7 @@
 package env
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
@@ -8,10 +9,14 @@ import (
 	"sync"
 
 	"github.com/abdul-hamid-achik/hitspec/packages/builtin"
+	"github.com/tidwall/gjson"
 )
 
 var variablePattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
+// responseKey addresses the stored response of a request, {{name.$response}}
+const responseKey = "$response"
+
 // WarnFunc is a function type for handling warnings
 type WarnFunc func(format string, args ...any)
 
@@ -22,16 +27,21 @@ type Resolver struct {
 	mu        sync.RWMutex
 	variables map[string]any
 	captures  map[string]any
-	dotenv    map[string]string
+	responses map[string][]byte // Whole responses of named requests, as JSON
+	dotenv    map[string]string // From --env-file, overrides the process environment
+	layers    map[string]string // From .env, .env.<environment> and .env.local
 	funcs     *builtin.Registry
 	warnFunc  WarnFunc
+	hermetic  bool // Ignore the process environment
 }
 
 func NewResolver() *Resolver {
 	return &Resolver{
 		variables: make(map[string]any),
 		captures:  make(map[string]any),
+		responses: make(map[string][]byte),
 		dotenv:    make(map[string]string),
+		layers:    make(map[string]string),
 		funcs:     builtin.NewRegistry(),
 	}
 }
@@ -52,6 +62,69 @@ func (r *Resolver) LoadDotEnv(path string) error {
 	return nil
 }
 
+// SetDotEnvLayers replaces the values loaded from layered dotenv files. They
+// have the lowest precedence: process environment variables and values from
+// LoadDotEnv override them.
+func (r *Resolver) SetDotEnvLayers(vars map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.layers = make(map[string]string, len(vars))
+	for k, v := range vars {
+		r.layers[k] = v
+	}
+}
+
+// SetHermetic makes the resolver ignore the process environment, both for
+// variable lookups and the $env() builtin, which then only returns its default.
+func (r *Resolver) SetHermetic(hermetic bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hermetic = hermetic
+	if hermetic {
+		r.funcs.Register("env", func(args []string) any {
+			if len(args) >= 2 {
+				return args[1]
+			}
+			return ""
+		})
+	} else {
+		r.funcs = builtin.NewRegistry()
+	}
+}
+
+// lookupEnv resolves a name from --env-file values, the process environment
+// and dotenv layers, in that order.
+func (r *Resolver) lookupEnv(name string) (string, bool) {
+	r.mu.RLock()
+	if val, ok := r.dotenv[name]; ok {
+		r.mu.RUnlock()
+		return val, true
+	}
+	hermetic := r.hermetic
+	r.mu.RUnlock()
+	if val := os.Getenv(name); val != "" && !hermetic {
+		return val, true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	val, ok := r.layers[name]
+	return val, ok
+}
+
+// IsDefined reports whether name resolves to a capture, variable, dotenv value
+// or process environment variable.
+func (r *Resolver) IsDefined(name string) bool {
+	r.mu.RLock()
+	_, isCapture := r.captures[name]
+	_, isVariable := r.variables[name]
+	r.mu.RUnlock()
+	if isCapture || isVariable {
+		return true
+	}
+	_, ok := r.lookupEnv(name)
+	return ok
+}
+
 // SetWarnFunc sets a function to be called when warnings occur (e.g., unresolved variables)
 func (r *Resolver) SetWarnFunc(fn WarnFunc) {
 	r.mu.Lock()
@@ -90,6 +163,44 @@ func (r *Resolver) SetCapture(requestName, captureName string, value any) {
 	r.captures[captureName] = value
 }
 
+// SetResponse stores the structured response of a request so it can be
+// referenced as {{requestName.$response.path}}, e.g. {{login.$response.status}}
+// or {{login.$response.body.user.id}}.
+func (r *Resolver) SetResponse(requestName string, response any) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		r.warn("storing response of %s: %v", requestName, err)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[requestName] = data
+}
+
+// LookupResponse resolves a requestName.$response[.path] expression. Objects
+// and arrays are returned as JSON.
+func (r *Resolver) LookupResponse(expr string) (string, bool) {
+	name, path, ok := strings.Cut(expr, "."+responseKey)
+	if !ok || (path != "" && path[0] != '.') {
+		return "", false
+	}
+	r.mu.RLock()
+	data, ok := r.responses[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return string(data), true
+	}
+	result := gjson.GetBytes(data, path)
+	if !result.Exists() {
+		return "", false
+	}
+	return result.String(), true
+}
+
 func (r *Resolver) GetCapture(name string) (any, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -97,30 +208,90 @@ func (r *Resolver) GetCapture(name string) (any, bool) {
 	return v, ok
 }
 
+// ReplaceVariables calls fn for each {{...}} reference in input and replaces
+// the reference with its result. References may be nested, as in
+// {{$urlEncode({{next}})}}: inner references are replaced first, and fn gets
+// the outer reference with their values in place.
+func ReplaceVariables(input string, fn func(match string) string) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(input, "{{")
+		if start < 0 {
+			result.WriteString(input)
+			return result.String()
+		}
+		end := closingBraces(input, start)
+		if end < 0 {
+			result.WriteString(input[:start+2])
+			input = input[start+2:]
+			continue
+		}
+
+		inner := input[start+2 : end]
+		if strings.Contains(inner, "{{") {
+			inner = ReplaceVariables(inner, fn)
+		}
+		result.WriteString(input[:start])
+		if strings.TrimSpace(inner) == "" {
+			result.WriteString(input[start : end+2])
+		} else {
+			result.WriteString(fn("{{" + inner + "}}"))
+		}
+		input = input[end+2:]
+	}
+}
+
+// closingBraces returns the index of the }} closing the {{ at start, or -1.
+// Braces inside function arguments, like {{$json({"a":{"b":1}})}}, don't
+// close the reference.
+func closingBraces(input string, start int) int {
+	depth, parens := 0, 0
+	for i := start; i < len(input)-1; i++ {
+		switch {
+		case input[i] == '(':
+			parens++
+		case input[i] == ')' && parens > 0:
+			parens--
+		case input[i] == '{' && input[i+1] == '{':
+			depth++
+			i++
+		case input[i] == '}' && input[i+1] == '}' && (parens == 0 || depth > 1):
+			depth--
+			if depth == 0 {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
 func (r *Resolver) Resolve(input string) string {
-	return variablePattern.ReplaceAllStringFunc(input, func(match string) string {
+	return ReplaceVariables(input, func(match string) string {
 		expr := match[2 : len(match)-2]
 		expr = strings.TrimSpace(expr)
 
+		// {{> path}} body partials are expanded by the http package
+		if strings.HasPrefix(expr, ">") {
+			return match
+		}
+
 		if strings.HasPrefix(expr, "$") {
 			funcExpr := expr[1:] // strip the $
 			// Check if it's a function call (has parentheses)
 			if strings.Contains(funcExpr, "(") {
 				if result, ok := r.funcs.Call(funcExpr); ok {
+					if err, isErr := result.(error); isErr {
+						r.warn("%s: %v", expr[:strings.Index(expr, "(")], err)
+						return match
+					}
 					return fmt.Sprintf("%v", result)
 				}
 				r.warn("unresolved function call: %s", expr)
 				return match
 			}
 			// Otherwise treat as environment variable
-			// Check dotenv first, then OS env
-			r.mu.RLock()
-			if val, ok := r.dotenv[funcExpr]; ok {
-				r.mu.RUnlock()
-				return val
-			}
-			r.mu.RUnlock()
-			if val := os.Getenv(funcExpr); val != "" {
+			if val, ok := r.lookupEnv(funcExpr); ok {
 				return val
 			}
 			r.warn("unresolved environment variable: $%s", funcExpr)
@@ -130,12 +301,24 @@ func (r *Resolver) Resolve(input string) string {
 		// Keep for backward compatibility with non-$ function calls
 		if strings.Contains(expr, "(") {
 			if result, ok := r.funcs.Call(expr); ok {
+				if err, isErr := result.(error); isErr {
+					r.warn("%s: %v", expr[:strings.Index(expr, "(")], err)
+					return match
+				}
 				return fmt.Sprintf("%v", result)
 			}
 			r.warn("unresolved function call: %s", expr)
 			return match
 		}
 
+		if strings.Contains(expr, "."+responseKey) {
+			if val, ok := r.LookupResponse(expr); ok {
+				return val
+			}
+			r.warn("unresolved response reference: %s", expr)
+			return match
+		}
+
 		r.mu.RLock()
 		if val, ok := r.captures[expr]; ok {
 			r.mu.RUnlock()
@@ -147,15 +330,10 @@ func (r *Resolver) Resolve(input string) string {
 			return fmt.Sprintf("%v", val)
 		}
 
-		// Check dotenv file
-		if val, ok := r.dotenv[expr]; ok {
-			r.mu.RUnlock()
-			return val
-		}
 		r.mu.RUnlock()
 
-		// Fallback to OS environment variable
-		if val := os.Getenv(expr); val != "" {
+		// Fallback to dotenv files and OS environment variables
+		if val, ok := r.lookupEnv(expr); ok {
 			return val
 		}
 
@@ -196,6 +374,27 @@ func (r *Resolver) GetVariable(name string) (any, bool) {
 	return nil, false
 }
 
+// Snapshot returns a copy of every value visible to {{name}} lookups. Captures
+// take precedence over variables, which take precedence over dotenv values.
+func (r *Resolver) Snapshot() map[string]any {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]any, len(r.layers)+len(r.dotenv)+len(r.variables)+len(r.captures))
+	for k, v := range r.layers {
+		result[k] = v
+	}
+	for k, v := range r.dotenv {
+		result[k] = v
+	}
+	for k, v := range r.variables {
+		result[k] = v
+	}
+	for k, v := range r.captures {
+		result[k] = v
+	}
+	return result
+}
+
 func (r *Resolver) Clone() *Resolver {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -209,13 +408,22 @@ func (r *Resolver) Clone() *Resolver {
 	for k, v := range r.dotenv {
 		clone.dotenv[k] = v
 	}
+	for k, v := range r.layers {
+		clone.layers[k] = v
+	}
+	for k, v := range r.responses {
+		clone.responses[k] = v
+	}
+	if r.hermetic {
+		clone.SetHermetic(true)
+	}
+	clone.warnFunc = r.warnFunc
 	return clone
 }
 
 // HasUnresolvedVariables checks if the resolved string still contains unresolved {{...}} variables
 func (r *Resolver) HasUnresolvedVariables(input string) bool {
-	resolved := r.Resolve(input)
-	return variablePattern.MatchString(resolved)
+	return len(r.GetUnresolvedVariables(input)) > 0
 }
 
 // GetUnresolvedVariables returns a list of variable names that could not be resolved
@@ -227,9 +435,12 @@ func (r *Resolver) GetUnresolvedVariables(input string) []string {
 	}
 	vars := make([]string, 0, len(matches))
 	for _, match := range matches {
-		if len(match) > 1 {
+		if len(match) > 1 && !strings.HasPrefix(strings.TrimSpace(match[1]), ">") {
 			vars = append(vars, match[1])
 		}
 	}
+	if len(vars) == 0 {
+		return nil
+	}
 	return vars
 }
@@ -1,6 +1,8 @@
 package env
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -117,6 +119,12 @@ func TestResolverGetUnresolvedVariables(t *testing.T) {
 			variables: nil,
 			expected:  []string{"setupProject.projectId"},
 		},
+		{
+			name:      "body partials are not variables",
+			input:     `{"user": {{> ./user.json}}, "id": {{id}}}`,
+			variables: nil,
+			expected:  []string{"id"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -213,3 +221,142 @@ func TestResolverResolve(t *testing.T) {
 		})
 	}
 }
+
+func TestResolverHermetic(t *testing.T) {
+	t.Setenv("HITSPEC_TEST_HERMETIC", "process")
+
+	r := NewResolver()
+	r.SetHermetic(true)
+	r.SetVariable("name", "file")
+
+	tests := map[string]string{
+		"{{name}}":                                  "file",
+		"{{HITSPEC_TEST_HERMETIC}}":                 "{{HITSPEC_TEST_HERMETIC}}",
+		"{{$HITSPEC_TEST_HERMETIC}}":                "{{$HITSPEC_TEST_HERMETIC}}",
+		"{{$env(HITSPEC_TEST_HERMETIC)}}":           "",
+		"{{$env(HITSPEC_TEST_HERMETIC, fallback)}}": "fallback",
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if got := r.Clone().Resolve("{{$env(HITSPEC_TEST_HERMETIC, fallback)}}"); got != "fallback" {
+		t.Errorf("cloned resolver is not hermetic, got %q", got)
+	}
+
+	r.SetHermetic(false)
+	if got := r.Resolve("{{$env(HITSPEC_TEST_HERMETIC)}}"); got != "process" {
+		t.Errorf("Resolve after SetHermetic(false) = %q, want %q", got, "process")
+	}
+}
+
+func TestResolverResponseReference(t *testing.T) {
+	r := NewResolver()
+	r.SetResponse("login", map[string]any{
+		"status":   201,
+		"headers":  map[string]any{"Content-Type": "application/json"},
+		"body":     map[string]any{"user": map[string]any{"id": 7, "roles": []any{"admin"}}},
+		"duration": 12,
+	})
+
+	tests := map[string]string{
+		"{{login.$response.status}}":               "201",
+		"{{login.$response.headers.Content-Type}}": "application/json",
+		"{{login.$response.body.user.id}}":         "7",
+		"{{login.$response.body.user.roles.0}}":    "admin",
+		"{{login.$response.body.user}}":            `{"id":7,"roles":["admin"]}`,
+		"{{login.$response.body.missing}}":         "{{login.$response.body.missing}}",
+		"{{other.$response.status}}":               "{{other.$response.status}}",
+		"{{login.$responses}}":                     "{{login.$responses}}",
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestResolverNestedReferences(t *testing.T) {
+	r := NewResolver()
+	r.SetVariable("next", "/cart?item=1 2")
+	r.SetCapture("login", "location", "https%3A%2F%2Fapp.example.com%2Fhome%3Ftab%3D1")
+
+	tests := map[string]string{
+		"{{$urlencode({{next}})}}":                 "%2Fcart%3Fitem%3D1+2",
+		"{{$urldecode({{login.location}})}}":       "https://app.example.com/home?tab=1",
+		"{{$urlEncode(a b)}}":                      "a+b",
+		"/login?next={{$urlencode({{next}})}}&x=1": "/login?next=%2Fcart%3Fitem%3D1+2&x=1",
+		"{{$urlencode({{missing}})}}":              "%7B%7Bmissing%7D%7D",
+		`{"user": {"id": "{{next}}"}}`:             `{"user": {"id": "/cart?item=1 2"}}`,
+		"{{ }} and {{":                             "{{ }} and {{",
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestResolverBase64Decode(t *testing.T) {
+	r := NewResolver()
+	// Payload segment of a JWT: URL-safe alphabet, no padding
+	r.SetVariable("payload", "eyJzdWIiOiIxMjM0In0")
+
+	tests := map[string]string{
+		"{{$base64decode(aGVsbG8=)}}":         "hello",
+		"{{$base64Decode(aGVsbG8)}}":          "hello",
+		"{{$base64decode({{payload}})}}":      `{"sub":"1234"}`,
+		"{{$base64decode(Pz8-)}}":             "??>",
+		"{{$base64decode(not base64!)}}":      "",
+		"{{$base64({{$base64decode(aGk)}})}}": "aGk=",
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestResolverJSON(t *testing.T) {
+	r := NewResolver()
+	r.SetVariable("user", `{"name": "Ada", "id": 12345678901234567890}`)
+	r.SetVariable("broken", `{"name": `)
+	var warnings []string
+	r.SetWarnFunc(func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	})
+
+	tests := map[string]string{
+		`{{$json({ "b": 1, "a": [1, 2] })}}`:                 `{"a":[1,2],"b":1}`,
+		`{{$json({"z": {"y": true, "x": null}})}}`:           `{"z":{"x":null,"y":true}}`,
+		`{{$json({"owner": {{user}}, "tags": ["a", "b"]})}}`: `{"owner":{"id":12345678901234567890,"name":"Ada"},"tags":["a","b"]}`,
+		`{{$json({{broken}})}}`:                              `{{$json({"name": )}}`,
+		`{{json({{broken}})}}`:                               `{{json({"name": )}}`,
+	}
+	for input, want := range tests {
+		if got := r.Resolve(input); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "invalid JSON") || !strings.Contains(warnings[1], "invalid JSON") {
+		t.Errorf("warnings = %v, want two invalid JSON warnings", warnings)
+	}
+}
+
+func TestResolverLeavesPartials(t *testing.T) {
+	r := NewResolver()
+	var warnings []string
+	r.SetWarnFunc(func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	})
+
+	input := `{"user": {{> ./user.json}}}`
+	if got := r.Resolve(input); got != input {
+		t.Errorf("Resolve(%q) = %q, want it unchanged", input, got)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
@@ -1,9 +1,13 @@
 package parser
 
+import "time"
+
 type File struct {
-	Path      string
-	Variables []*Variable
-	Requests  []*Request
+	Path           string
+	Imports        []string // Paths from @import, relative to the file
+	Variables      []*Variable
+	DefaultHeaders []*Header // From a @headers block; already merged into each request's Headers
+	Requests       []*Request
 }
 
 type Variable struct {
@@ -25,8 +29,10 @@ type Request struct {
 	DBAssertions  []*DBAssertion
 	ShellCommands []*ShellCommand
 	Captures      []*Capture
+	Variables     []*Variable // @var variables, visible to this request only
 	Metadata      *RequestMetadata
 	Line          int
+	GeneratedName bool // Name was derived from the method and URL; the file gives none
 }
 
 type ShellCommand struct {
@@ -43,21 +49,57 @@ type DBAssertion struct {
 }
 
 type RequestMetadata struct {
-	Skip         string
-	Only         bool
-	Timeout      int
-	Retry        int
-	RetryDelay   int
-	RetryOn      []int
-	Depends      []string
-	Auth         *AuthConfig
-	Condition    *Condition
-	PreHooks     []*Hook
-	PostHooks    []*Hook
-	DBConnection string
-	WaitFor      *WaitForConfig
-	Stress       *StressMetadata
-	Custom       map[string]string // Custom annotations (e.g., @x-custom, @contract.state)
+	Skip            string
+	Only            bool
+	Soft            bool          // Failed assertions are reported as a soft failure and don't trigger bail
+	Timeout         time.Duration // @timeout, 0 for the client's timeout
+	Retry           int
+	RetryDelay      int
+	RetryOn         []int
+	RetryNetwork    bool          // Retry transient network errors such as connection resets
+	MaxRedirects    *int          // Overrides the client's redirect limit; 0 doesn't follow redirects
+	FollowRedirects *bool         // Overrides whether the client follows redirects
+	ExpectStatus    []StatusRange // Statuses accepted when there are no assertions (default 2xx)
+	Encoding        string        // Charset the body is transcoded to before sending (e.g. shift_jis)
+	BodyFile        string        // File sent as the body, relative to the request file
+	BodySchema      string        // JSON Schema the body must match before sending, relative to the request file
+	NDJSON          bool          // Response body is parsed as newline-delimited JSON whatever its content type
+	Persist         bool          // Captures are written to the --persist-captures file
+	PersistNames    []string      // Captures to persist; empty persists every capture
+	Teardown        bool          // Runs after the other requests of the file, even when bail stops it early
+	Group           string        // Group the request is reported under
+	GroupSetup      bool          // Runs first in its group; the group is skipped when it fails
+	Depends         []string
+	DataFrom        string   // Response path or capture of an array the request runs once per element of
+	Require         []string // Variables that must be set before the file runs
+	Auth            *AuthConfig
+	Condition       *Condition
+	PreHooks        []*Hook
+	PostHooks       []*Hook
+	DBConnection    string
+	WaitFor         *WaitForConfig
+	Stress          *StressMetadata
+	Custom          map[string]string // Custom annotations (e.g., @x-custom, @contract.state)
+}
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// AcceptsStatus reports whether code counts as success for a request without
+// assertions: any @expect-status range, or 2xx when none is set.
+func (m *RequestMetadata) AcceptsStatus(code int) bool {
+	if m == nil || len(m.ExpectStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, r := range m.ExpectStatus {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
 }
 
 // StressMetadata holds stress test configuration for a request
@@ -139,6 +181,7 @@ type Body struct {
 	Raw         string
 	Multipart   []*MultipartField
 	GraphQL     *GraphQLBody
+	Patch       []*PatchOperation
 	Line        int
 }
 
@@ -153,13 +196,17 @@ const (
 	BodyRaw
 	BodyXML
 	BodyGraphQL
+	BodyStdin     // "< -": the body is read from stdin when the request runs
+	BodyJSONPatch // >>>patch block, sent as an RFC 6902 JSON Patch document
 )
 
 type MultipartField struct {
-	Type  MultipartFieldType
-	Name  string
-	Value string
-	Path  string
+	Type        MultipartFieldType
+	Name        string
+	Value       string
+	Path        string // File to upload, or for a value part the file holding its value
+	ContentType string // Content-Type of the part (file parts default to application/octet-stream)
+	Filename    string // Filename sent for a file part (default the base name of Path)
 }
 
 type MultipartFieldType int
@@ -169,6 +216,15 @@ const (
 	MultipartFieldFile
 )
 
+// PatchOperation is one line of a >>>patch block
+type PatchOperation struct {
+	Op    string // add, remove, replace, move, copy or test
+	Path  string
+	From  string // Source path of move and copy
+	Value string // JSON value of add, replace and test, parsed after variables are resolved
+	Line  int
+}
+
 type GraphQLBody struct {
 	Query     string
 	Variables string
@@ -177,7 +233,10 @@ type GraphQLBody struct {
 type Assertion struct {
 	Subject  string
 	Operator AssertionOperator
+	Negate   bool // Set by a "not" prefix; the operator's result is inverted
 	Expected interface{}
+	ByKey    string // Field that matches array elements when diffing ("byKey=id")
+	Message  string // Custom failure message written after the assertion: : "message"
 	Line     int
 }
 
@@ -206,6 +265,9 @@ const (
 	OpEach
 	OpSchema
 	OpSnapshot
+	OpIncludesAll
+	OpIncludesAny
+	OpMonotonic
 )
 
 func (op AssertionOperator) String() string {
@@ -254,16 +316,36 @@ func (op AssertionOperator) String() string {
 		return "schema"
 	case OpSnapshot:
 		return "snapshot"
+	case OpIncludesAll:
+		return "includesAll"
+	case OpIncludesAny:
+		return "includesAny"
+	case OpMonotonic:
+		return "monotonic"
 	default:
 		return "unknown"
 	}
 }
 
 type Capture struct {
-	Name   string
-	Source CaptureSource
-	Path   string
-	Line   int
+	Name       string
+	Source     CaptureSource
+	Path       string
+	Index      int                // Value index for CaptureHeaderValues; -1 captures every value
+	Transforms []CaptureTransform // Applied in order to the extracted value, before Type
+	Type       string             // Type the value is converted to ("as int"): int, float, string or bool
+	Line       int
+}
+
+// CaptureTypes are the types a captured value can be converted to with "as"
+var CaptureTypes = []string{"int", "float", "string", "bool"}
+
+// CaptureTransform derives a captured value from the extracted one: "length"
+// (also written "count") counts the items, keys or characters of the value;
+// "+", "-", "*" and "/" apply arithmetic with Operand.
+type CaptureTransform struct {
+	Op      string
+	Operand float64
 }
 
 type CaptureSource int
@@ -273,6 +355,8 @@ const (
 	CaptureHeader
 	CaptureStatus
 	CaptureDuration
+	CaptureHeaderValues
+	CaptureCookie
 )
 
 func (s CaptureSource) String() string {
@@ -285,6 +369,10 @@ func (s CaptureSource) String() string {
 		return "status"
 	case CaptureDuration:
 		return "duration"
+	case CaptureHeaderValues:
+		return "headers"
+	case CaptureCookie:
+		return "cookie"
 	default:
 		return "unknown"
 	}
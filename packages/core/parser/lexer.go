@@ -42,6 +42,7 @@ const (
 	TokenVariablesStart
 	TokenDBStart
 	TokenShellStart
+	TokenPatchStart
 	TokenIdentifier
 	TokenLeftBracket
 	TokenRightBracket
@@ -385,6 +386,8 @@ func (l *Lexer) readBlockStart() Token {
 		return Token{Type: TokenDBStart, Value: blockType, Line: line, Column: col}
 	case "shell":
 		return Token{Type: TokenShellStart, Value: blockType, Line: line, Column: col}
+	case "patch":
+		return Token{Type: TokenPatchStart, Value: blockType, Line: line, Column: col}
 	default:
 		return Token{Type: TokenAssertionStart, Value: blockType, Line: line, Column: col}
 	}
@@ -479,7 +482,7 @@ func (l *Lexer) readIdentifierOrKeyword() Token {
 	ident := l.readIdentifier()
 	upper := strings.ToUpper(ident)
 
-	if isHTTPMethod(upper) {
+	if isHTTPMethod(upper) || (col == 1 && l.isExtensionMethod(ident)) {
 		return Token{Type: TokenMethod, Value: upper, Line: line, Column: col}
 	}
 
@@ -494,7 +497,7 @@ func (l *Lexer) readIdentifierOrKeyword() Token {
 	case "null":
 		return Token{Type: TokenNull, Value: ident, Line: line, Column: col}
 	case "contains", "startswith", "endswith", "matches", "exists", "length",
-		"includes", "in", "type", "each", "schema":
+		"includes", "includesall", "includesany", "in", "type", "each", "schema", "monotonic":
 		return Token{Type: TokenOperator, Value: lower, Line: line, Column: col}
 	}
 
@@ -586,6 +589,17 @@ func (l *Lexer) ReadRestOfLine() string {
 	return strings.TrimSpace(l.readToEndOfLine())
 }
 
+// ReadWord reads up to the next whitespace, comma or closing bracket, for
+// values such as application/json that continue past an identifier
+func (l *Lexer) ReadWord() string {
+	var builder strings.Builder
+	for l.ch != 0 && l.ch != ' ' && l.ch != '\t' && l.ch != '\n' && l.ch != '\r' && l.ch != ',' && l.ch != ']' {
+		builder.WriteByte(l.ch)
+		l.readChar()
+	}
+	return builder.String()
+}
+
 func (l *Lexer) CurrentLine() int {
 	return l.line
 }
@@ -602,6 +616,30 @@ func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
+// isExtensionMethod reports whether ident, just read at the start of a line,
+// is a method outside the standard set such as PROPFIND, MKCOL or REPORT: an
+// uppercase word followed by a URL on the same line
+func (l *Lexer) isExtensionMethod(ident string) bool {
+	if ident == "" || ident[0] == '-' {
+		return false
+	}
+	for i := 0; i < len(ident); i++ {
+		if (ident[i] < 'A' || ident[i] > 'Z') && ident[i] != '-' {
+			return false
+		}
+	}
+	if l.ch != ' ' && l.ch != '\t' {
+		return false
+	}
+	rest := strings.TrimLeft(l.input[l.pos:], " \t")
+	for _, prefix := range []string{"http://", "https://", "/", "{{"} {
+		if strings.HasPrefix(rest, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func isHTTPMethod(s string) bool {
 	switch s {
 	case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE", "CONNECT", "WS":
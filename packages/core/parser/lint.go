@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Lint rule identifiers, reported alongside each warning
+const (
+	RuleNoAssertions   = "no-assertions"
+	RuleUnusedVariable = "unused-variable"
+	RuleDependsOrder   = "depends-order"
+	RuleLocalhostURL   = "localhost-url"
+)
+
+// Warning is a lint finding: the file parses, but something in it is likely
+// a mistake a reviewer would flag
+type Warning struct {
+	Line    int
+	Rule    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d: %s [%s]", w.Line, w.Message, w.Rule)
+}
+
+// localHosts are hostnames that only work on the author's machine
+var localHosts = map[string]bool{
+	"localhost": true,
+	"127.0.0.1": true,
+	"0.0.0.0":   true,
+	"::1":       true,
+}
+
+// Lint checks a parsed file for suspicious patterns. source is the file's
+// text, searched for references to the file's variables.
+func Lint(file *File, source string) []Warning {
+	var warnings []Warning
+
+	position := make(map[string]int, len(file.Requests))
+	for i, req := range file.Requests {
+		if req.Name != "" {
+			position[req.Name] = i
+		}
+	}
+
+	for i, req := range file.Requests {
+		label := requestLabel(req)
+
+		if !hasChecks(req) {
+			warnings = append(warnings, Warning{
+				Line:    req.Line,
+				Rule:    RuleNoAssertions,
+				Message: fmt.Sprintf("%s has no assertions", label),
+			})
+		}
+
+		if req.Metadata != nil {
+			for _, dep := range req.Metadata.Depends {
+				// Dependencies outside this file come from @import
+				if j, ok := position[dep]; ok && j > i {
+					warnings = append(warnings, Warning{
+						Line:    req.Line,
+						Rule:    RuleDependsOrder,
+						Message: fmt.Sprintf("%s depends on %q, which is defined later and will run first", label, dep),
+					})
+				}
+			}
+		}
+
+		if host := literalHost(req.URL); localHosts[host] {
+			warnings = append(warnings, Warning{
+				Line:    req.Line,
+				Rule:    RuleLocalhostURL,
+				Message: fmt.Sprintf("%s uses a hardcoded %s URL; use a variable such as {{baseUrl}}", label, host),
+			})
+		}
+	}
+
+	for _, v := range file.Variables {
+		ref := regexp.MustCompile(`\{\{\s*` + regexp.QuoteMeta(v.Name) + `\s*\}\}`)
+		if !ref.MatchString(source) {
+			warnings = append(warnings, Warning{
+				Line:    v.Line,
+				Rule:    RuleUnusedVariable,
+				Message: fmt.Sprintf("variable %q is never used", v.Name),
+			})
+		}
+	}
+
+	return warnings
+}
+
+func requestLabel(req *Request) string {
+	if req.Name != "" && !req.GeneratedName {
+		return fmt.Sprintf("request %q", req.Name)
+	}
+	return fmt.Sprintf("%s %s", req.Method, req.URL)
+}
+
+// hasChecks reports whether a request verifies its response in some way.
+// Skipped requests and those with @expect-status are not flagged.
+func hasChecks(req *Request) bool {
+	if len(req.Assertions) > 0 || len(req.DBAssertions) > 0 {
+		return true
+	}
+	if req.Metadata == nil {
+		return false
+	}
+	return req.Metadata.Skip != "" || len(req.Metadata.ExpectStatus) > 0
+}
+
+// literalHost returns the hostname of a URL written out in full, or "" when
+// the host comes from a variable
+func literalHost(raw string) string {
+	if !strings.Contains(raw, "://") {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || strings.Contains(u.Host, "{{") {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintRules(t *testing.T, input string) map[string][]int {
+	t.Helper()
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+
+	rules := make(map[string][]int)
+	for _, w := range Lint(file, input) {
+		rules[w.Rule] = append(rules[w.Rule], w.Line)
+	}
+	return rules
+}
+
+func TestLint_Clean(t *testing.T) {
+	input := `@baseUrl = https://api.example.com
+
+### Create user
+# @name createUser
+POST {{baseUrl}}/users
+
+>>>
+expect status 201
+<<<
+
+### Get user
+# @name getUser
+# @depends createUser
+GET {{ baseUrl }}/users/1
+
+>>>
+expect status 200
+<<<`
+
+	assert.Empty(t, lintRules(t, input))
+}
+
+func TestLint_Warnings(t *testing.T) {
+	input := `@baseUrl = https://api.example.com
+@token = secret
+
+### Get user
+# @name getUser
+# @depends createUser
+GET {{baseUrl}}/users/1
+
+>>>
+expect status 200
+<<<
+
+### Create user
+# @name createUser
+POST http://localhost:3000/users
+`
+
+	rules := lintRules(t, input)
+	assert.Equal(t, []int{2}, rules[RuleUnusedVariable])
+	assert.Len(t, rules[RuleDependsOrder], 1)
+	assert.Len(t, rules[RuleNoAssertions], 1)
+	assert.Len(t, rules[RuleLocalhostURL], 1)
+}
+
+func TestLint_SkipsExpectStatusAndSkipped(t *testing.T) {
+	input := `### Health
+# @expect-status 2xx
+GET https://api.example.com/health
+
+### Legacy
+# @skip deprecated
+GET https://api.example.com/legacy`
+
+	assert.Empty(t, lintRules(t, input))
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+	leadingVariable = regexp.MustCompile(`^\{\{[^}]*\}\}`)
+)
+
+// generateNames names the requests that have neither a @name nor a ###
+// title after their method and URL path, so GET {{baseUrl}}/users/{{id}}
+// becomes get_users_id. Names are unique within the file: a name already
+// taken gets a _2, _3, ... suffix, in the order the requests appear.
+func generateNames(file *File) {
+	taken := make(map[string]bool, len(file.Requests))
+	for _, req := range file.Requests {
+		if req.Name != "" {
+			taken[req.Name] = true
+		}
+	}
+
+	for _, req := range file.Requests {
+		if req.Name != "" {
+			continue
+		}
+		base := sanitizeName(req.Method + " " + urlPath(req.URL))
+		if base == "" {
+			base = "request"
+		}
+		name := base
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		taken[name] = true
+		req.Name = name
+		req.GeneratedName = true
+	}
+}
+
+// urlPath returns the path of a request URL, without the scheme, host,
+// a leading {{baseUrl}}-style variable, query or fragment
+func urlPath(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if j := strings.Index(url, "/"); j >= 0 {
+			return url[j:]
+		}
+		return ""
+	}
+	return leadingVariable.ReplaceAllString(url, "")
+}
+
+// sanitizeName lowercases s and joins its alphanumeric runs with underscores,
+// the way the importers name requests
+func sanitizeName(s string) string {
+	return strings.ToLower(strings.Trim(nonAlphanumeric.ReplaceAllString(s, "_"), "_"))
+}
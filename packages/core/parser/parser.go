@@ -1,10 +1,16 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 type Parser struct {
@@ -62,19 +68,33 @@ func (p *Parser) ParseFile() (*File, error) {
 	file := &File{Path: p.file}
 	p.skipNewlines()
 
-	for p.curToken.Type == TokenVariable {
-		v := &Variable{
-			Name:  p.curToken.Value,
-			Value: p.curToken.Literal.(string),
-			Line:  p.curToken.Line,
+	for p.curToken.Type == TokenVariable || p.isImportAnnotation() || p.isHeadersAnnotation() {
+		if p.curToken.Type == TokenVariable {
+			v := &Variable{
+				Name:  p.curToken.Value,
+				Value: p.curToken.Literal.(string),
+				Line:  p.curToken.Line,
+			}
+			file.Variables = append(file.Variables, v)
+		} else if p.isHeadersAnnotation() {
+			headers, err := p.parseDefaultHeaders()
+			if err != nil {
+				return nil, err
+			}
+			file.DefaultHeaders = append(file.DefaultHeaders, headers...)
+			continue
+		} else {
+			file.Imports = append(file.Imports, p.curToken.Literal.(string))
 		}
-		file.Variables = append(file.Variables, v)
 		p.nextToken()
 		p.skipNewlines()
 	}
 
 	for p.curToken.Type != TokenEOF {
-		if p.curToken.Type == TokenRequestSeparator {
+		if p.isImportAnnotation() {
+			file.Imports = append(file.Imports, p.curToken.Literal.(string))
+			p.nextToken()
+		} else if p.curToken.Type == TokenRequestSeparator {
 			req, err := p.parseRequest()
 			if err != nil {
 				return nil, err
@@ -92,9 +112,102 @@ func (p *Parser) ParseFile() (*File, error) {
 		p.skipNewlines()
 	}
 
+	if err := p.checkDuplicateNames(file); err != nil {
+		return nil, err
+	}
+	generateNames(file)
+	applyDefaultHeaders(file)
+
 	return file, nil
 }
 
+// parseDefaultHeaders parses a file-level @headers block: header lines
+// following the annotation, up to the first line that isn't a header
+func (p *Parser) parseDefaultHeaders() ([]*Header, error) {
+	if rest := p.curToken.Literal.(string); rest != "" {
+		return nil, &ParseError{
+			File:    p.file,
+			Line:    p.curToken.Line,
+			Column:  p.curToken.Column,
+			Message: "@headers takes no value; list the headers on the lines below it",
+		}
+	}
+	p.nextToken()
+	p.skipNewlines()
+
+	var headers []*Header
+	for p.curToken.Type == TokenIdentifier {
+		header, err := p.parseHeader()
+		if err != nil {
+			return nil, err
+		}
+		if header != nil {
+			headers = append(headers, header)
+		}
+		p.skipNewlines()
+	}
+	return headers, nil
+}
+
+// applyDefaultHeaders adds the file's default headers to every request that
+// doesn't set a header of the same name itself
+func applyDefaultHeaders(file *File) {
+	if len(file.DefaultHeaders) == 0 {
+		return
+	}
+	for _, req := range file.Requests {
+		var headers []*Header
+		for _, def := range file.DefaultHeaders {
+			if !hasHeader(req.Headers, def.Key) {
+				h := *def
+				headers = append(headers, &h)
+			}
+		}
+		req.Headers = append(headers, req.Headers...)
+	}
+}
+
+func hasHeader(headers []*Header, key string) bool {
+	for _, h := range headers {
+		if strings.EqualFold(h.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDuplicateNames rejects files where two requests share a @name, since
+// @depends and captures like {{name.token}} could then resolve to either
+func (p *Parser) checkDuplicateNames(file *File) error {
+	lines := make(map[string]int)
+	for _, req := range file.Requests {
+		if req.Name == "" {
+			continue
+		}
+		if first, ok := lines[req.Name]; ok {
+			return &ParseError{
+				File:    p.file,
+				Line:    req.Line,
+				Column:  1,
+				Message: fmt.Sprintf("duplicate request name %q (first defined on line %d)", req.Name, first),
+			}
+		}
+		lines[req.Name] = req.Line
+	}
+	return nil
+}
+
+// isImportAnnotation reports whether the current token is a file-level @import
+func (p *Parser) isImportAnnotation() bool {
+	return p.curToken.Type == TokenAnnotation && strings.EqualFold(p.curToken.Value, "import")
+}
+
+// isHeadersAnnotation reports whether the current token starts a file-level
+// @headers block
+func (p *Parser) isHeadersAnnotation() bool {
+	return p.curToken.Type == TokenAnnotation && strings.EqualFold(p.curToken.Value, "headers")
+}
+
 func (p *Parser) parseRequest() (*Request, error) {
 	req := &Request{
 		Metadata: &RequestMetadata{},
@@ -241,11 +354,13 @@ func (p *Parser) parseAnnotation(req *Request) error {
 		}
 	case "only":
 		req.Metadata.Only = true
+	case "soft", "continue-on-failure":
+		req.Metadata.Soft = true
 	case "timeout":
-		if v, err := strconv.Atoi(value); err == nil {
+		if v, err := parseTimeout(value); err == nil {
 			req.Metadata.Timeout = v
 		} else if value != "" {
-			fmt.Fprintf(os.Stderr, "warning: invalid timeout value %q (expected integer): %v\n", value, err)
+			fmt.Fprintf(os.Stderr, "warning: invalid timeout value %q: %v\n", value, err)
 		}
 	case "retry":
 		if v, err := strconv.Atoi(value); err == nil {
@@ -259,6 +374,76 @@ func (p *Parser) parseAnnotation(req *Request) error {
 		} else if value != "" {
 			fmt.Fprintf(os.Stderr, "warning: invalid retryDelay value %q (expected integer): %v\n", value, err)
 		}
+	case "retryon", "retry-on":
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if strings.EqualFold(part, "network") {
+				req.Metadata.RetryNetwork = true
+			} else if v, err := strconv.Atoi(part); err == nil {
+				req.Metadata.RetryOn = append(req.Metadata.RetryOn, v)
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: invalid retryOn value %q (expected status codes or network)\n", part)
+			}
+		}
+	case "retry-network", "retrynetwork", "retry-on-network-error":
+		req.Metadata.RetryNetwork = true
+	case "max-redirects", "maxredirects":
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			req.Metadata.MaxRedirects = &v
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: invalid max-redirects value %q (expected a non-negative integer)\n", value)
+		}
+	case "follow-redirects", "followredirects":
+		if v, err := strconv.ParseBool(value); err == nil || value == "" {
+			follow := value == "" || v
+			req.Metadata.FollowRedirects = &follow
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: invalid follow-redirects value %q (expected true or false)\n", value)
+		}
+	case "disable-redirects", "disableredirects", "no-redirects":
+		follow := false
+		req.Metadata.FollowRedirects = &follow
+	case "expect-status", "expectstatus":
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			r, err := parseStatusRange(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: invalid expect-status value %q: %v\n", v, err)
+				continue
+			}
+			req.Metadata.ExpectStatus = append(req.Metadata.ExpectStatus, r)
+		}
+	case "encoding":
+		if _, err := htmlindex.Get(value); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: unsupported encoding %q\n", value)
+		} else {
+			req.Metadata.Encoding = strings.ToLower(value)
+		}
+	case "body-file":
+		req.Metadata.BodyFile = value
+	case "request-schema":
+		req.Metadata.BodySchema = value
+	case "teardown":
+		req.Metadata.Teardown = true
+	case "ndjson", "jsonl":
+		req.Metadata.NDJSON = true
+	case "group":
+		req.Metadata.Group = value
+	case "setup":
+		req.Metadata.GroupSetup = true
+	case "persist":
+		req.Metadata.Persist = true
+		for _, n := range strings.Split(value, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				req.Metadata.PersistNames = append(req.Metadata.PersistNames, n)
+			}
+		}
 	case "depends":
 		deps := strings.Split(value, ",")
 		for _, d := range deps {
@@ -267,6 +452,42 @@ func (p *Parser) parseAnnotation(req *Request) error {
 				req.Metadata.Depends = append(req.Metadata.Depends, d)
 			}
 		}
+	case "data-from":
+		name, path, _ := strings.Cut(value, ".")
+		if name == "" || path == "" {
+			return &ParseError{
+				File:    p.file,
+				Line:    p.curToken.Line,
+				Column:  p.curToken.Column,
+				Message: fmt.Sprintf("@data-from needs a request name and a path, like listUsers.body.users, got %q", value),
+			}
+		}
+		req.Metadata.DataFrom = value
+		// The referenced request has to run first
+		if !slices.Contains(req.Metadata.Depends, name) {
+			req.Metadata.Depends = append(req.Metadata.Depends, name)
+		}
+	case "import":
+		return &ParseError{
+			File:    p.file,
+			Line:    p.curToken.Line,
+			Column:  p.curToken.Column,
+			Message: "@import must appear before the first request",
+		}
+	case "headers":
+		return &ParseError{
+			File:    p.file,
+			Line:    p.curToken.Line,
+			Column:  p.curToken.Column,
+			Message: "@headers must appear before the first request",
+		}
+	case "require":
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v != "" {
+				req.Metadata.Require = append(req.Metadata.Require, v)
+			}
+		}
 	case "auth":
 		auth, err := parseAuthConfig(value)
 		if err != nil {
@@ -276,6 +497,31 @@ func (p *Parser) parseAnnotation(req *Request) error {
 	case "before":
 		hook := &Hook{Type: HookExec, Command: value}
 		req.Metadata.PreHooks = append(req.Metadata.PreHooks, hook)
+	case "pre":
+		// @pre name = template computes a variable right before the request is built
+		if varName, _, ok := strings.Cut(value, "="); !ok || strings.TrimSpace(varName) == "" {
+			return &ParseError{
+				File:    p.file,
+				Line:    p.curToken.Line,
+				Column:  p.curToken.Column,
+				Message: "invalid @pre hook " + strconv.Quote(value) + ", expected: @pre name = template",
+			}
+		}
+		hook := &Hook{Type: HookSet, Command: value}
+		req.Metadata.PreHooks = append(req.Metadata.PreHooks, hook)
+	case "var":
+		// @var name = value declares a variable visible to this request only
+		name, v, ok := strings.Cut(value, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t{}") {
+			return &ParseError{
+				File:    p.file,
+				Line:    p.curToken.Line,
+				Column:  p.curToken.Column,
+				Message: "invalid @var " + strconv.Quote(value) + ", expected: @var name = value",
+			}
+		}
+		req.Variables = append(req.Variables, &Variable{Name: name, Value: strings.TrimSpace(v), Line: p.curToken.Line})
 	case "after":
 		hook := &Hook{Type: HookExec, Command: value, Always: true}
 		req.Metadata.PostHooks = append(req.Metadata.PostHooks, hook)
@@ -348,6 +594,39 @@ func (p *Parser) parseAnnotation(req *Request) error {
 	return nil
 }
 
+// parseTimeout parses a @timeout value: a duration such as 500ms or 2s, or a
+// bare number of milliseconds
+func parseTimeout(value string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(value); err == nil {
+		if ms < 0 {
+			return 0, fmt.Errorf("timeout must not be negative")
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration like 500ms or 2s, or milliseconds")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("timeout must not be negative")
+	}
+	return d, nil
+}
+
+// parseStatusRange parses a status code such as 201 or a class such as 4xx
+func parseStatusRange(value string) (StatusRange, error) {
+	lower := strings.ToLower(value)
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") && lower[0] >= '1' && lower[0] <= '5' {
+		base := int(lower[0]-'0') * 100
+		return StatusRange{Min: base, Max: base + 99}, nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 100 || code > 599 {
+		return StatusRange{}, fmt.Errorf("expected a status code like 201 or a class like 2xx")
+	}
+	return StatusRange{Min: code, Max: code}, nil
+}
+
 func parseAuthConfig(value string) (*AuthConfig, error) {
 	parts := strings.Fields(value)
 	if len(parts) == 0 {
@@ -492,6 +771,9 @@ func (p *Parser) parseBody() (*Body, error) {
 	if p.curToken.Type == TokenGraphQLStart {
 		return p.parseGraphQLBody()
 	}
+	if p.curToken.Type == TokenPatchStart {
+		return p.parsePatchBody()
+	}
 	if p.curToken.Type == TokenQueryParam && p.curToken.Value == "&" {
 		return p.parseFormBlockBody()
 	}
@@ -529,7 +811,10 @@ func (p *Parser) parseBody() (*Body, error) {
 		Line: line,
 	}
 
-	if strings.HasPrefix(raw, "{") || strings.HasPrefix(raw, "[") {
+	// Whitespace between tokens isn't kept, so "< -" arrives as "<-"
+	if raw == "<-" {
+		body.ContentType = BodyStdin
+	} else if strings.HasPrefix(raw, "{") || strings.HasPrefix(raw, "[") {
 		body.ContentType = BodyJSON
 	} else if strings.HasPrefix(raw, "<?xml") || strings.HasPrefix(raw, "<") {
 		body.ContentType = BodyXML
@@ -590,52 +875,150 @@ func (p *Parser) parseFormBlockBody() (*Body, error) {
 
 func (p *Parser) parseMultipartBody() (*Body, error) {
 	line := p.curToken.Line
-	p.nextToken()
-	p.skipNewlines()
 
 	body := &Body{
 		ContentType: BodyMultipart,
 		Line:        line,
 	}
 
-	for p.curToken.Type != TokenAssertionEnd && p.curToken.Type != TokenEOF {
-		if p.curToken.Type == TokenIdentifier {
-			fieldType := strings.ToLower(p.curToken.Value)
-			p.nextToken()
-			if p.curToken.Type == TokenWhitespace {
-				p.nextToken()
+	// Each line is a part:
+	//   field name = value        (or: name = value)
+	//   field name < ./path[; type=content/type]  (value read from a file)
+	//   file name = ./path[, content/type][; type=content/type][; filename=name]
+	//   name < @./path            (file part)
+	raw := p.lexer.ReadRawUntilBlockEnd()
+	for i, text := range strings.Split(raw, "\n") {
+		text = strings.TrimSpace(text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		field, err := parseMultipartField(text)
+		if err != nil {
+			return nil, &ParseError{
+				File:    p.file,
+				Line:    line + i + 1,
+				Message: err.Error(),
 			}
+		}
+		body.Multipart = append(body.Multipart, field)
+	}
 
-			field := &MultipartField{}
+	p.nextToken()
+	if p.curToken.Type == TokenAssertionEnd {
+		p.nextToken()
+	}
 
-			if fieldType == "file" {
-				field.Type = MultipartFieldFile
-				if p.curToken.Value == "@" {
-					p.nextToken()
-				}
-				field.Path = p.lexer.ReadRestOfLine()
-			} else if fieldType == "field" {
-				field.Type = MultipartFieldValue
-				field.Name = p.curToken.Value
-				p.nextToken()
-				if p.curToken.Type == TokenWhitespace {
-					p.nextToken()
-				}
-				if p.curToken.Type == TokenEquals {
-					p.nextToken()
-				}
-				if p.curToken.Type == TokenWhitespace {
-					p.nextToken()
-				}
-				field.Value = p.lexer.ReadRestOfLine()
-			}
+	return body, nil
+}
 
-			body.Multipart = append(body.Multipart, field)
+// parseMultipartField parses a single line of a multipart block
+func parseMultipartField(text string) (*MultipartField, error) {
+	keyword, rest, _ := strings.Cut(text, " ")
+	switch strings.ToLower(keyword) {
+	case "field":
+		lt, eq := strings.Index(rest, "<"), strings.Index(rest, "=")
+		if lt >= 0 && (eq < 0 || lt < eq) {
+			field := parseMultipartFile(strings.TrimSpace(rest[:lt]), rest[lt+1:])
+			field.Type = MultipartFieldValue
+			return field, nil
 		}
-		p.nextToken()
-		p.skipNewlines()
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid multipart field %q (expected: field name = value or field name < ./path)", text)
+		}
+		return &MultipartField{
+			Type:  MultipartFieldValue,
+			Name:  strings.TrimSpace(name),
+			Value: strings.TrimSpace(value),
+		}, nil
+	case "file":
+		name, spec, ok := strings.Cut(rest, "=")
+		if !ok {
+			name, spec, ok = strings.Cut(rest, "<")
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid multipart file %q (expected: file name = ./path)", text)
+		}
+		return parseMultipartFile(strings.TrimSpace(name), spec), nil
+	}
+
+	// Shorthand forms without a keyword: name < @./path or name = value
+	lt, eq := strings.Index(text, "<"), strings.Index(text, "=")
+	if lt >= 0 && (eq < 0 || lt < eq) {
+		return parseMultipartFile(strings.TrimSpace(text[:lt]), text[lt+1:]), nil
+	}
+	if name, value, ok := strings.Cut(text, "="); ok {
+		return &MultipartField{
+			Type:  MultipartFieldValue,
+			Name:  strings.TrimSpace(name),
+			Value: strings.TrimSpace(value),
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid multipart line %q", text)
+}
+
+// parseMultipartFile parses the path and options of a file part, e.g.
+// "./report.pdf; type=application/pdf; filename=q3.pdf". A content type may
+// also follow the path after a comma.
+func parseMultipartFile(name, spec string) *MultipartField {
+	field := &MultipartField{Type: MultipartFieldFile, Name: name}
+
+	parts := strings.Split(spec, ";")
+	path := strings.TrimPrefix(strings.TrimSpace(parts[0]), "@")
+	if p, contentType, ok := strings.Cut(path, ","); ok {
+		path = p
+		field.ContentType = strings.TrimSpace(contentType)
+	}
+	field.Path = strings.TrimSpace(path)
+
+	for _, opt := range parts[1:] {
+		key, value, _ := strings.Cut(opt, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "type", "content-type":
+			field.ContentType = strings.TrimSpace(value)
+		case "filename":
+			field.Filename = strings.TrimSpace(value)
+		}
+	}
+	return field
+}
+
+// parsePatchBody parses a >>>patch block into JSON Patch operations, one per
+// line:
+//
+//	add /tags/- "admin"
+//	replace /name {{newName}}
+//	remove /legacy
+//	move /old /new
+//	copy /from /to
+//	test /version 3
+func (p *Parser) parsePatchBody() (*Body, error) {
+	line := p.curToken.Line
+
+	body := &Body{
+		ContentType: BodyJSONPatch,
+		Line:        line,
+	}
+
+	raw := p.lexer.ReadRawUntilBlockEnd()
+	for i, text := range strings.Split(raw, "\n") {
+		text = strings.TrimSpace(text)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		op, err := parsePatchOperation(text)
+		if err != nil {
+			return nil, &ParseError{
+				File:    p.file,
+				Line:    line + i + 1,
+				Message: err.Error(),
+			}
+		}
+		op.Line = line + i + 1
+		body.Patch = append(body.Patch, op)
 	}
 
+	p.nextToken()
 	if p.curToken.Type == TokenAssertionEnd {
 		p.nextToken()
 	}
@@ -643,6 +1026,42 @@ func (p *Parser) parseMultipartBody() (*Body, error) {
 	return body, nil
 }
 
+// parsePatchOperation parses a single line of a patch block
+func parsePatchOperation(text string) (*PatchOperation, error) {
+	fields := strings.Fields(text)
+	op := &PatchOperation{Op: strings.ToLower(fields[0])}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid patch operation %q (expected: %s /path value)", text, op.Op)
+		}
+		op.Path = fields[1]
+		// The value is the rest of the line, which may contain spaces
+		rest := strings.TrimSpace(text[len(fields[0]):])
+		op.Value = strings.TrimSpace(rest[len(fields[1]):])
+	case "remove":
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid patch operation %q (expected: remove /path)", text)
+		}
+		op.Path = fields[1]
+	case "move", "copy":
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid patch operation %q (expected: %s /from /path)", text, op.Op)
+		}
+		op.From, op.Path = fields[1], fields[2]
+	default:
+		return nil, fmt.Errorf("unknown patch operation %q (expected add, remove, replace, move, copy or test)", fields[0])
+	}
+
+	for _, path := range []string{op.Path, op.From} {
+		if path != "" && !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "{{") {
+			return nil, fmt.Errorf("invalid patch path %q: JSON pointers start with /", path)
+		}
+	}
+	return op, nil
+}
+
 func (p *Parser) parseGraphQLBody() (*Body, error) {
 	line := p.curToken.Line
 	p.nextToken()
@@ -709,7 +1128,29 @@ func (p *Parser) parseAssertion() (*Assertion, error) {
 	subject := p.parseAssertionSubject()
 	p.skipWhitespace()
 
-	operator, err := p.parseAssertionOperator()
+	// The header subject takes the header name as a second word
+	if subject == "header" && p.curToken.Type != TokenOperator && p.curToken.Type != TokenNewline && p.curToken.Type != TokenEOF {
+		subject += " " + p.parseAssertionSubject()
+		p.skipWhitespace()
+	}
+
+	// Composite assertions such as secure-headers stand alone
+	if subject == "secure-headers" && (p.curToken.Type == TokenNewline || p.curToken.Type == TokenEOF || p.curToken.Type == TokenColon) {
+		message, err := p.parseAssertionMessage()
+		if err != nil {
+			return nil, err
+		}
+		return &Assertion{Subject: subject, Operator: OpExists, Message: message, Line: line}, nil
+	}
+	if subject == "secure-headers" {
+		return nil, &ParseError{
+			Line:    p.curToken.Line,
+			Column:  p.curToken.Column,
+			Message: "secure-headers takes no operator and can't be negated, got " + p.curToken.Value,
+		}
+	}
+
+	operator, negate, err := p.parseAssertionOperator()
 	if err != nil {
 		return nil, err
 	}
@@ -717,18 +1158,177 @@ func (p *Parser) parseAssertion() (*Assertion, error) {
 	p.skipWhitespace()
 
 	var expected any
-	if operator != OpExists && operator != OpNotExists {
-		expected = p.parseAssertionExpected()
+	var byKey, message string
+	if operator == OpMonotonic {
+		if expected, message, err = p.parseMonotonicExpected(); err != nil {
+			return nil, err
+		}
+	} else if operator != OpExists && operator != OpNotExists {
+		if p.curToken.Type == TokenLeftBracket || (p.curToken.Type == TokenText && p.curToken.Value == "{") {
+			expected, byKey, message = p.parseLiteralExpected()
+		} else if readsRestOfLine(p.curToken) {
+			// The message is part of the raw value read to the end of the line
+			raw, _ := p.parseAssertionExpected().(string)
+			expected, message = splitAssertionMessage(raw)
+		} else {
+			expected = p.parseAssertionExpected()
+		}
+	}
+	if message == "" {
+		if message, err = p.parseAssertionMessage(); err != nil {
+			return nil, err
+		}
+	}
+	if s, ok := expected.(string); ok && subject == "raw" {
+		expected = unescapeRaw(s, line)
 	}
 
 	return &Assertion{
 		Subject:  subject,
 		Operator: operator,
+		Negate:   negate,
 		Expected: expected,
+		ByKey:    byKey,
+		Message:  message,
 		Line:     line,
 	}, nil
 }
 
+// unescapeRaw interprets the Go escape sequences (\r, \n, \t, \x00...) of
+// a raw assertion's expected value, so whitespace and control bytes can be
+// written exactly
+func unescapeRaw(s string, line int) string {
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: line %d: invalid escape sequence in raw assertion %q, comparing it as written\n", line, s)
+		return s
+	}
+	return unquoted
+}
+
+// parseAssertionMessage parses the optional custom failure message ending an
+// assertion: expect body.balance >= 0 : "balance must never be negative"
+func (p *Parser) parseAssertionMessage() (string, error) {
+	if p.curToken.Type != TokenColon {
+		return "", nil
+	}
+	line, column := p.curToken.Line, p.curToken.Column
+	p.nextToken()
+	if p.curToken.Type != TokenString {
+		return "", &ParseError{
+			File:    p.file,
+			Line:    line,
+			Column:  column,
+			Message: "expected a quoted message after ':' in assertion",
+		}
+	}
+	message, _ := p.curToken.Literal.(string)
+	p.nextToken()
+	return message, nil
+}
+
+// assertionMessage matches a custom failure message at the end of a value
+// read to the end of the line
+var assertionMessage = regexp.MustCompile(`\s+:\s*("(?:[^"\\]|\\.)*")\s*$`)
+
+// splitAssertionMessage splits a trailing : "message" off a raw value
+func splitAssertionMessage(raw string) (string, string) {
+	m := assertionMessage.FindStringSubmatchIndex(raw)
+	if m == nil {
+		return raw, ""
+	}
+	message, err := strconv.Unquote(raw[m[2]:m[3]])
+	if err != nil {
+		return raw, ""
+	}
+	return strings.TrimSpace(raw[:m[0]]), message
+}
+
+// readsRestOfLine reports whether parseAssertionExpected reads the value
+// starting at tok to the end of the line rather than as a single token
+func readsRestOfLine(tok Token) bool {
+	switch tok.Type {
+	case TokenString, TokenNumber, TokenBoolean, TokenNull, TokenLeftBracket, TokenIdentifier:
+		return false
+	case TokenText:
+		return tok.Value != "{"
+	default:
+		return true
+	}
+}
+
+// monotonicOrder matches the expected value of the monotonic operator:
+// asc or desc, optionally followed by the element field to compare
+var monotonicOrder = regexp.MustCompile(`(?i)^(asc|desc)(?:\s+by\s+(\S+))?$`)
+
+// parseMonotonicExpected parses the rest of a monotonic assertion, such as
+// expect body.events monotonic asc by timestamp, into "asc by timestamp".
+// The optional custom message is returned after it.
+func (p *Parser) parseMonotonicExpected() (string, string, error) {
+	line, column := p.curToken.Line, p.curToken.Column
+	raw := ""
+	if p.curToken.Type != TokenNewline && p.curToken.Type != TokenEOF {
+		raw = p.curToken.Value
+		if p.lexer.ch == ' ' || p.lexer.ch == '\t' {
+			raw += " "
+		}
+		raw += p.lexer.ReadRestOfLine()
+		p.nextToken()
+	}
+
+	raw, message := splitAssertionMessage(strings.TrimSpace(raw))
+	m := monotonicOrder.FindStringSubmatch(raw)
+	if m == nil {
+		return "", "", &ParseError{
+			File:    p.file,
+			Line:    line,
+			Column:  column,
+			Message: fmt.Sprintf("expected asc or desc, optionally followed by 'by <field>', after monotonic, got %q", raw),
+		}
+	}
+	order := strings.ToLower(m[1])
+	if m[2] != "" {
+		order += " by " + m[2]
+	}
+	return order, message, nil
+}
+
+var byKeyOption = regexp.MustCompile(`\s+byKey=([\w.-]+)\s*$`)
+
+// parseLiteralExpected parses an expected array or object literal up to the
+// end of the line, with an optional trailing byKey=field option and custom
+// message, which are returned after the value. Arrays that
+// aren't valid JSON, such as [1, 2, pending], are parsed element by element.
+func (p *Parser) parseLiteralExpected() (any, string, string) {
+	open := p.curToken.Value
+	raw := open + p.lexer.ReadRestOfLine()
+	line := p.curToken.Line
+	p.nextToken()
+
+	raw, message := splitAssertionMessage(raw)
+	byKey := ""
+	if m := byKeyOption.FindStringSubmatch(raw); m != nil {
+		byKey = m[1]
+		raw = raw[:len(raw)-len(m[0])]
+	}
+	raw = strings.TrimSpace(raw)
+
+	if open == "[" {
+		var arr []any
+		if err := json.Unmarshal([]byte(raw), &arr); err == nil {
+			return arr, byKey, message
+		}
+		return NewParser(raw).parseArray(), byKey, message
+	}
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: line %d: invalid object literal %s: %v\n", line, raw, err)
+		return raw, byKey, message
+	}
+	return obj, byKey, message
+}
+
 func (p *Parser) parseAssertionSubject() string {
 	var builder strings.Builder
 	for p.curToken.Type != TokenWhitespace &&
@@ -748,67 +1348,128 @@ func (p *Parser) parseAssertionSubject() string {
 	return builder.String()
 }
 
-func (p *Parser) parseAssertionOperator() (AssertionOperator, error) {
+// parseAssertionOperator parses the operator of an assertion. Any operator can
+// be negated with a "not" prefix or a "!" prefix (expect body.url not matches
+// /^http:/), which is reported through the returned negate flag unless the
+// operator has a dedicated negated form such as != or !contains.
+func (p *Parser) parseAssertionOperator() (AssertionOperator, bool, error) {
+	negate := false
+	if p.curToken.Type == TokenIdentifier && strings.EqualFold(p.curToken.Value, "not") {
+		negate = true
+		p.nextToken()
+		p.skipWhitespace()
+		if p.curToken.Type != TokenOperator {
+			return OpEquals, false, &ParseError{
+				File:    p.file,
+				Line:    p.curToken.Line,
+				Column:  p.curToken.Column,
+				Message: "expected operator after 'not', got " + p.curToken.Value,
+			}
+		}
+	}
+
 	if p.curToken.Type != TokenOperator {
-		return OpEquals, nil
+		return OpEquals, false, nil
 	}
 
 	op := strings.ToLower(p.curToken.Value)
+	line, column := p.curToken.Line, p.curToken.Column
 	p.nextToken()
 
-	switch op {
+	operator, ok := operatorFromName(op)
+	if !ok && strings.HasPrefix(op, "!") {
+		// Generic negation for operators without a dedicated "!" form
+		if operator, ok = operatorFromName(op[1:]); ok {
+			negate = !negate
+		}
+	}
+	if !ok {
+		return OpEquals, false, &ParseError{
+			File:    p.file,
+			Line:    line,
+			Column:  column,
+			Message: "unknown operator: " + op,
+		}
+	}
+
+	if negate {
+		if negated, ok := negatedOperators[operator]; ok {
+			return negated, false, nil
+		}
+	}
+	return operator, negate, nil
+}
+
+// negatedOperators maps operators to their dedicated negated form and back
+var negatedOperators = map[AssertionOperator]AssertionOperator{
+	OpEquals:      OpNotEquals,
+	OpNotEquals:   OpEquals,
+	OpContains:    OpNotContains,
+	OpNotContains: OpContains,
+	OpExists:      OpNotExists,
+	OpNotExists:   OpExists,
+	OpIncludes:    OpNotIncludes,
+	OpNotIncludes: OpIncludes,
+	OpIn:          OpNotIn,
+	OpNotIn:       OpIn,
+}
+
+// operatorFromName returns the operator for a lowercased operator name
+func operatorFromName(name string) (AssertionOperator, bool) {
+	switch name {
 	case "==":
-		return OpEquals, nil
+		return OpEquals, true
 	case "!=":
-		return OpNotEquals, nil
+		return OpNotEquals, true
 	case ">":
-		return OpGreaterThan, nil
+		return OpGreaterThan, true
 	case ">=":
-		return OpGreaterOrEqual, nil
+		return OpGreaterOrEqual, true
 	case "<":
-		return OpLessThan, nil
+		return OpLessThan, true
 	case "<=":
-		return OpLessOrEqual, nil
+		return OpLessOrEqual, true
 	case "contains":
-		return OpContains, nil
+		return OpContains, true
 	case "!contains":
-		return OpNotContains, nil
+		return OpNotContains, true
 	case "startswith":
-		return OpStartsWith, nil
+		return OpStartsWith, true
 	case "endswith":
-		return OpEndsWith, nil
+		return OpEndsWith, true
 	case "matches":
-		return OpMatches, nil
+		return OpMatches, true
 	case "exists":
-		return OpExists, nil
+		return OpExists, true
 	case "!exists":
-		return OpNotExists, nil
+		return OpNotExists, true
 	case "length":
-		return OpLength, nil
+		return OpLength, true
 	case "includes":
-		return OpIncludes, nil
+		return OpIncludes, true
 	case "!includes":
-		return OpNotIncludes, nil
+		return OpNotIncludes, true
+	case "includesall":
+		return OpIncludesAll, true
+	case "includesany":
+		return OpIncludesAny, true
 	case "in":
-		return OpIn, nil
+		return OpIn, true
 	case "!in":
-		return OpNotIn, nil
+		return OpNotIn, true
 	case "type":
-		return OpType, nil
+		return OpType, true
 	case "each":
-		return OpEach, nil
+		return OpEach, true
+	case "monotonic":
+		return OpMonotonic, true
 	case "schema":
-		return OpSchema, nil
+		return OpSchema, true
 	case "snapshot":
-		return OpSnapshot, nil
+		return OpSnapshot, true
 	}
 
-	return OpEquals, &ParseError{
-		File:    p.file,
-		Line:    p.curToken.Line,
-		Column:  p.curToken.Column,
-		Message: "unknown operator: " + op,
-	}
+	return OpEquals, false
 }
 
 func (p *Parser) parseAssertionExpected() any {
@@ -838,10 +1499,36 @@ func (p *Parser) parseAssertionExpected() any {
 		return nil
 	case TokenLeftBracket:
 		return p.parseArray()
+	case TokenVariableRef:
+		// Resolved when the request runs; text joined to the reference, as
+		// in {{env}}-eu, is part of the value
+		var builder strings.Builder
+		for p.curToken.Type != TokenWhitespace &&
+			p.curToken.Type != TokenNewline &&
+			p.curToken.Type != TokenEOF &&
+			p.curToken.Type != TokenComma &&
+			p.curToken.Type != TokenRightBracket {
+			if p.curToken.Type == TokenVariableRef {
+				builder.WriteString("{{" + p.curToken.Value + "}}")
+			} else {
+				builder.WriteString(p.curToken.Value)
+			}
+			p.nextTokenRaw()
+		}
+		p.skipWhitespace()
+		return builder.String()
 	case TokenIdentifier:
 		v := p.curToken.Value
+		if p.lexer.ch == '/' {
+			v += p.lexer.ReadWord()
+		}
 		p.nextToken()
 		return v
+	case TokenText:
+		if p.curToken.Value == "{" {
+			return p.parseObjectLiteral()
+		}
+		fallthrough
 	default:
 		v := p.lexer.ReadRestOfLine()
 		p.nextToken()
@@ -849,6 +1536,21 @@ func (p *Parser) parseAssertionExpected() any {
 	}
 }
 
+// parseObjectLiteral parses an inline JSON object such as
+// { "name": "John", "age": 30 } up to the end of the line
+func (p *Parser) parseObjectLiteral() any {
+	raw := "{" + p.lexer.ReadRestOfLine()
+	line := p.curToken.Line
+	p.nextToken()
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: line %d: invalid object literal %s: %v\n", line, raw, err)
+		return raw
+	}
+	return obj
+}
+
 func (p *Parser) parseArray() []any {
 	p.nextToken()
 	var arr []any
@@ -922,10 +1624,34 @@ func (p *Parser) parseCapture() (*Capture, error) {
 	path := strings.TrimSpace(pathBuilder.String())
 
 	source := CaptureBody
-	if strings.HasPrefix(path, "header") {
+	index := 0
+	if strings.HasPrefix(path, "headers[") && strings.HasSuffix(path, "]") {
+		// headers[] Name captures every value, headers[N] Name the Nth value
+		source = CaptureHeaderValues
+		index = -1
+		if idx := path[len("headers[") : len(path)-1]; idx != "" {
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 0 {
+				return nil, &ParseError{
+					File:    p.file,
+					Line:    line,
+					Message: "invalid header value index: " + idx,
+				}
+			}
+			index = n
+		}
+		path = p.readCaptureHeaderName()
+	} else if strings.HasPrefix(path, "header") {
 		source = CaptureHeader
 		path = strings.TrimPrefix(path, "header")
 		path = strings.TrimSpace(path)
+		if path == "" {
+			path = p.readCaptureHeaderName()
+		}
+	} else if path == "cookie" && p.curToken.Type == TokenWhitespace {
+		// cookie Name captures the value of a cookie from Set-Cookie
+		source = CaptureCookie
+		path = p.readCaptureHeaderName()
 	} else if strings.HasPrefix(path, "body.") {
 		path = strings.TrimPrefix(path, "body.")
 	} else if strings.HasPrefix(path, "body") && len(path) > 4 && path[4] == '[' {
@@ -938,16 +1664,92 @@ func (p *Parser) parseCapture() (*Capture, error) {
 		path = ""
 	}
 
+	// Optional transforms and "as <type>" follow the path, or the header name
+	var rest string
+	switch source {
+	case CaptureHeader, CaptureHeaderValues, CaptureCookie:
+		if fields := strings.Fields(path); len(fields) > 0 {
+			path = fields[0]
+			rest = strings.Join(fields[1:], " ")
+		}
+	default:
+		if p.curToken.Type == TokenWhitespace {
+			rest = strings.TrimSpace(p.lexer.ReadRestOfLine())
+		}
+	}
+	transforms, typ, err := parseCaptureTransforms(rest)
+	if err != nil {
+		return nil, &ParseError{
+			File:    p.file,
+			Line:    line,
+			Message: err.Error(),
+		}
+	}
+	if typ != "" && !slices.Contains(CaptureTypes, typ) {
+		return nil, &ParseError{
+			File:    p.file,
+			Line:    line,
+			Message: fmt.Sprintf("unknown capture type %q (expected one of: %s)", typ, strings.Join(CaptureTypes, ", ")),
+		}
+	}
+
 	p.nextToken()
 
 	return &Capture{
-		Name:   name,
-		Source: source,
-		Path:   path,
-		Line:   line,
+		Name:       name,
+		Source:     source,
+		Path:       path,
+		Index:      index,
+		Transforms: transforms,
+		Type:       typ,
+		Line:       line,
 	}, nil
 }
 
+// parseCaptureTransforms parses what follows a capture path: transforms such
+// as "length" or "* 1000", then an optional "as <type>"
+func parseCaptureTransforms(rest string) ([]CaptureTransform, string, error) {
+	fields := strings.Fields(rest)
+	typ := ""
+	if n := len(fields); n >= 2 && fields[n-2] == "as" {
+		typ = strings.ToLower(fields[n-1])
+		fields = fields[:n-2]
+	}
+
+	var transforms []CaptureTransform
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case strings.EqualFold(field, "length"), strings.EqualFold(field, "count"):
+			transforms = append(transforms, CaptureTransform{Op: "length"})
+			continue
+		case strings.ContainsAny(field[:1], "+-*/"):
+			op, operand := field[:1], field[1:]
+			if operand == "" && i+1 < len(fields) {
+				i++
+				operand = fields[i]
+			}
+			n, err := strconv.ParseFloat(operand, 64)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid operand %q for %s in capture", operand, op)
+			}
+			transforms = append(transforms, CaptureTransform{Op: op, Operand: n})
+			continue
+		}
+		return nil, "", fmt.Errorf("unexpected %q after capture path (expected: length, count, an arithmetic operation such as + 1, or as <type>)", strings.Join(fields[i:], " "))
+	}
+	return transforms, typ, nil
+}
+
+// readCaptureHeaderName reads the header name following "header" or
+// "headers[...]" in a capture line
+func (p *Parser) readCaptureHeaderName() string {
+	if p.curToken.Type != TokenWhitespace {
+		return ""
+	}
+	return p.lexer.ReadRestOfLine()
+}
+
 func (p *Parser) parseDBBlock() ([]*DBAssertion, error) {
 	p.nextToken()
 	p.skipNewlines()
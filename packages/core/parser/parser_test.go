@@ -2,6 +2,7 @@ package parser
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -107,6 +108,138 @@ userId from body.user.id
 	assert.Equal(t, "user.id", req.Captures[1].Path)
 }
 
+func TestParser_HeaderCaptures(t *testing.T) {
+	input := `### Login
+POST https://api.example.com/auth/login
+
+>>>capture
+requestId from header X-Request-Id
+cookies from headers[] Set-Cookie
+session from headers[1] Set-Cookie
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	req := file.Requests[0]
+	require.Len(t, req.Captures, 3)
+	assert.Equal(t, CaptureHeader, req.Captures[0].Source)
+	assert.Equal(t, "X-Request-Id", req.Captures[0].Path)
+	assert.Equal(t, CaptureHeaderValues, req.Captures[1].Source)
+	assert.Equal(t, "Set-Cookie", req.Captures[1].Path)
+	assert.Equal(t, -1, req.Captures[1].Index)
+	assert.Equal(t, CaptureHeaderValues, req.Captures[2].Source)
+	assert.Equal(t, "Set-Cookie", req.Captures[2].Path)
+	assert.Equal(t, 1, req.Captures[2].Index)
+}
+
+func TestParser_CookieCaptures(t *testing.T) {
+	input := `### Login
+POST https://api.example.com/auth/login
+
+>>>capture
+session from cookie session_id
+cookie from body.cookie
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	req := file.Requests[0]
+	require.Len(t, req.Captures, 2)
+	assert.Equal(t, CaptureCookie, req.Captures[0].Source)
+	assert.Equal(t, "session_id", req.Captures[0].Path)
+	assert.Equal(t, CaptureBody, req.Captures[1].Source)
+	assert.Equal(t, "cookie", req.Captures[1].Path)
+}
+
+func TestParser_CaptureTypes(t *testing.T) {
+	input := `### Create
+POST https://api.example.com/users
+
+>>>capture
+id from body.id as int
+code from status as string
+limit from header X-Rate-Limit as int
+session from cookie sid as string
+raw from body.raw
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	captures := file.Requests[0].Captures
+	require.Len(t, captures, 5)
+	assert.Equal(t, "id", captures[0].Path)
+	assert.Equal(t, "int", captures[0].Type)
+	assert.Equal(t, CaptureStatus, captures[1].Source)
+	assert.Equal(t, "string", captures[1].Type)
+	assert.Equal(t, "X-Rate-Limit", captures[2].Path)
+	assert.Equal(t, "int", captures[2].Type)
+	assert.Equal(t, "sid", captures[3].Path)
+	assert.Equal(t, "string", captures[3].Type)
+	assert.Empty(t, captures[4].Type)
+
+	_, err = Parse(`### Bad
+GET https://api.example.com/users
+
+>>>capture
+id from body.id as uuid
+<<<`, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown capture type")
+}
+
+func TestParser_CaptureTransforms(t *testing.T) {
+	input := `### List
+GET https://api.example.com/users
+
+>>>capture
+total from body.items length
+pages from body.items count / 10
+next from body.page +1 as string
+ms from duration * 1000
+size from header Content-Length - 2 as int
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	captures := file.Requests[0].Captures
+	require.Len(t, captures, 5)
+	assert.Equal(t, "items", captures[0].Path)
+	assert.Equal(t, []CaptureTransform{{Op: "length"}}, captures[0].Transforms)
+	assert.Equal(t, []CaptureTransform{{Op: "length"}, {Op: "/", Operand: 10}}, captures[1].Transforms)
+	assert.Equal(t, []CaptureTransform{{Op: "+", Operand: 1}}, captures[2].Transforms)
+	assert.Equal(t, "string", captures[2].Type)
+	assert.Equal(t, []CaptureTransform{{Op: "*", Operand: 1000}}, captures[3].Transforms)
+	assert.Equal(t, "Content-Length", captures[4].Path)
+	assert.Equal(t, []CaptureTransform{{Op: "-", Operand: 2}}, captures[4].Transforms)
+	assert.Equal(t, "int", captures[4].Type)
+
+	_, err = Parse(`### Bad
+GET https://api.example.com/users
+
+>>>capture
+total from body.items sum
+<<<`, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unexpected "sum" after capture path`)
+
+	_, err = Parse(`### Bad
+GET https://api.example.com/users
+
+>>>capture
+total from body.total * many
+<<<`, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid operand "many"`)
+}
+
 func TestParser_Annotations(t *testing.T) {
 	input := `### Test Request
 # @name myTest
@@ -127,7 +260,7 @@ GET https://api.example.com/test`
 	assert.Equal(t, "This is a test request", req.Description)
 	assert.Contains(t, req.Tags, "smoke")
 	assert.Contains(t, req.Tags, "auth")
-	assert.Equal(t, 5000, req.Metadata.Timeout)
+	assert.Equal(t, 5*time.Second, req.Metadata.Timeout)
 	assert.Equal(t, 3, req.Metadata.Retry)
 }
 
@@ -168,6 +301,9 @@ func TestParser_AssertionOperators(t *testing.T) {
 		{"expect body.id exists", OpExists},
 		{"expect body.error !exists", OpNotExists},
 		{"expect body.items length 10", OpLength},
+		{"expect body.roles includesAll [admin, user]", OpIncludesAll},
+		{"expect body.roles includesAny [admin, owner]", OpIncludesAny},
+		{"expect body.events monotonic asc by timestamp", OpMonotonic},
 	}
 
 	for _, tt := range tests {
@@ -182,6 +318,183 @@ func TestParser_AssertionOperators(t *testing.T) {
 	}
 }
 
+func TestParser_NegatedOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected AssertionOperator
+		negate   bool
+	}{
+		{"expect body.url not matches /^http:/", OpMatches, true},
+		{"expect body.name !startsWith \"tmp\"", OpStartsWith, true},
+		{"expect body.items !length 0", OpLength, true},
+		{"expect body.id not type string", OpType, true},
+		{"expect body.name not contains \"error\"", OpNotContains, false},
+		{"expect body.error not exists", OpNotExists, false},
+		{"expect status not != 200", OpEquals, false},
+		{"expect body.tags not !includes \"api\"", OpIncludes, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			input := "### Test\nGET http://test.com\n\n>>>\n" + tt.input + "\n<<<"
+			file, err := Parse(input, "test.http")
+			require.NoError(t, err)
+			require.Len(t, file.Requests[0].Assertions, 1)
+			a := file.Requests[0].Assertions[0]
+			assert.Equal(t, tt.expected, a.Operator)
+			assert.Equal(t, tt.negate, a.Negate)
+		})
+	}
+
+	t.Run("not without operator", func(t *testing.T) {
+		_, err := Parse("### Test\nGET http://test.com\n\n>>>\nexpect body.name not \"x\"\n<<<", "test.http")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expected operator after 'not'")
+	})
+}
+
+func TestParser_ObjectLiteralExpected(t *testing.T) {
+	input := `### Test
+GET http://test.com
+
+>>>
+expect body.user == { "name": "John", "age": 30, "tags": ["a"] }
+expect status 200
+<<<`
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests[0].Assertions, 2)
+
+	a := file.Requests[0].Assertions[0]
+	assert.Equal(t, OpEquals, a.Operator)
+	assert.Equal(t, map[string]any{"name": "John", "age": float64(30), "tags": []any{"a"}}, a.Expected)
+	assert.Equal(t, "status", file.Requests[0].Assertions[1].Subject)
+}
+
+func TestParser_ArrayLiteralByKey(t *testing.T) {
+	input := `### Test
+GET http://test.com
+
+>>>
+expect body.items == [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}] byKey=id
+expect body.tags == [admin, "ops", 3]
+expect body.user == {"roles": [{"id": 1}]} byKey=id
+<<<`
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assertions := file.Requests[0].Assertions
+	require.Len(t, assertions, 3)
+
+	assert.Equal(t, []any{
+		map[string]any{"id": float64(1), "name": "a"},
+		map[string]any{"id": float64(2), "name": "b"},
+	}, assertions[0].Expected)
+	assert.Equal(t, "id", assertions[0].ByKey)
+	assert.Equal(t, []any{"admin", "ops", 3}, assertions[1].Expected)
+	assert.Empty(t, assertions[1].ByKey)
+	assert.Equal(t, map[string]any{"roles": []any{map[string]any{"id": float64(1)}}}, assertions[2].Expected)
+	assert.Equal(t, "id", assertions[2].ByKey)
+}
+
+func TestParser_Imports(t *testing.T) {
+	input := `# @import common.http
+# @import ./shared/auth.http
+@baseUrl = https://api.example.com
+
+### Profile
+# @depends login
+GET {{baseUrl}}/me`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"common.http", "./shared/auth.http"}, file.Imports)
+	require.Len(t, file.Requests, 1)
+	assert.Equal(t, []string{"login"}, file.Requests[0].Metadata.Depends)
+}
+
+func TestParser_ImportAfterRequest(t *testing.T) {
+	input := `### Profile
+# @import common.http
+GET https://api.example.com/me`
+
+	_, err := Parse(input, "test.http")
+	assert.Error(t, err)
+}
+
+func TestParser_ExpectStatus(t *testing.T) {
+	input := `### Redirect
+# @expect-status 3xx, 404
+GET https://api.example.com/old`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	meta := file.Requests[0].Metadata
+	assert.Equal(t, []StatusRange{{Min: 300, Max: 399}, {Min: 404, Max: 404}}, meta.ExpectStatus)
+	assert.True(t, meta.AcceptsStatus(302))
+	assert.True(t, meta.AcceptsStatus(404))
+	assert.False(t, meta.AcceptsStatus(200))
+	assert.False(t, meta.AcceptsStatus(500))
+
+	var empty *RequestMetadata
+	assert.True(t, empty.AcceptsStatus(204))
+	assert.False(t, empty.AcceptsStatus(404))
+}
+
+func TestParser_Encoding(t *testing.T) {
+	input := `### Legacy
+# @encoding Shift_JIS
+POST https://legacy.example.com/orders
+
+### Unknown
+# @encoding klingon
+POST https://legacy.example.com/orders`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 2)
+
+	assert.Equal(t, "shift_jis", file.Requests[0].Metadata.Encoding)
+	assert.Empty(t, file.Requests[1].Metadata.Encoding)
+}
+
+func TestParser_MultipartBody(t *testing.T) {
+	input := `### Upload
+POST https://api.example.com/upload
+
+>>>multipart
+field name = John Doe
+file avatar = ./photo.jpg
+file document = ./report.pdf, application/pdf
+file scan = ./scan.bin; type=image/tiff; filename=scan.tiff
+title = Quarterly
+attachment < @./notes.txt
+field meta < ./meta.json; type=application/json
+<<<
+
+>>>
+expect status 200
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 1)
+
+	req := file.Requests[0]
+	require.NotNil(t, req.Body)
+	assert.Equal(t, []*MultipartField{
+		{Type: MultipartFieldValue, Name: "name", Value: "John Doe"},
+		{Type: MultipartFieldFile, Name: "avatar", Path: "./photo.jpg"},
+		{Type: MultipartFieldFile, Name: "document", Path: "./report.pdf", ContentType: "application/pdf"},
+		{Type: MultipartFieldFile, Name: "scan", Path: "./scan.bin", ContentType: "image/tiff", Filename: "scan.tiff"},
+		{Type: MultipartFieldValue, Name: "title", Value: "Quarterly"},
+		{Type: MultipartFieldFile, Name: "attachment", Path: "./notes.txt"},
+		{Type: MultipartFieldValue, Name: "meta", Path: "./meta.json", ContentType: "application/json"},
+	}, req.Body.Multipart)
+	require.Len(t, req.Assertions, 1)
+}
+
 func TestParser_QueryParams(t *testing.T) {
 	input := `### Search
 GET https://api.example.com/search
@@ -249,3 +562,599 @@ GET https://api.example.com/skip`
 	req := file.Requests[0]
 	assert.Equal(t, "This test is temporarily disabled", req.Metadata.Skip)
 }
+
+func TestParser_DuplicateNames(t *testing.T) {
+	input := `### First
+# @name getUser
+GET https://api.example.com/users/1
+
+### Second
+# @name getUser
+GET https://api.example.com/users/2`
+
+	_, err := Parse(input, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `duplicate request name "getUser"`)
+}
+
+func TestParser_MediaTypeExpected(t *testing.T) {
+	input := `GET https://api.example.com/avatar
+
+>>>
+expect contentType json
+expect contentType image/png
+expect header Content-Type == application/vnd.api+json
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests[0].Assertions, 3)
+
+	a := file.Requests[0].Assertions
+	assert.Equal(t, "contentType", a[0].Subject)
+	assert.Equal(t, "json", a[0].Expected)
+	assert.Equal(t, "image/png", a[1].Expected)
+	assert.Equal(t, "application/vnd.api+json", a[2].Expected)
+}
+
+func TestParser_ExtensionMethods(t *testing.T) {
+	input := `### List collection
+PROPFIND {{baseUrl}}/dav/files/
+Depth: 1
+Content-Type: application/xml
+
+<?xml version="1.0"?>
+<propfind xmlns="DAV:"><allprop/></propfind>
+
+>>>
+expect status 207
+<<<
+
+### Create collection
+MKCOL https://dav.example.com/dav/new/
+
+### Version control
+VERSION-CONTROL /dav/files/report.txt`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 3)
+
+	assert.Equal(t, "PROPFIND", file.Requests[0].Method)
+	assert.Equal(t, "{{baseUrl}}/dav/files/", file.Requests[0].URL)
+	require.Len(t, file.Requests[0].Headers, 2)
+	assert.Contains(t, file.Requests[0].Body.Raw, "<allprop/>")
+	assert.Equal(t, "MKCOL", file.Requests[1].Method)
+	assert.Equal(t, "VERSION-CONTROL", file.Requests[2].Method)
+	assert.Equal(t, "/dav/files/report.txt", file.Requests[2].URL)
+}
+
+func TestParser_BodyFile(t *testing.T) {
+	input := `### Upload
+# @body-file ./fixtures/{{size}}.json
+POST https://api.example.com/import`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.Equal(t, "./fixtures/{{size}}.json", file.Requests[0].Metadata.BodyFile)
+}
+
+func TestParser_RequestSchema(t *testing.T) {
+	input := `### Create user
+# @request-schema ./schemas/user.request.json
+POST https://api.example.com/users
+Content-Type: application/json
+
+{"name": "John"}`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.Equal(t, "./schemas/user.request.json", file.Requests[0].Metadata.BodySchema)
+}
+
+func TestParser_Persist(t *testing.T) {
+	input := `### Login
+# @persist token, refreshToken
+POST https://api.example.com/login
+
+### Profile
+# @persist
+GET https://api.example.com/me
+
+### Other
+GET https://api.example.com/other`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 3)
+	assert.True(t, file.Requests[0].Metadata.Persist)
+	assert.Equal(t, []string{"token", "refreshToken"}, file.Requests[0].Metadata.PersistNames)
+	assert.True(t, file.Requests[1].Metadata.Persist)
+	assert.Empty(t, file.Requests[1].Metadata.PersistNames)
+	assert.False(t, file.Requests[2].Metadata.Persist)
+}
+
+func TestParser_Teardown(t *testing.T) {
+	input := `### Delete user
+# @teardown
+DELETE https://api.example.com/users/1
+
+### Get user
+GET https://api.example.com/users/1`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.True(t, file.Requests[0].Metadata.Teardown)
+	assert.False(t, file.Requests[1].Metadata.Teardown)
+}
+
+func TestParser_Group(t *testing.T) {
+	input := `### Create cart
+# @group checkout
+# @setup
+POST https://api.example.com/carts
+
+### Pay
+# @group checkout
+POST https://api.example.com/payments
+
+### Health
+GET https://api.example.com/health`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", file.Requests[0].Metadata.Group)
+	assert.True(t, file.Requests[0].Metadata.GroupSetup)
+	assert.Equal(t, "checkout", file.Requests[1].Metadata.Group)
+	assert.False(t, file.Requests[1].Metadata.GroupSetup)
+	assert.Empty(t, file.Requests[2].Metadata.Group)
+}
+
+func TestParser_NDJSON(t *testing.T) {
+	input := `### Events
+# @ndjson
+GET https://api.example.com/events
+
+### Logs
+# @jsonl
+GET https://api.example.com/logs
+
+### Health
+GET https://api.example.com/health`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	assert.True(t, file.Requests[0].Metadata.NDJSON)
+	assert.True(t, file.Requests[1].Metadata.NDJSON)
+	assert.False(t, file.Requests[2].Metadata.NDJSON)
+}
+
+func TestParser_RawAssertion(t *testing.T) {
+	input := `### Signed
+GET https://api.example.com/signed
+
+>>>
+expect raw == "  {\"ok\": true}\r\n"
+expect raw contains "\t"
+expect body.raw == "\n"
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+
+	assertions := file.Requests[0].Assertions
+	require.Len(t, assertions, 3)
+	assert.Equal(t, "  {\"ok\": true}\r\n", assertions[0].Expected)
+	assert.Equal(t, "\t", assertions[1].Expected)
+	// Escapes are only interpreted for the raw subject
+	assert.Equal(t, `\n`, assertions[2].Expected)
+}
+
+func TestParser_RequestVariables(t *testing.T) {
+	input := `### Signed
+# @var path = /orders/{{orderId}}
+# @var stamp = {{$timestamp()}}
+POST https://api.example.com{{path}}
+
+### Other
+GET https://api.example.com/health`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 2)
+	require.Len(t, file.Requests[0].Variables, 2)
+	assert.Equal(t, "path", file.Requests[0].Variables[0].Name)
+	assert.Equal(t, "/orders/{{orderId}}", file.Requests[0].Variables[0].Value)
+	assert.Equal(t, "stamp", file.Requests[0].Variables[1].Name)
+	assert.Empty(t, file.Requests[1].Variables)
+	assert.Empty(t, file.Variables)
+
+	_, err = Parse(`### Bad
+# @var path
+GET https://api.example.com`, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected: @var name = value")
+}
+
+func TestParser_DataFrom(t *testing.T) {
+	input := `### Check user
+# @name checkUser
+# @data-from listUsers.body.users
+GET https://api.example.com/users/{{item.id}}`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	meta := file.Requests[0].Metadata
+	assert.Equal(t, "listUsers.body.users", meta.DataFrom)
+	assert.Equal(t, []string{"listUsers"}, meta.Depends)
+
+	_, err = Parse(`### Bad
+# @data-from listUsers
+GET https://api.example.com`, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "@data-from needs a request name and a path")
+}
+
+func TestParser_VariableExpectedValues(t *testing.T) {
+	input := `GET https://api.example.com/info
+
+>>>
+expect body.env == {{env}}
+expect body.region == {{env}}-eu : "wrong region"
+expect body.tier in [{{tier}}, "free"]
+expect body.name == "{{name}}"
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	asserts := file.Requests[0].Assertions
+	require.Len(t, asserts, 4)
+	assert.Equal(t, "{{env}}", asserts[0].Expected)
+	assert.Equal(t, "{{env}}-eu", asserts[1].Expected)
+	assert.Equal(t, "wrong region", asserts[1].Message)
+	assert.Equal(t, []any{"{{tier}}", "free"}, asserts[2].Expected)
+	assert.Equal(t, "{{name}}", asserts[3].Expected)
+}
+
+func TestParser_TimeoutDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"5000", 5 * time.Second},
+		{"500ms", 500 * time.Millisecond},
+		{"2s", 2 * time.Second},
+		{"1m", time.Minute},
+		{"soon", 0},
+		{"-1s", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			file, err := Parse("### Test\n# @timeout "+tt.value+"\nGET https://example.com\n", "test.http")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, file.Requests[0].Metadata.Timeout)
+		})
+	}
+}
+
+func TestParser_GeneratedNames(t *testing.T) {
+	input := `###
+GET {{baseUrl}}/users/{{id}}?expand=true
+
+###
+GET https://api.example.com/users/{{id}}
+
+###
+# @name get_users_id_2
+POST {{baseUrl}}/users
+
+###
+GET {{baseUrl}}/users/{{id}}
+
+### Health
+GET https://api.example.com/
+
+###
+DELETE https://api.example.com`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 6)
+
+	var names []string
+	for _, req := range file.Requests {
+		names = append(names, req.Name)
+	}
+	assert.Equal(t, []string{"get_users_id", "get_users_id_3", "get_users_id_2", "get_users_id_4", "Health", "delete"}, names)
+	assert.True(t, file.Requests[0].GeneratedName)
+	assert.False(t, file.Requests[2].GeneratedName)
+	assert.False(t, file.Requests[4].GeneratedName)
+
+	again, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	for i, req := range again.Requests {
+		assert.Equal(t, names[i], req.Name)
+	}
+}
+
+func TestParser_Monotonic(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+		message  string
+	}{
+		{"expect body.events monotonic asc by timestamp", "asc by timestamp", ""},
+		{"expect body.events monotonic DESC by meta.createdAt", "desc by meta.createdAt", ""},
+		{"expect body.times monotonic asc", "asc", ""},
+		{`expect body.events monotonic asc by at : "events out of order"`, "asc by at", "events out of order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			file, err := Parse("### Test\nGET http://test.com\n\n>>>\n"+tt.line+"\nexpect status 200\n<<<", "test.http")
+			require.NoError(t, err)
+			require.Len(t, file.Requests[0].Assertions, 2)
+			a := file.Requests[0].Assertions[0]
+			assert.Equal(t, OpMonotonic, a.Operator)
+			assert.Equal(t, tt.expected, a.Expected)
+			assert.Equal(t, tt.message, a.Message)
+		})
+	}
+
+	for _, line := range []string{"expect body.events monotonic", "expect body.events monotonic up by at"} {
+		_, err := Parse("### Test\nGET http://test.com\n\n>>>\n"+line+"\n<<<", "test.http")
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParser_FollowRedirects(t *testing.T) {
+	input := `### Inspect
+# @follow-redirects false
+GET https://api.example.com/login
+
+### Follow
+# @follow-redirects true
+GET https://api.example.com/login
+
+### Bare
+# @follow-redirects
+GET https://api.example.com/login
+
+### Disabled
+# @disable-redirects
+GET https://api.example.com/login
+
+### Default
+GET https://api.example.com/login`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.Requests, 5)
+
+	for i, want := range []bool{false, true, true, false} {
+		require.NotNil(t, file.Requests[i].Metadata.FollowRedirects, file.Requests[i].Name)
+		assert.Equal(t, want, *file.Requests[i].Metadata.FollowRedirects, file.Requests[i].Name)
+	}
+	if m := file.Requests[4].Metadata; m != nil {
+		assert.Nil(t, m.FollowRedirects)
+	}
+}
+
+func TestParser_MaxRedirects(t *testing.T) {
+	input := `### Limited
+# @max-redirects 0
+GET https://api.example.com/old
+
+### Default
+GET https://api.example.com/new`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.NotNil(t, file.Requests[0].Metadata.MaxRedirects)
+	assert.Equal(t, 0, *file.Requests[0].Metadata.MaxRedirects)
+	assert.Nil(t, file.Requests[1].Metadata.MaxRedirects)
+}
+
+func TestParser_HeaderNotExists(t *testing.T) {
+	input := `GET https://api.example.com/
+
+>>>
+expect header X-Powered-By !exists
+expect header Server not exists
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	a := file.Requests[0].Assertions
+	require.Len(t, a, 2)
+	assert.Equal(t, "header X-Powered-By", a[0].Subject)
+	assert.Equal(t, OpNotExists, a[0].Operator)
+	assert.Equal(t, "header Server", a[1].Subject)
+	assert.Equal(t, OpNotExists, a[1].Operator)
+}
+
+func TestParser_SecureHeaders(t *testing.T) {
+	input := `GET https://api.example.com/
+
+>>>
+expect secure-headers
+expect status 200
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	a := file.Requests[0].Assertions
+	require.Len(t, a, 2)
+	assert.Equal(t, "secure-headers", a[0].Subject)
+	assert.Equal(t, OpExists, a[0].Operator)
+	assert.Equal(t, "status", a[1].Subject)
+
+	for _, line := range []string{"expect secure-headers not exists", "expect secure-headers == 1"} {
+		_, err := Parse("GET https://api.example.com/\n\n>>>\n"+line+"\n<<<", "test.http")
+		assert.ErrorContains(t, err, "secure-headers takes no operator", line)
+	}
+}
+
+func TestParser_AssertionMessage(t *testing.T) {
+	input := `GET https://api.example.com/account
+
+>>>
+expect body.balance >= 0 : "balance must never be negative"
+expect body.currency == "EUR" : "accounts are in euros"
+expect body.status == active : "account must be active"
+expect body.id exists : "every account has an id"
+expect body.roles includes ["admin"] : "owner is an admin"
+expect header Content-Type contains application/json : "always \"JSON\""
+expect secure-headers : "security baseline"
+expect status 200
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	a := file.Requests[0].Assertions
+	require.Len(t, a, 8)
+
+	assert.Equal(t, 0, a[0].Expected)
+	assert.Equal(t, "balance must never be negative", a[0].Message)
+	assert.Equal(t, "EUR", a[1].Expected)
+	assert.Equal(t, "accounts are in euros", a[1].Message)
+	assert.Equal(t, "active", a[2].Expected)
+	assert.Equal(t, "account must be active", a[2].Message)
+	assert.Equal(t, "every account has an id", a[3].Message)
+	assert.Equal(t, []any{"admin"}, a[4].Expected)
+	assert.Equal(t, "owner is an admin", a[4].Message)
+	assert.Equal(t, "application/json", a[5].Expected)
+	assert.Equal(t, `always "JSON"`, a[5].Message)
+	assert.Equal(t, "security baseline", a[6].Message)
+	assert.Empty(t, a[7].Message)
+}
+
+func TestParser_AssertionMessageUnquoted(t *testing.T) {
+	input := `GET https://api.example.com/
+
+>>>
+expect status 200 : must succeed
+<<<`
+
+	_, err := Parse(input, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected a quoted message")
+}
+
+func TestParser_DefaultHeaders(t *testing.T) {
+	input := `@baseUrl = https://api.example.com
+
+@headers
+Accept: application/json
+X-Api-Key: {{apiKey}}
+
+### List users
+GET {{baseUrl}}/users
+
+### Create user
+POST {{baseUrl}}/users
+accept: text/plain
+Content-Type: application/json
+
+{"name": "test"}`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	require.Len(t, file.DefaultHeaders, 2)
+	require.Len(t, file.Variables, 1)
+
+	headers := func(req *Request) map[string]string {
+		m := make(map[string]string)
+		for _, h := range req.Headers {
+			m[h.Key] = h.Value
+		}
+		return m
+	}
+	assert.Equal(t, map[string]string{
+		"Accept":    "application/json",
+		"X-Api-Key": "{{apiKey}}",
+	}, headers(file.Requests[0]))
+	assert.Equal(t, map[string]string{
+		"accept":       "text/plain",
+		"X-Api-Key":    "{{apiKey}}",
+		"Content-Type": "application/json",
+	}, headers(file.Requests[1]))
+	assert.Contains(t, file.Requests[1].Body.Raw, `"test"`)
+}
+
+func TestParser_DefaultHeadersAfterRequest(t *testing.T) {
+	input := `### List users
+GET https://api.example.com/users
+
+### Create user
+# @headers
+POST https://api.example.com/users`
+
+	_, err := Parse(input, "test.http")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "@headers must appear before the first request")
+}
+
+func TestParser_StdinBody(t *testing.T) {
+	input := `POST https://api.example.com/fuzz
+Content-Type: application/json
+
+< -
+
+>>>
+expect status 200
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	req := file.Requests[0]
+	require.NotNil(t, req.Body)
+	assert.Equal(t, BodyStdin, req.Body.ContentType)
+	assert.Len(t, req.Assertions, 1)
+}
+
+func TestParser_PatchBlock(t *testing.T) {
+	input := `PATCH https://api.example.com/users/1
+
+>>>patch
+replace /name "Jane Doe"
+add /tags/- {{tag}}
+remove /legacy
+copy /address /billingAddress
+<<<
+
+>>>
+expect status 200
+<<<`
+
+	file, err := Parse(input, "test.http")
+	require.NoError(t, err)
+	req := file.Requests[0]
+	require.NotNil(t, req.Body)
+	assert.Equal(t, BodyJSONPatch, req.Body.ContentType)
+	require.Len(t, req.Body.Patch, 4)
+	assert.Equal(t, PatchOperation{Op: "replace", Path: "/name", Value: `"Jane Doe"`, Line: 4}, *req.Body.Patch[0])
+	assert.Equal(t, "{{tag}}", req.Body.Patch[1].Value)
+	assert.Equal(t, "remove", req.Body.Patch[2].Op)
+	assert.Equal(t, "/address", req.Body.Patch[3].From)
+	assert.Equal(t, "/billingAddress", req.Body.Patch[3].Path)
+	assert.Len(t, req.Assertions, 1)
+}
+
+func TestParser_PatchBlockErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"rename /a /b", "unknown patch operation"},
+		{"replace /name", "expected: replace /path value"},
+		{"remove /a /b", "expected: remove /path"},
+		{"add name 1", "JSON pointers start with /"},
+	}
+	for _, tt := range tests {
+		input := "PATCH https://api.example.com/users/1\n\n>>>patch\n" + tt.line + "\n<<<\n"
+		_, err := Parse(input, "test.http")
+		require.Error(t, err, tt.line)
+		assert.Contains(t, err.Error(), tt.want)
+		assert.Contains(t, err.Error(), ":4", "error reports the line")
+	}
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+)
+
+// validateBody checks the interpolated body of a request against its
+// @request-schema, so a malformed payload fails before it is sent
+func validateBody(req *http.Request, schemaPath, baseDir string) error {
+	body, err := req.ReadBody()
+	if err != nil {
+		return fmt.Errorf("reading request body for @request-schema: %w", err)
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("request body isn't valid JSON, so it can't match %s", schemaPath)
+	}
+	if err := assertions.ValidateSchema(body, schemaPath, baseDir); err != nil {
+		return fmt.Errorf("request body doesn't match %s, not sent: %w", schemaPath, err)
+	}
+	return nil
+}
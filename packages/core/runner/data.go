@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// runDataRequest runs a @data-from request once for each element of the array
+// it references, with the element bound to {{item}} and its position to
+// {{index}}. Each run is reported as name[index].
+func (r *Runner) runDataRequest(req *parser.Request, baseDir string, filePath string) []*RequestResult {
+	items, err := r.dataItems(req.Metadata.DataFrom)
+	if err != nil {
+		return []*RequestResult{{Name: req.Name, Error: err}}
+	}
+	if len(items) == 0 {
+		return []*RequestResult{{
+			Name:       req.Name,
+			Skipped:    true,
+			SkipReason: fmt.Sprintf("no data from %s", req.Metadata.DataFrom),
+		}}
+	}
+
+	results := make([]*RequestResult, len(items))
+	for i, item := range items {
+		data := map[string]any{"index": i}
+		bindItem(data, "item", item)
+		result := r.runRequestWithRetry(req, baseDir, filePath, false, data)
+		result.Name = fmt.Sprintf("%s[%d]", req.Name, i)
+		results[i] = result
+	}
+	return results
+}
+
+// dataItems returns the elements of the array a @data-from expression refers
+// to: a capture such as listUsers.users, or a path into the stored response of
+// a request such as listUsers.body.users
+func (r *Runner) dataItems(expr string) ([]any, error) {
+	name, path, _ := strings.Cut(expr, ".")
+	path = strings.TrimPrefix(path, "$response.")
+
+	var data []byte
+	if value, ok := r.resolver.GetCapture(name + "." + path); ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("@data-from %s: %w", expr, err)
+		}
+		data = encoded
+	} else if value, ok := r.resolver.LookupResponse(name + ".$response." + path); ok {
+		data = []byte(value)
+	} else {
+		return nil, fmt.Errorf("@data-from %s: no capture or response value found", expr)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var items []any
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("@data-from %s is not a JSON array", expr)
+	}
+	return items, nil
+}
+
+// bindItem sets name to value in vars, and for objects and arrays also every
+// nested field, so {{item.user.id}} and {{item.tags.0}} resolve. Objects and
+// arrays themselves are bound as JSON.
+func bindItem(vars map[string]any, name string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			bindItem(vars, name+"."+key, field)
+		}
+	case []any:
+		for i, elem := range v {
+			bindItem(vars, name+"."+strconv.Itoa(i), elem)
+		}
+	case nil:
+		vars[name] = "null"
+		return
+	default:
+		vars[name] = v
+		return
+	}
+	encoded, _ := json.Marshal(value)
+	vars[name] = string(encoded)
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// DependencyGraph holds the @depends relationships between requests
+type DependencyGraph struct {
+	Requests   map[string]*parser.Request
+	Dependents map[string][]string // Request key to the requests depending on it, in file order
+	Missing    map[string][]string // Request key to the dependencies that don't exist
+	InDegree   map[string]int      // Request key to the number of dependencies that exist
+}
+
+// RequestKey identifies a request in a DependencyGraph: its name, or a
+// unique placeholder for unnamed requests, which can't be depended on
+func RequestKey(req *parser.Request) string {
+	if req.Name == "" {
+		return fmt.Sprintf("__anon_%p", req)
+	}
+	return req.Name
+}
+
+// NewDependencyGraph builds the dependency graph of requests
+func NewDependencyGraph(requests []*parser.Request) *DependencyGraph {
+	g := &DependencyGraph{
+		Requests:   make(map[string]*parser.Request),
+		Dependents: make(map[string][]string),
+		Missing:    make(map[string][]string),
+		InDegree:   make(map[string]int),
+	}
+	for _, req := range requests {
+		key := RequestKey(req)
+		g.Requests[key] = req
+		g.InDegree[key] = 0
+	}
+
+	for _, req := range requests {
+		if req.Metadata == nil {
+			continue
+		}
+		key := RequestKey(req)
+		for _, dep := range req.Metadata.Depends {
+			if _, exists := g.Requests[dep]; exists {
+				g.Dependents[dep] = append(g.Dependents[dep], key)
+				g.InDegree[key]++
+			} else {
+				g.Missing[key] = append(g.Missing[key], dep)
+			}
+		}
+	}
+	return g
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// GroupResult is the outcome of the requests sharing a @group; the group
+// passes when none of them failed
+type GroupResult struct {
+	Name    string
+	Passed  bool
+	Results []*RequestResult
+}
+
+// groupOf returns the @group of a request, or "" when it has none
+func groupOf(req *parser.Request) string {
+	if req.Metadata == nil {
+		return ""
+	}
+	return req.Metadata.Group
+}
+
+func isGroupSetup(req *parser.Request) bool {
+	return req.Metadata != nil && req.Metadata.GroupSetup
+}
+
+// groupOrder moves the requests of each @group together, at the position of
+// the group's first request, with its @setup requests first. The order is
+// kept as is when grouping would run a request before one it depends on.
+func groupOrder(requests []*parser.Request) []*parser.Request {
+	var buckets [][]*parser.Request
+	index := make(map[string]int)
+	for _, req := range requests {
+		group := groupOf(req)
+		if group == "" {
+			buckets = append(buckets, []*parser.Request{req})
+			continue
+		}
+		i, ok := index[group]
+		if !ok {
+			i = len(buckets)
+			index[group] = i
+			buckets = append(buckets, nil)
+		}
+		buckets[i] = append(buckets[i], req)
+	}
+	if len(index) == 0 {
+		return requests
+	}
+
+	ordered := make([]*parser.Request, 0, len(requests))
+	for _, bucket := range buckets {
+		sort.SliceStable(bucket, func(i, j int) bool {
+			return isGroupSetup(bucket[i]) && !isGroupSetup(bucket[j])
+		})
+		ordered = append(ordered, bucket...)
+	}
+
+	// Dependencies win over grouping
+	names := make(map[string]bool)
+	for _, req := range requests {
+		if req.Name != "" {
+			names[req.Name] = true
+		}
+	}
+	done := make(map[string]bool)
+	for _, req := range ordered {
+		if req.Metadata != nil {
+			for _, dep := range req.Metadata.Depends {
+				if names[dep] && !done[dep] {
+					fmt.Fprintf(os.Stderr, "warning: request %q depends on %q, which runs later when grouped; groups may be interleaved\n", RequestKey(req), dep)
+					return requests
+				}
+			}
+		}
+		done[req.Name] = true
+	}
+	return ordered
+}
+
+// groupResults collects the results of each group, in order of first appearance
+func groupResults(results []*RequestResult) []*GroupResult {
+	var groups []*GroupResult
+	index := make(map[string]*GroupResult)
+	for _, r := range results {
+		if r.Group == "" {
+			continue
+		}
+		g, ok := index[r.Group]
+		if !ok {
+			g = &GroupResult{Name: r.Group, Passed: true}
+			index[r.Group] = g
+			groups = append(groups, g)
+		}
+		g.Results = append(g.Results, r)
+		if !r.Skipped && !r.Passed && !r.SoftFailed {
+			g.Passed = false
+		}
+	}
+	return groups
+}
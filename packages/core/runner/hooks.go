@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 )
@@ -75,7 +76,10 @@ func (r *Runner) executeHook(hook *parser.Hook, baseDir string, resolver func(st
 	cmd.Dir = baseDir
 	cmd.Env = os.Environ()
 
+	r.log.Debug("running hook", "command", cmdStr, "dir", baseDir)
+	start := time.Now()
 	output, err := cmd.CombinedOutput()
+	r.log.Debug("hook finished", "command", cmdStr, "duration", time.Since(start), "error", err)
 	if err != nil {
 		return fmt.Errorf("command %q failed: %v\nOutput: %s", hook.Command, err, string(output))
 	}
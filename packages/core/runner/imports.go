@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// importedFile is a file pulled in with @import
+type importedFile struct {
+	path string
+	file *parser.File
+}
+
+// loadImports parses the files imported by file, depth first, so nested
+// imports come before the files that import them. Each file is loaded once.
+func loadImports(file *parser.File, visited map[string]bool) ([]*importedFile, error) {
+	var result []*importedFile
+	baseDir := filepath.Dir(file.Path)
+
+	for _, imp := range file.Imports {
+		path := imp
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving import %s: %w", imp, err)
+		}
+		if visited[abs] {
+			continue
+		}
+		visited[abs] = true
+
+		imported, err := parser.ParseFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("parsing import %s: %w", imp, err)
+		}
+
+		nested, err := loadImports(imported, visited)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nested...)
+		result = append(result, &importedFile{path: path, file: imported})
+	}
+
+	return result, nil
+}
+
+// namespace is how qualified references name an imported file: its base
+// name without the extension, so requests in common.http are common.<name>
+func (f *importedFile) namespace() string {
+	base := filepath.Base(f.path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// neededImports returns the imported requests that the file's requests
+// depend on, directly or through other imported requests, mapped to the path
+// of the file that defines them, along with those requests in import and
+// definition order.
+//
+// A name refers to the request in the same file when there is one.
+// Otherwise it must identify a single imported request: a name defined in
+// two imports is an error unless it is qualified as file.name
+// (e.g. @depends common.login). Imported requests that
+// are referenced qualified, or whose name clashes with another running
+// request, are renamed to their qualified name, and the @depends entries
+// pointing at them are rewritten to match, so their captures are available as
+// {{common.login.token}}.
+func neededImports(file *parser.File, imports []*importedFile) (map[*parser.Request]string, []*parser.Request, error) {
+	local := make(map[string]bool)
+	for _, req := range file.Requests {
+		if req.Name != "" {
+			local[req.Name] = true
+		}
+	}
+
+	byName := make(map[string][]*parser.Request)
+	qualified := make(map[string][]*parser.Request)
+	origin := make(map[*parser.Request]*importedFile)
+	for _, imp := range imports {
+		for _, req := range imp.file.Requests {
+			if req.Name == "" {
+				continue
+			}
+			byName[req.Name] = append(byName[req.Name], req)
+			q := imp.namespace() + "." + req.Name
+			qualified[q] = append(qualified[q], req)
+			origin[req] = imp
+		}
+	}
+
+	// resolve finds the imported request dep refers to from a request of
+	// file from (nil for the importing file). It returns nil for requests of
+	// the importing file and unknown names, which topologicalSort reports.
+	resolve := func(dep string, from *importedFile) (*parser.Request, bool, error) {
+		if from == nil && local[dep] {
+			return nil, false, nil
+		}
+		for _, req := range byName[dep] {
+			if origin[req] == from {
+				return req, false, nil
+			}
+		}
+		switch candidates := byName[dep]; len(candidates) {
+		case 0:
+		case 1:
+			return candidates[0], false, nil
+		default:
+			return nil, false, ambiguousDependency(dep, candidates, origin)
+		}
+		if local[dep] {
+			return nil, false, nil
+		}
+		switch matches := qualified[dep]; len(matches) {
+		case 0:
+			return nil, false, nil
+		case 1:
+			return matches[0], true, nil
+		default:
+			return nil, false, fmt.Errorf("dependency %q is ambiguous: several imported files are named %s", dep, strings.SplitN(dep, ".", 2)[0])
+		}
+	}
+
+	needed := make(map[*parser.Request]string)
+	rename := make(map[*parser.Request]bool)
+	targets := make(map[*parser.Request][]*parser.Request) // per @depends entry, nil for local
+	queue := append([]*parser.Request(nil), file.Requests...)
+	for len(queue) > 0 {
+		req := queue[0]
+		queue = queue[1:]
+		if req.Metadata == nil || len(req.Metadata.Depends) == 0 {
+			continue
+		}
+		from := origin[req]
+		resolved := make([]*parser.Request, len(req.Metadata.Depends))
+		for i, dep := range req.Metadata.Depends {
+			target, isQualified, err := resolve(dep, from)
+			if err != nil {
+				return nil, nil, err
+			}
+			if target == nil {
+				continue
+			}
+			resolved[i] = target
+			if isQualified {
+				rename[target] = true
+			}
+			if _, seen := needed[target]; !seen {
+				needed[target] = origin[target].path
+				queue = append(queue, target)
+			}
+		}
+		targets[req] = resolved
+	}
+
+	running := make(map[string]int)
+	for req := range needed {
+		running[req.Name]++
+	}
+	for req := range needed {
+		if local[req.Name] || running[req.Name] > 1 {
+			rename[req] = true
+		}
+	}
+	for req := range rename {
+		req.Name = origin[req].namespace() + "." + req.Name
+	}
+	for req, resolved := range targets {
+		for i, target := range resolved {
+			if target != nil {
+				req.Metadata.Depends[i] = target.Name
+			}
+		}
+	}
+
+	var ordered []*parser.Request
+	for _, imp := range imports {
+		for _, req := range imp.file.Requests {
+			if _, ok := needed[req]; ok {
+				ordered = append(ordered, req)
+			}
+		}
+	}
+
+	return needed, ordered, nil
+}
+
+// ambiguousDependency lists the imported files defining a dependency name
+// and how to qualify it
+func ambiguousDependency(dep string, candidates []*parser.Request, origin map[*parser.Request]*importedFile) error {
+	var places, qualified []string
+	for _, req := range candidates {
+		places = append(places, filepath.Base(origin[req].path))
+		qualified = append(qualified, origin[req].namespace()+"."+dep)
+	}
+	return fmt.Errorf("dependency %q is ambiguous: defined in %s; qualify it as %s",
+		dep, strings.Join(places, ", "), strings.Join(qualified, " or "))
+}
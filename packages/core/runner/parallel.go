@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// warnUndeclaredCaptures warns about requests of a parallel batch that use
+// the captures or response of another request in the batch. Requests of a
+// batch don't see each other's captures, so such a request needs @depends,
+// which runs the file sequentially.
+func warnUndeclaredCaptures(requests []*parser.Request) {
+	for _, req := range requests {
+		text := requestText(req)
+		for _, other := range requests {
+			if other == req || other.Name == "" {
+				continue
+			}
+			if usesCapturesOf(text, other) {
+				fmt.Fprintf(os.Stderr, "warning: request %q uses captures of %q, which runs in parallel with it; add @depends %s to run them in order\n",
+					RequestKey(req), other.Name, other.Name)
+			}
+		}
+	}
+}
+
+// usesCapturesOf reports whether text references a capture or the stored
+// response of req
+func usesCapturesOf(text string, req *parser.Request) bool {
+	if strings.Contains(text, "{{"+req.Name+".") {
+		return true
+	}
+	for _, c := range req.Captures {
+		if strings.Contains(text, "{{"+c.Name+"}}") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestText returns the parts of req that variables are resolved in
+func requestText(req *parser.Request) string {
+	var b strings.Builder
+	b.WriteString(req.URL)
+	for _, h := range req.Headers {
+		b.WriteString("\n" + h.Value)
+	}
+	for _, q := range req.QueryParams {
+		b.WriteString("\n" + q.Value)
+	}
+	for _, v := range req.Variables {
+		b.WriteString("\n" + v.Value)
+	}
+	if req.Body != nil {
+		b.WriteString("\n" + req.Body.Raw)
+		for _, f := range req.Body.Multipart {
+			b.WriteString("\n" + f.Value)
+		}
+		if req.Body.GraphQL != nil {
+			b.WriteString("\n" + req.Body.GraphQL.Query + "\n" + req.Body.GraphQL.Variables)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// persistedCaptures returns the captures of a @persist request that are
+// written to the --persist-captures file: the listed names, or every capture
+// when none are listed
+func persistedCaptures(req *parser.Request, captures map[string]any) map[string]any {
+	persisted := make(map[string]any)
+	if len(req.Metadata.PersistNames) == 0 {
+		for name, value := range captures {
+			persisted[name] = value
+		}
+		return persisted
+	}
+
+	for _, name := range req.Metadata.PersistNames {
+		if value, ok := captures[name]; ok {
+			persisted[name] = value
+		} else if !declaresCapture(req, name) {
+			fmt.Fprintf(os.Stderr, "warning: request %q persists %q, which it doesn't capture\n", req.Name, name)
+		}
+	}
+	return persisted
+}
+
+func declaresCapture(req *parser.Request, name string) bool {
+	for _, c := range req.Captures {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PersistedCaptures collects the @persist captures of a run; a name captured
+// more than once keeps the value of the last request
+func PersistedCaptures(results []*RunResult) map[string]any {
+	persisted := make(map[string]any)
+	for _, result := range results {
+		for _, r := range result.Results {
+			for name, value := range r.Persist {
+				persisted[name] = value
+			}
+		}
+	}
+	return persisted
+}
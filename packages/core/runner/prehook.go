@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// preHookFuncs are the functions available to @pre templates
+var preHookFuncs = template.FuncMap{
+	"sha256": func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"md5": func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"hmacSHA256": func(key, message string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(message))
+		return hex.EncodeToString(mac.Sum(nil))
+	},
+	"base64":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"urlEncode": url.QueryEscape,
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"trim":      strings.TrimSpace,
+	"timestamp": func() int64 { return time.Now().Unix() },
+}
+
+// hasSetHooks reports whether hooks include a @pre variable hook
+func hasSetHooks(hooks []*parser.Hook) bool {
+	for _, hook := range hooks {
+		if hook.Type == parser.HookSet {
+			return true
+		}
+	}
+	return false
+}
+
+// executeSetHooks evaluates @pre hooks and stores each result as a variable of
+// scope, the request's own copy of the resolver, so it doesn't leak into
+// other requests.
+// Each hook has the form "name = template" where template is a Go text/template
+// whose data is the request's current variables, including its @var
+// variables, and captures, e.g.:
+//
+//	# @pre signature = {{ hmacSHA256 .secret (index . "login.token") }}
+func (r *Runner) executeSetHooks(hooks []*parser.Hook, scope *env.Resolver) error {
+	for _, hook := range hooks {
+		if hook.Type != parser.HookSet {
+			continue
+		}
+
+		name, text, _ := strings.Cut(hook.Command, "=")
+		name = strings.TrimSpace(name)
+
+		data := scope.Snapshot()
+		funcs := template.FuncMap{
+			"var": func(key string) any { return data[key] },
+		}
+
+		tmpl, err := template.New(name).Funcs(preHookFuncs).Funcs(funcs).Option("missingkey=zero").Parse(strings.TrimSpace(text))
+		if err != nil {
+			return fmt.Errorf("@pre %s: %w", name, err)
+		}
+
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return fmt.Errorf("@pre %s: %w", name, err)
+		}
+
+		scope.SetVariable(name, out.String())
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package runner
+
+import "sync/atomic"
+
+// RetryBudget caps the retries of a whole run. It is shared by every file
+// and request of the run, including parallel ones; once it is spent, failed
+// requests are reported without retrying.
+type RetryBudget struct {
+	limit   int64
+	used    atomic.Int64
+	refused atomic.Int64
+}
+
+// NewRetryBudget returns a budget of limit retries
+func NewRetryBudget(limit int) *RetryBudget {
+	return &RetryBudget{limit: int64(limit)}
+}
+
+// Take spends one retry, reporting false when none are left. A nil budget
+// never runs out.
+func (b *RetryBudget) Take() bool {
+	if b == nil {
+		return true
+	}
+	for {
+		used := b.used.Load()
+		if used >= b.limit {
+			b.refused.Add(1)
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+1) {
+			return true
+		}
+	}
+}
+
+// Used returns the number of retries spent
+func (b *RetryBudget) Used() int {
+	return int(b.used.Load())
+}
+
+// Limit returns the number of retries the budget allows
+func (b *RetryBudget) Limit() int {
+	return int(b.limit)
+}
+
+// Refused returns the number of retries denied because the budget was spent
+func (b *RetryBudget) Refused() int {
+	return int(b.refused.Load())
+}
@@ -2,8 +2,10 @@ package runner
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,12 +22,19 @@ const (
 	DefaultConcurrency = 5
 	// DefaultRetryDelayMs is the default delay between retries in milliseconds
 	DefaultRetryDelayMs = 1000
+	// DefaultNetworkRetries is the number of retries for @retry-network
+	// requests without @retry
+	DefaultNetworkRetries = 3
 )
 
 type Runner struct {
-	client   *http.Client
-	resolver *env.Resolver
-	config   *Config
+	client    *http.Client
+	base      *env.Resolver // Resolver state shared by all files (--env-file)
+	resolver  *env.Resolver // Resolver of the file being run
+	batch     *env.Resolver // Resolver state before the running parallel batch
+	config    *Config
+	log       *slog.Logger
+	snapshots *snapshot.Manager // Snapshot manager of the file being run
 }
 
 type Config struct {
@@ -34,16 +43,37 @@ type Config struct {
 	Verbose            bool
 	Timeout            time.Duration
 	FollowRedirect     bool
+	MaxRedirects       *int // Redirects to follow before returning the redirect response (nil for the default)
 	Bail               bool
 	NameFilter         string
 	TagsFilter         []string
 	Parallel           bool
 	Concurrency        int
 	ValidateSSL        bool
+	InsecureHosts      []string // Hosts whose certificates are not verified
 	Proxy              string
+	UserAgent          *string          // User-Agent sent instead of Go's default; "" sends none
+	FixtureMode        http.FixtureMode // Record responses to FixtureDir, or replay them from it
+	FixtureDir         string
+	FixtureIgnore      []string // Request parts left out of fixture keys, e.g. body.requestId
 	DefaultHeaders     map[string]string
+	HostHeaders        map[string]map[string]string // Default headers by host glob
+	SecureHeaders      map[string]string            // Headers checked by expect secure-headers (nil for the default baseline)
 	ConfigEnvironments map[string]map[string]any
-	UpdateSnapshots    bool // Update snapshots instead of comparing
+	UpdateSnapshots    bool              // Update snapshots instead of comparing
+	Variables          map[string]string // Overrides applied after file and environment variables
+	NoEnv              bool              // Ignore environments, dotenv files and the process environment
+	RetryBudget        *RetryBudget      // Retries allowed across the whole run (nil for no limit)
+	Logger             *slog.Logger      // Logs the runner's decisions, such as retries and hooks (nil logs nothing)
+	// Stdin supplies the body of a request written with "< -" (--stdin-body).
+	// When nil, such requests fail.
+	Stdin *StdinBody
+	// RequestFilter, when set, limits a run to the requests it accepts plus
+	// the requests they depend on.
+	RequestFilter func(file, name string) bool
+	// PromptVariable is called for each missing @require variable. When nil,
+	// missing required variables fail the file.
+	PromptVariable func(name string) (string, error)
 }
 
 func NewRunner(cfg *Config) *Runner {
@@ -58,24 +88,51 @@ func NewRunner(cfg *Config) *Runner {
 		clientOpts = append(clientOpts, http.WithTimeout(cfg.Timeout))
 	}
 	clientOpts = append(clientOpts, http.WithFollowRedirects(cfg.FollowRedirect))
+	if cfg.MaxRedirects != nil {
+		clientOpts = append(clientOpts, http.WithMaxRedirects(*cfg.MaxRedirects))
+	}
 	clientOpts = append(clientOpts, http.WithValidateSSL(cfg.ValidateSSL))
+	if len(cfg.InsecureHosts) > 0 {
+		clientOpts = append(clientOpts, http.WithInsecureHosts(cfg.InsecureHosts))
+	}
 
 	if cfg.Proxy != "" {
 		clientOpts = append(clientOpts, http.WithProxy(cfg.Proxy))
 	}
 
+	if cfg.UserAgent != nil {
+		clientOpts = append(clientOpts, http.WithUserAgent(*cfg.UserAgent))
+	}
+
+	if cfg.FixtureMode != 0 {
+		clientOpts = append(clientOpts, http.WithFixtures(cfg.FixtureMode, cfg.FixtureDir))
+		clientOpts = append(clientOpts, http.WithFixtureIgnore(cfg.FixtureIgnore...))
+	}
+
 	if len(cfg.DefaultHeaders) > 0 {
 		clientOpts = append(clientOpts, http.WithDefaultHeaders(cfg.DefaultHeaders))
 	}
 
+	if len(cfg.HostHeaders) > 0 {
+		clientOpts = append(clientOpts, http.WithHostHeaders(cfg.HostHeaders))
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	clientOpts = append(clientOpts, http.WithLogger(logger))
+
 	resolver := env.NewResolver()
 	// Set up warning function to print to stderr
 	resolver.SetWarnFunc(func(format string, args ...any) {
 		fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
 	})
 
+	resolver.SetHermetic(cfg.NoEnv)
+
 	// Load dotenv file if specified
-	if cfg.EnvFile != "" {
+	if cfg.EnvFile != "" && !cfg.NoEnv {
 		if err := resolver.LoadDotEnv(cfg.EnvFile); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: failed to load env file: %v\n", err)
 		}
@@ -83,32 +140,42 @@ func NewRunner(cfg *Config) *Runner {
 
 	return &Runner{
 		client:   http.NewClient(clientOpts...),
-		resolver: resolver,
+		base:     resolver,
+		resolver: resolver.Clone(),
 		config:   cfg,
+		log:      logger,
 	}
 }
 
 type RunResult struct {
-	File     string
-	Results  []*RequestResult
-	Duration time.Duration
-	Passed   int
-	Failed   int
-	Skipped  int
+	File        string
+	Environment string // Environment the file ran against, set when a run covers several
+	Results     []*RequestResult
+	Duration    time.Duration
+	Passed      int
+	Failed      int
+	SoftFailed  int
+	Skipped     int
+	Groups      []*GroupResult // Results of each @group, in order of first appearance
 }
 
 type RequestResult struct {
 	Name         string
+	Group        string // The request's @group
 	Passed       bool
+	SoftFailed   bool // Failed assertions on a @soft request
 	Skipped      bool
 	SkipReason   string
+	StartedAt    time.Time // When the request was sent
 	Duration     time.Duration
+	AssertTime   time.Duration // Time spent evaluating assertions, not included in Duration
 	Request      *http.Request
 	Response     *http.Response
 	Assertions   []*assertions.Result
 	DBAssertions []*DBAssertionResult
 	ShellResults []*ShellResult
 	Captures     map[string]any
+	Persist      map[string]any // Captures marked with @persist
 	Error        error
 }
 
@@ -118,25 +185,127 @@ func (r *Runner) RunFile(path string) (*RunResult, error) {
 		return nil, fmt.Errorf("parsing file: %w", err)
 	}
 
-	environment, err := env.LoadEnvironment(filepath.Dir(path), r.config.Environment, r.config.ConfigEnvironments)
+	// Start every file from a fresh resolver so variables and captures of
+	// previously run files don't leak into this one
+	r.resolver = r.base.Clone()
+
+	if !r.config.NoEnv {
+		environment, err := env.LoadEnvironment(filepath.Dir(path), r.config.Environment, r.config.ConfigEnvironments)
+		if err != nil {
+			return nil, fmt.Errorf("loading environment: %w", err)
+		}
+
+		r.resolver.SetDotEnvLayers(environment.DotEnv)
+		// {{env}} is the name of the selected environment, unless the
+		// environment or the file defines its own env variable
+		if environment.Name != "" {
+			r.resolver.SetVariable("env", environment.Name)
+		}
+		r.resolver.SetVariables(environment.Variables)
+
+		r.log.Debug("environment loaded", "file", path, "env", environment.Name,
+			"variables", len(environment.Variables), "dotenv", len(environment.DotEnv))
+		if _, ok := r.config.ConfigEnvironments[environment.Name]; !ok && environment.Name != "" && len(r.config.ConfigEnvironments) > 0 {
+			r.log.Warn("environment is not defined in the config file", "env", environment.Name)
+		}
+	} else {
+		r.log.Debug("environments ignored (--no-env)", "file", path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	imports, err := loadImports(file, map[string]bool{absPath: true})
 	if err != nil {
-		return nil, fmt.Errorf("loading environment: %w", err)
+		return nil, err
 	}
 
-	r.resolver.SetVariables(environment.Variables)
+	// Variables of imported files can be overridden by the importing file
+	for _, imp := range imports {
+		for _, v := range imp.file.Variables {
+			r.resolver.SetVariable(v.Name, v.Value)
+		}
+	}
 
 	for _, v := range file.Variables {
 		r.resolver.SetVariable(v.Name, v.Value)
 	}
 
+	for k, v := range r.config.Variables {
+		r.resolver.SetVariable(k, v)
+	}
+
+	if err := r.resolveRequiredVariables(file); err != nil {
+		return nil, err
+	}
+
 	// Initialize snapshot manager for this file
-	snapshotManager := snapshot.NewManager(filepath.Dir(path), r.config.UpdateSnapshots)
-	snapshot.SetGlobalManager(snapshotManager)
+	r.snapshots = snapshot.NewManager(filepath.Dir(path), r.config.UpdateSnapshots)
 
-	return r.runRequests(file)
+	imported, importOrder, err := neededImports(file, imports)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.runRequests(file, imported, importOrder)
 }
 
-func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
+// resolveRequiredVariables ensures every @require variable is defined,
+// prompting for missing values when a PromptVariable callback is configured.
+func (r *Runner) resolveRequiredVariables(file *parser.File) error {
+	missing := MissingRequired(file, r.resolver.IsDefined)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if r.config.PromptVariable == nil {
+		return fmt.Errorf("missing required variables: %s", strings.Join(missing, ", "))
+	}
+
+	for _, name := range missing {
+		value, err := r.config.PromptVariable(name)
+		if err != nil {
+			return fmt.Errorf("reading required variable %s: %w", name, err)
+		}
+		r.resolver.SetVariable(name, value)
+	}
+	return nil
+}
+
+// MissingRequired returns the @require variables of file, in order, that
+// defined doesn't accept. Variables captured by a request in the file don't
+// need to be set upfront.
+func MissingRequired(file *parser.File, defined func(name string) bool) []string {
+	captured := make(map[string]bool)
+	for _, req := range file.Requests {
+		for _, c := range req.Captures {
+			captured[c.Name] = true
+			captured[req.Name+"."+c.Name] = true
+		}
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, req := range file.Requests {
+		if req.Metadata == nil {
+			continue
+		}
+		for _, name := range req.Metadata.Require {
+			if seen[name] || captured[name] || defined(name) {
+				continue
+			}
+			seen[name] = true
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// runRequests runs the requests of file along with the imported requests they
+// depend on. imported maps each imported request to the file defining it;
+// importOrder lists the same requests in import and definition order.
+func (r *Runner) runRequests(file *parser.File, imported map[*parser.Request]string, importOrder []*parser.Request) (*RunResult, error) {
 	start := time.Now()
 	result := &RunResult{
 		File: file.Path,
@@ -145,6 +314,21 @@ func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
 	// Get base directory for file path resolution (multipart files)
 	baseDir := filepath.Dir(file.Path)
 
+	// Imported requests resolve relative paths against their own file
+	sourceOf := func(req *parser.Request) (string, string) {
+		if path, ok := imported[req]; ok {
+			return filepath.Dir(path), path
+		}
+		return baseDir, file.Path
+	}
+
+	requests := file.Requests
+	if len(imported) > 0 {
+		requests = make([]*parser.Request, 0, len(file.Requests)+len(imported))
+		requests = append(requests, file.Requests...)
+		requests = append(requests, importOrder...)
+	}
+
 	hasOnly := false
 	for _, req := range file.Requests {
 		if req.Metadata != nil && req.Metadata.Only {
@@ -154,17 +338,22 @@ func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
 	}
 
 	// Determine execution order using topological sort
-	sortedRequests, err := r.topologicalSort(file.Requests)
+	sortedRequests, err := r.topologicalSort(requests)
 	if err != nil {
 		return nil, err
 	}
 
+	selected := r.selectRequests(file.Path, requests)
+
 	// Filter requests first
-	var filteredRequests []*parser.Request
+	var filteredRequests, teardown []*parser.Request
 	for _, req := range sortedRequests {
-		if !r.shouldRun(req, hasOnly) {
+		// Imported requests only run as dependencies, so filters don't apply
+		_, isImported := imported[req]
+		if !isImported && (!r.shouldRun(req, hasOnly) || (selected != nil && !selected[req])) {
 			result.Results = append(result.Results, &RequestResult{
 				Name:       req.Name,
+				Group:      groupOf(req),
 				Skipped:    true,
 				SkipReason: "filtered out",
 			})
@@ -175,6 +364,7 @@ func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
 		if req.Metadata != nil && req.Metadata.Skip != "" {
 			result.Results = append(result.Results, &RequestResult{
 				Name:       req.Name,
+				Group:      groupOf(req),
 				Skipped:    true,
 				SkipReason: req.Metadata.Skip,
 			})
@@ -182,34 +372,61 @@ func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
 			continue
 		}
 
+		// Teardown requests run last, even when bail stops the file early
+		if req.Metadata != nil && req.Metadata.Teardown {
+			teardown = append(teardown, req)
+			continue
+		}
+
 		filteredRequests = append(filteredRequests, req)
 	}
+	filteredRequests = groupOrder(filteredRequests)
 
-	// Check if we can run in parallel (no dependencies between remaining requests)
-	hasDependencies := false
-	for _, req := range filteredRequests {
-		if req.Metadata != nil && len(req.Metadata.Depends) > 0 {
-			hasDependencies = true
-			break
+	// Track executed requests for dependency checking
+	executed := make(map[string]*RequestResult)
+
+	// record counts a request's result and reports whether it failed
+	record := func(req *parser.Request, reqResult *RequestResult) bool {
+		result.Results = append(result.Results, reqResult)
+		if req != nil {
+			reqResult.Group = groupOf(req)
+			// A failed run of a @data-from request stands for all of them
+			if prev := executed[req.Name]; req.Name != "" && (prev == nil || prev.Passed) {
+				executed[req.Name] = reqResult
+			}
 		}
+
+		if reqResult.Skipped {
+			result.Skipped++
+		} else if reqResult.Passed {
+			result.Passed++
+		} else if reqResult.SoftFailed {
+			// Soft failures are reported but never stop the run
+			result.SoftFailed++
+		} else {
+			result.Failed++
+			return true
+		}
+		return false
 	}
 
-	// Run in parallel if configured and no dependencies
-	if r.config.Parallel && !hasDependencies {
-		results := r.runParallel(filteredRequests, baseDir, file.Path)
-		for _, reqResult := range results {
-			result.Results = append(result.Results, reqResult)
-			if reqResult.Passed {
-				result.Passed++
-			} else if !reqResult.Skipped {
-				result.Failed++
+	// Groups whose @setup failed; the rest of the group is skipped
+	failedSetups := make(map[string]bool)
+
+	// runSequential runs requests in order with dependency checking, stopping
+	// at the first failure when bail is true
+	runSequential := func(requests []*parser.Request, bail bool) {
+		for _, req := range requests {
+			if group := groupOf(req); failedSetups[group] && !isGroupSetup(req) {
+				record(nil, &RequestResult{
+					Name:       req.Name,
+					Group:      group,
+					Skipped:    true,
+					SkipReason: "group setup failed",
+				})
+				continue
 			}
-		}
-	} else {
-		// Run sequentially with dependency checking
-		executed := make(map[string]*RequestResult)
 
-		for _, req := range filteredRequests {
 			// Check dependencies - if any dependency failed, skip this request
 			if req.Metadata != nil && len(req.Metadata.Depends) > 0 {
 				dependencyFailed := false
@@ -222,35 +439,65 @@ func (r *Runner) runRequests(file *parser.File) (*RunResult, error) {
 					}
 				}
 				if dependencyFailed {
-					result.Results = append(result.Results, &RequestResult{
+					record(nil, &RequestResult{
 						Name:       req.Name,
+						Group:      groupOf(req),
 						Skipped:    true,
 						SkipReason: "dependency failed",
 					})
-					result.Skipped++
 					continue
 				}
 			}
 
-			reqResult := r.runRequest(req, baseDir, file.Path)
-			result.Results = append(result.Results, reqResult)
-
-			// Track executed request
-			if req.Name != "" {
-				executed[req.Name] = reqResult
-			}
-
-			if reqResult.Passed {
-				result.Passed++
-			} else if !reqResult.Skipped {
-				result.Failed++
-				if r.config.Bail {
+			reqBaseDir, reqPath := sourceOf(req)
+			if req.Metadata != nil && req.Metadata.DataFrom != "" {
+				failed := false
+				for _, reqResult := range r.runDataRequest(req, reqBaseDir, reqPath) {
+					if record(req, reqResult) {
+						failed = true
+					}
+				}
+				if failed && bail {
 					break
 				}
+				continue
+			}
+
+			reqResult := r.runRequest(req, reqBaseDir, reqPath)
+			failed := record(req, reqResult)
+			if isGroupSetup(req) && !reqResult.Passed {
+				failedSetups[groupOf(req)] = true
 			}
+			if failed && bail {
+				break
+			}
+		}
+	}
+
+	// Check if we can run in parallel (no dependencies between remaining
+	// requests, and no group setup that has to run first)
+	hasDependencies := false
+	for _, req := range filteredRequests {
+		if req.Metadata != nil && (len(req.Metadata.Depends) > 0 || req.Metadata.GroupSetup) {
+			hasDependencies = true
+			break
 		}
 	}
 
+	// Run in parallel if configured and no dependencies
+	if r.config.Parallel && !hasDependencies && len(imported) == 0 {
+		results := r.runParallel(filteredRequests, baseDir, file.Path)
+		for i, reqResult := range results {
+			record(filteredRequests[i], reqResult)
+		}
+	} else {
+		runSequential(filteredRequests, r.config.Bail)
+	}
+
+	// Teardown is owed whether or not the requests above passed
+	runSequential(teardown, false)
+
+	result.Groups = groupResults(result.Results)
 	result.Duration = time.Since(start)
 	return result, nil
 }
@@ -261,6 +508,9 @@ func (r *Runner) runParallel(requests []*parser.Request, baseDir string, filePat
 		concurrency = DefaultConcurrency
 	}
 
+	warnUndeclaredCaptures(requests)
+	r.batch = r.resolver.Clone()
+
 	results := make([]*RequestResult, len(requests))
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, concurrency)
@@ -283,45 +533,27 @@ func (r *Runner) runParallel(requests []*parser.Request, baseDir string, filePat
 
 // topologicalSort returns requests in dependency-respecting order
 func (r *Runner) topologicalSort(requests []*parser.Request) ([]*parser.Request, error) {
-	// Build adjacency list and in-degree count
-	inDegree := make(map[string]int)
-	adjacency := make(map[string][]string)
-	requestMap := make(map[string]*parser.Request)
+	graph := NewDependencyGraph(requests)
+	inDegree := graph.InDegree
+	adjacency := graph.Dependents
+	requestMap := graph.Requests
 
-	// Initialize all requests
 	for _, req := range requests {
-		name := req.Name
-		if name == "" {
-			name = fmt.Sprintf("__anon_%p", req)
+		name := RequestKey(req)
+		for _, dep := range graph.Missing[name] {
+			fmt.Fprintf(os.Stderr, "warning: request %q depends on %q which does not exist\n", name, dep)
 		}
-		inDegree[name] = 0
-		requestMap[name] = req
 	}
 
-	// Build graph from dependencies
-	for _, req := range requests {
-		name := req.Name
-		if name == "" {
-			name = fmt.Sprintf("__anon_%p", req)
-		}
-
-		if req.Metadata != nil && len(req.Metadata.Depends) > 0 {
-			for _, dep := range req.Metadata.Depends {
-				if _, exists := requestMap[dep]; exists {
-					adjacency[dep] = append(adjacency[dep], name)
-					inDegree[name]++
-				} else {
-					fmt.Fprintf(os.Stderr, "warning: request %q depends on %q which does not exist\n", name, dep)
-				}
-			}
-		}
-	}
-
-	// Kahn's algorithm for topological sort
+	// Kahn's algorithm for topological sort, seeded in file order so
+	// independent requests run in the order they are written
 	var queue []string
-	for name, degree := range inDegree {
-		if degree == 0 {
+	queued := make(map[string]bool)
+	for _, req := range requests {
+		name := RequestKey(req)
+		if inDegree[name] == 0 && !queued[name] {
 			queue = append(queue, name)
+			queued[name] = true
 		}
 	}
 
@@ -387,21 +619,65 @@ func (r *Runner) shouldRun(req *parser.Request, hasOnly bool) bool {
 	return true
 }
 
+// selectRequests returns the requests accepted by the configured
+// RequestFilter together with their transitive dependencies, or nil when no
+// filter is set.
+func (r *Runner) selectRequests(filePath string, requests []*parser.Request) map[*parser.Request]bool {
+	if r.config.RequestFilter == nil {
+		return nil
+	}
+
+	byName := make(map[string]*parser.Request)
+	for _, req := range requests {
+		if req.Name != "" {
+			byName[req.Name] = req
+		}
+	}
+
+	selected := make(map[*parser.Request]bool)
+	var queue []*parser.Request
+	for _, req := range requests {
+		if r.config.RequestFilter(filePath, req.Name) {
+			queue = append(queue, req)
+		}
+	}
+	for len(queue) > 0 {
+		req := queue[0]
+		queue = queue[1:]
+		if selected[req] {
+			continue
+		}
+		selected[req] = true
+		if req.Metadata != nil {
+			for _, dep := range req.Metadata.Depends {
+				if depReq, ok := byName[dep]; ok {
+					queue = append(queue, depReq)
+				}
+			}
+		}
+	}
+	return selected
+}
+
 func (r *Runner) runRequest(req *parser.Request, baseDir string, filePath string) *RequestResult {
-	return r.runRequestWithRetry(req, baseDir, filePath, false)
+	return r.runRequestWithRetry(req, baseDir, filePath, false, nil)
 }
 
-// runRequestParallel runs a request in parallel mode (no captures set in resolver)
+// runRequestParallel runs a request in parallel mode, resolving variables as
+// they were before the batch started so requests don't see each other's captures
 func (r *Runner) runRequestParallel(req *parser.Request, baseDir string, filePath string) *RequestResult {
-	return r.runRequestWithRetry(req, baseDir, filePath, true)
+	return r.runRequestWithRetry(req, baseDir, filePath, true, nil)
 }
 
-// runRequestWithRetry executes a request with retry logic
-func (r *Runner) runRequestWithRetry(req *parser.Request, baseDir string, filePath string, parallel bool) *RequestResult {
+// runRequestWithRetry executes a request with retry logic. data holds
+// variables scoped to this run, such as the {{item}} of a @data-from request.
+func (r *Runner) runRequestWithRetry(req *parser.Request, baseDir string, filePath string, parallel bool, data map[string]any) *RequestResult {
 	// Determine retry settings
 	maxRetries := 0
 	retryDelay := DefaultRetryDelayMs
 	var retryOnStatuses []int
+	retryNetwork := false
+	retryResponses := true
 
 	if req.Metadata != nil {
 		if req.Metadata.Retry > 0 {
@@ -413,33 +689,39 @@ func (r *Runner) runRequestWithRetry(req *parser.Request, baseDir string, filePa
 		if len(req.Metadata.RetryOn) > 0 {
 			retryOnStatuses = req.Metadata.RetryOn
 		}
+		retryNetwork = req.Metadata.RetryNetwork
+		if retryNetwork && maxRetries == 0 {
+			// A bare @retry-network only retries network errors
+			maxRetries = DefaultNetworkRetries
+			retryResponses = len(retryOnStatuses) > 0
+		}
 	}
 
 	var result *RequestResult
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		result = r.executeRequest(req, baseDir, filePath, parallel)
+		result = r.executeRequest(req, baseDir, filePath, parallel, data)
 
 		// If passed, no need to retry
 		if result.Passed {
 			return result
 		}
 
-		// Check if we should retry based on status code
-		if len(retryOnStatuses) > 0 && result.Response != nil {
-			shouldRetry := false
-			for _, status := range retryOnStatuses {
-				if result.Response.StatusCode == status {
-					shouldRetry = true
-					break
-				}
-			}
-			if !shouldRetry {
-				return result
+		if !shouldRetry(result, retryOnStatuses, retryNetwork, retryResponses) {
+			if maxRetries > 0 {
+				r.log.Debug("not retrying", "request", req.Name, "reason", retryReason(result))
 			}
+			return result
 		}
 
-		// If we have more retries left, wait and try again
+		// If we have more retries left, wait and try again, unless the run's
+		// retry budget is spent
 		if attempt < maxRetries {
+			if !r.config.RetryBudget.Take() {
+				r.log.Warn("retry budget spent, not retrying", "request", req.Name)
+				return result
+			}
+			r.log.Info("retrying request", "request", req.Name, "retry", attempt+1, "of", maxRetries,
+				"delay", time.Duration(retryDelay)*time.Millisecond, "reason", retryReason(result))
 			time.Sleep(time.Duration(retryDelay) * time.Millisecond)
 		}
 	}
@@ -447,15 +729,68 @@ func (r *Runner) runRequestWithRetry(req *parser.Request, baseDir string, filePa
 	return result
 }
 
-func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath string, parallel bool) *RequestResult {
+// retryReason describes why an attempt failed, for the log
+func retryReason(result *RequestResult) string {
+	switch {
+	case result.Error != nil:
+		return result.Error.Error()
+	case result.Response != nil:
+		return fmt.Sprintf("status %d", result.Response.StatusCode)
+	default:
+		return "failed"
+	}
+}
+
+// shouldRetry decides whether a failed attempt is retried. Failures without a
+// response are always retried, or only network errors with @retry-network.
+// Unless retryResponses is false, responses are retried when their status is
+// listed in @retryOn, or on any failure without @retryOn.
+func shouldRetry(result *RequestResult, retryOnStatuses []int, retryNetwork, retryResponses bool) bool {
+	if result.Response == nil {
+		// Without a response @retryOn doesn't apply, so any failure is
+		// retried unless @retry-network narrows it to network errors
+		if retryNetwork {
+			return http.IsNetworkError(result.Error)
+		}
+		return true
+	}
+	if !retryResponses {
+		return false
+	}
+	if len(retryOnStatuses) == 0 {
+		return true
+	}
+	for _, status := range retryOnStatuses {
+		if result.Response.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath string, parallel bool, data map[string]any) *RequestResult {
 	result := &RequestResult{
 		Name:     req.Name,
 		Captures: make(map[string]any),
 	}
 
+	// Run data, @var and @pre variables resolve in a copy of the resolver, so
+	// they don't leak into later requests
+	scope := r.resolver
+	if parallel {
+		scope = r.batch
+	}
+	if len(data) > 0 || len(req.Variables) > 0 || (req.Metadata != nil && hasSetHooks(req.Metadata.PreHooks)) {
+		scope = scope.Clone()
+		scope.SetVariables(data)
+		for _, v := range req.Variables {
+			scope.SetVariable(v.Name, scope.Resolve(v.Value))
+		}
+	}
+
 	// Wait for service readiness if configured
 	if req.Metadata != nil && req.Metadata.WaitFor != nil {
-		if err := r.waitForService(req.Metadata.WaitFor, r.resolver.Resolve); err != nil {
+		if err := r.waitForService(req.Metadata.WaitFor, scope.Resolve); err != nil {
 			result.Error = err
 			result.Passed = false
 			return result
@@ -464,7 +799,7 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 
 	// Execute pre-hooks
 	if req.Metadata != nil && len(req.Metadata.PreHooks) > 0 {
-		if err := r.executePreHooks(req.Metadata.PreHooks, baseDir, r.resolver.Resolve); err != nil {
+		if err := r.executePreHooks(req.Metadata.PreHooks, baseDir, scope.Resolve); err != nil {
 			result.Error = err
 			result.Passed = false
 			return result
@@ -474,7 +809,7 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 	// Defer post-hooks execution (always runs, even on failure)
 	if req.Metadata != nil && len(req.Metadata.PostHooks) > 0 {
 		defer func() {
-			if err := r.executePostHooks(req.Metadata.PostHooks, baseDir, r.resolver.Resolve); err != nil {
+			if err := r.executePostHooks(req.Metadata.PostHooks, baseDir, scope.Resolve); err != nil {
 				// Only set error if no previous error
 				if result.Error == nil {
 					result.Error = err
@@ -483,11 +818,45 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 		}()
 	}
 
+	// Compute @pre variables with the captures of already executed requests in scope
+	if req.Metadata != nil && len(req.Metadata.PreHooks) > 0 {
+		if err := r.executeSetHooks(req.Metadata.PreHooks, scope); err != nil {
+			result.Error = err
+			result.Passed = false
+			return result
+		}
+	}
+
 	start := time.Now()
+	result.StartedAt = start
 
-	httpReq := http.BuildRequestFromASTWithBaseDir(req, r.resolver.Resolve, baseDir)
+	httpReq := http.BuildRequestFromASTWithBaseDir(req, scope.Resolve, baseDir)
 	result.Request = httpReq
 
+	if req.Body != nil && req.Body.ContentType == parser.BodyStdin {
+		body, err := r.stdinBody(req, filePath)
+		if err != nil {
+			result.Error = err
+			result.Passed = false
+			return result
+		}
+		httpReq.SetBody(body)
+	}
+
+	if err := httpReq.BuildErr(); err != nil {
+		result.Error = err
+		result.Passed = false
+		return result
+	}
+
+	if req.Metadata != nil && req.Metadata.BodySchema != "" {
+		if err := validateBody(httpReq, scope.Resolve(req.Metadata.BodySchema), baseDir); err != nil {
+			result.Error = err
+			result.Passed = false
+			return result
+		}
+	}
+
 	resp, err := r.client.Do(httpReq)
 	result.Duration = time.Since(start)
 
@@ -497,11 +866,18 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 		return result
 	}
 	result.Response = resp
+	if req.Metadata != nil && req.Metadata.NDJSON {
+		resp.NDJSON = true
+	}
 
 	if len(req.Assertions) > 0 {
-		result.Assertions = assertions.EvaluateAllWithBaseDir(resp, req.Assertions, baseDir,
+		assertStart := time.Now()
+		result.Assertions = assertions.EvaluateAllWithBaseDir(resp, assertions.Resolve(req.Assertions, scope.Resolve), baseDir,
 			assertions.WithTestFile(filePath),
-			assertions.WithRequestName(req.Name))
+			assertions.WithRequestName(req.Name),
+			assertions.WithSnapshotManager(r.snapshots),
+			assertions.WithSecureHeaders(r.config.SecureHeaders))
+		result.AssertTime = time.Since(assertStart)
 		result.Passed = true
 		for _, a := range result.Assertions {
 			if !a.Passed {
@@ -510,12 +886,12 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 			}
 		}
 	} else {
-		result.Passed = resp.IsSuccess()
+		result.Passed = req.Metadata.AcceptsStatus(resp.StatusCode)
 	}
 
 	// Execute database assertions if configured
 	if len(req.DBAssertions) > 0 && req.Metadata != nil && req.Metadata.DBConnection != "" {
-		dbResults, err := r.executeDBAssertions(req.DBAssertions, req.Metadata.DBConnection, r.resolver.Resolve)
+		dbResults, err := r.executeDBAssertions(req.DBAssertions, req.Metadata.DBConnection, scope.Resolve)
 		if err != nil {
 			result.Error = err
 			result.Passed = false
@@ -532,7 +908,7 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 
 	// Execute shell commands if configured
 	if len(req.ShellCommands) > 0 {
-		shellResults, err := r.executeShellCommands(req.ShellCommands, baseDir, r.resolver.Resolve)
+		shellResults, err := r.executeShellCommands(req.ShellCommands, baseDir, scope.Resolve)
 		if err != nil {
 			result.Error = err
 			result.Passed = false
@@ -540,18 +916,26 @@ func (r *Runner) executeRequest(req *parser.Request, baseDir string, filePath st
 		result.ShellResults = shellResults
 	}
 
+	if !result.Passed && result.Error == nil && req.Metadata != nil && req.Metadata.Soft {
+		result.SoftFailed = true
+	}
+
 	if len(req.Captures) > 0 {
 		captures := capture.ExtractAll(resp, req.Captures)
 		for name, value := range captures {
 			result.Captures[name] = value
-			// Skip setting captures in resolver when running in parallel mode
-			// to avoid race conditions (parallel mode doesn't support dependencies anyway)
-			if !parallel {
-				r.resolver.SetCapture(req.Name, name, value)
-			}
+			r.resolver.SetCapture(req.Name, name, value)
 		}
 	}
 
+	if req.Metadata != nil && req.Metadata.Persist {
+		result.Persist = persistedCaptures(req, result.Captures)
+	}
+
+	if req.Name != "" {
+		r.resolver.SetResponse(req.Name, capture.Response(resp))
+	}
+
 	return result
 }
 
@@ -1,12 +1,21 @@
 package runner
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -182,6 +191,49 @@ GET ` + server.URL + `/a`
 	assert.Equal(t, []string{"/a", "/b"}, executionOrder)
 }
 
+func TestRunner_IndependentRequestsRunInFileOrder(t *testing.T) {
+	executionOrder := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		executionOrder = append(executionOrder, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Zulu
+# @name zulu
+GET ` + server.URL + `/zulu
+
+### Last
+# @name last
+# @depends alpha
+GET ` + server.URL + `/last
+
+### Alpha
+# @name alpha
+GET ` + server.URL + `/alpha
+
+### Mike
+# @name mike
+GET ` + server.URL + `/mike
+
+### Anonymous
+GET ` + server.URL + `/anonymous`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	for range 5 {
+		executionOrder = executionOrder[:0]
+		result, err := NewRunner(&Config{}).RunFile(testFile)
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, result.Passed)
+		assert.Equal(t, []string{"/zulu", "/alpha", "/mike", "/anonymous", "/last"}, executionOrder)
+	}
+}
+
 func TestRunner_NameFilter(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -653,3 +705,1460 @@ expect status 200
 		assert.Equal(t, "1\n2\n", string(data))
 	})
 }
+
+func TestRunner_HeaderValueCaptures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.Header().Add("Set-Cookie", "csrf=xyz")
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Login
+# @name login
+POST ` + server.URL + `/login
+
+>>>capture
+requestId from header X-Request-Id
+cookies from headers[] Set-Cookie
+csrf from headers[1] Set-Cookie
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	captures := result.Results[0].Captures
+	assert.Equal(t, "req-1", captures["requestId"])
+	assert.Equal(t, []any{"session=abc", "csrf=xyz"}, captures["cookies"])
+	assert.Equal(t, "csrf=xyz", captures["csrf"])
+}
+
+func TestRunner_CaptureTypes(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":12345678,"active":"true"}`))
+			return
+		}
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Create
+# @name create
+POST ` + server.URL + `/users
+
+>>>capture
+id from body.id as int
+active from body.active as bool
+status from status as string
+<<<
+
+### Get
+# @depends create
+GET ` + server.URL + `/users/{{create.id}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	captures := result.Results[0].Captures
+	assert.Equal(t, 12345678, captures["id"])
+	assert.Equal(t, true, captures["active"])
+	assert.Equal(t, "201", captures["status"])
+	assert.Equal(t, "/users/12345678", gotPath)
+}
+
+func TestRunner_CaptureTransforms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[1,2,3],"name":"hitspec","page":"4","price":2.5}`))
+	}))
+	defer server.Close()
+
+	content := `### List
+GET ` + server.URL + `/items
+
+>>>capture
+total from body.items length
+chars from body.name count
+next from body.page + 1
+cents from body.price * 100
+half from body.items length / 2
+label from body.items length as string
+broken from body.price / 0
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	captures := result.Results[0].Captures
+	assert.Equal(t, 3, captures["total"])
+	assert.Equal(t, 7, captures["chars"])
+	assert.Equal(t, 5, captures["next"])
+	assert.Equal(t, 250, captures["cents"])
+	assert.Equal(t, 1.5, captures["half"])
+	assert.Equal(t, "3", captures["label"])
+	assert.NotContains(t, captures, "broken")
+}
+
+func TestRunner_RequestVariables(t *testing.T) {
+	var paths, signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer server.Close()
+
+	content := `@path = /shared
+
+### Create
+# @name create
+POST ` + server.URL + `/orders
+
+>>>capture
+id from body.id
+<<<
+
+### Signed
+# @var path = /orders/{{create.id}}
+# @var payload = GET {{path}}
+# @pre signature = {{ sha256 .payload }}
+GET ` + server.URL + `{{path}}
+X-Signature: {{signature}}
+
+### After
+GET ` + server.URL + `{{path}}
+X-Signature: {{payload}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	_, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	require.Len(t, paths, 3)
+	assert.Equal(t, "/orders/7", paths[1])
+	sum := sha256.Sum256([]byte("GET /orders/7"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), signatures[1])
+	// The @var variables are gone after their request
+	assert.Equal(t, "/shared", paths[2])
+	assert.Equal(t, "{{payload}}", signatures[2])
+}
+
+func TestRunner_DataFrom(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users":
+			w.Write([]byte(`{"users":[{"id":1,"role":{"name":"admin"}},{"id":2,"role":{"name":"viewer"}}],"none":[]}`))
+		default:
+			paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+			if r.URL.Path == "/users/2" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	content := `### List users
+# @name listUsers
+GET ` + server.URL + `/users
+
+### Check user
+# @name checkUser
+# @data-from listUsers.body.users
+GET ` + server.URL + `/users/{{item.id}}?role={{item.role.name}}&n={{index}}
+
+>>>
+expect status 200
+<<<
+
+### Nothing
+# @name nothing
+# @data-from listUsers.body.none
+GET ` + server.URL + `/never
+
+### After check
+# @name afterCheck
+# @depends checkUser
+GET ` + server.URL + `/after`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/users/1?role=admin&n=0", "/users/2?role=viewer&n=1"}, paths)
+	require.Len(t, result.Results, 5)
+	assert.Equal(t, "checkUser[0]", result.Results[1].Name)
+	assert.True(t, result.Results[1].Passed)
+	assert.Equal(t, "checkUser[1]", result.Results[2].Name)
+	assert.False(t, result.Results[2].Passed)
+	assert.True(t, result.Results[3].Skipped)
+	assert.Equal(t, "no data from listUsers.body.none", result.Results[3].SkipReason)
+	// Dependents see the failed run
+	assert.True(t, result.Results[4].Skipped)
+	assert.Equal(t, "dependency failed", result.Results[4].SkipReason)
+}
+
+func TestRunner_RetryBudget(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	content := `### First
+# @retry 3
+# @retryDelay 1
+GET ` + server.URL + `/first
+
+### Second
+# @retry 3
+# @retryDelay 1
+GET ` + server.URL + `/second`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	budget := NewRetryBudget(2)
+	r := NewRunner(&Config{RetryBudget: budget})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Failed)
+	// 2 first attempts plus the 2 retries of the budget
+	assert.Equal(t, int32(4), attempts.Load())
+	assert.Equal(t, 2, budget.Used())
+	assert.Equal(t, 2, budget.Refused())
+}
+
+func TestRunner_VariableExpectedValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"env":"staging","version":3,"region":"staging-eu","id":"abc"}`))
+	}))
+	defer server.Close()
+
+	content := `@version = 3
+
+### Create
+# @name create
+GET ` + server.URL + `/create
+
+>>>capture
+id from body.id
+<<<
+
+### Info
+GET ` + server.URL + `/info
+
+>>>
+expect body.env == {{env}}
+expect body.version == {{version}}
+expect body.region == {{env}}-eu
+expect body.id in [{{create.id}}, "none"]
+<<<
+
+### Wrong
+GET ` + server.URL + `/info
+
+>>>
+expect body.version > {{version}}
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{Environment: "staging"})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	require.Len(t, result.Results, 3)
+	for _, a := range result.Results[1].Assertions {
+		assert.True(t, a.Passed, "%s: %s", a.Subject, a.Message)
+	}
+	assert.False(t, result.Results[2].Passed)
+	assert.Equal(t, "3", result.Results[2].Assertions[0].Expected)
+}
+
+func TestRunner_ParallelCaptures(t *testing.T) {
+	var cleaned atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users":
+			_, _ = w.Write([]byte(`{"id": 7}`))
+		case "/orders":
+			_, _ = w.Write([]byte(`{"id": 9}`))
+		default:
+			cleaned.Store(r.URL.Path)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	content := `### Create user
+# @name user
+POST ` + server.URL + `/users
+
+>>>capture
+userId from body.id
+<<<
+
+### Create order
+# @name order
+POST ` + server.URL + `/orders
+
+>>>capture
+orderId from body.id
+<<<
+
+### Clean up
+# @teardown
+DELETE ` + server.URL + `/cleanup/{{user.userId}}/{{order.orderId}}
+`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{Parallel: true})
+	result, err := r.RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Passed)
+	assert.Equal(t, "/cleanup/7/9", cleaned.Load(), "captures of parallel requests reach later requests")
+
+	value, ok := r.resolver.GetCapture("order.orderId")
+	require.True(t, ok)
+	assert.EqualValues(t, 9, value)
+}
+
+func TestUsesCapturesOf(t *testing.T) {
+	login := &parser.Request{Name: "login", Captures: []*parser.Capture{{Name: "token"}}}
+
+	assert.True(t, usesCapturesOf(requestText(&parser.Request{URL: "{{login.token}}"}), login))
+	assert.True(t, usesCapturesOf(requestText(&parser.Request{Headers: []*parser.Header{{Value: "Bearer {{token}}"}}}), login))
+	assert.True(t, usesCapturesOf(requestText(&parser.Request{Body: &parser.Body{Raw: `{"s": {{login.$response.status}}}`}}), login))
+	assert.False(t, usesCapturesOf(requestText(&parser.Request{URL: "{{baseUrl}}/login"}), login))
+}
+
+func TestRunner_Logger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	content := `### Flaky
+# @retry 1
+# @retryDelay 1
+GET ` + server.URL + `/old
+
+>>>
+expect status 200
+<<<
+`
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := NewRunner(&Config{Environment: "dev", FollowRedirect: true, Logger: logger})
+	_, err := r.RunFile(testFile)
+	require.NoError(t, err)
+
+	assert.Contains(t, logs.String(), `msg="environment loaded"`)
+	assert.Contains(t, logs.String(), `msg="following redirect"`)
+	assert.Contains(t, logs.String(), `msg="retrying request" request=Flaky retry=1 of=1 delay=1ms reason="status 503"`)
+}
+
+func TestRunner_MaxRedirectsZero(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/end", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Start
+GET ` + server.URL + `/start
+
+>>>
+expect status 302
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	zero := 0
+	r := NewRunner(&Config{FollowRedirect: true, MaxRedirects: &zero})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+}
+
+func TestRunner_CookieCaptures(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Add("Set-Cookie", "session=abc123; Path=/; HttpOnly")
+			w.Header().Add("Set-Cookie", "theme=dark")
+		} else {
+			gotAuth = r.Header.Get("X-Session")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Login
+# @name login
+POST ` + server.URL + `/login
+
+>>>capture
+session from cookie session
+missing from cookie nope
+<<<
+
+### Profile
+# @depends login
+GET ` + server.URL + `/me
+X-Session: {{login.session}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	captures := result.Results[0].Captures
+	assert.Equal(t, "abc123", captures["session"])
+	assert.NotContains(t, captures, "missing")
+	assert.Equal(t, "abc123", gotAuth)
+}
+
+func TestRunner_IsolatesFiles(t *testing.T) {
+	var gotToken, gotTenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Set("X-Token", "from-a")
+		} else {
+			gotToken = r.Header.Get("X-Token")
+			gotTenant = r.Header.Get("X-Tenant")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	fileA := filepath.Join(tmpDir, "a.http")
+	require.NoError(t, os.WriteFile(fileA, []byte(`@tenant = acme
+
+### Login
+# @name login
+POST `+server.URL+`/login
+
+>>>capture
+token from header X-Token
+<<<`), 0644))
+	fileB := filepath.Join(tmpDir, "b.http")
+	require.NoError(t, os.WriteFile(fileB, []byte(`### Profile
+GET `+server.URL+`/me
+X-Token: {{login.token}}
+X-Tenant: {{tenant}}`), 0644))
+
+	r := NewRunner(&Config{})
+	_, err := r.RunFile(fileA)
+	require.NoError(t, err)
+	_, err = r.RunFile(fileB)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, "from-a", gotToken)
+	assert.NotEqual(t, "acme", gotTenant)
+}
+
+func TestRunner_SoftFailure(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Soft
+# @soft
+GET ` + server.URL + `/soft
+
+>>>
+expect status 201
+expect status 202
+<<<
+
+### Next
+GET ` + server.URL + `/next
+
+>>>
+expect status 200
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	r := NewRunner(&Config{Bail: true})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, requestCount, "soft failure should not trigger bail")
+	assert.Equal(t, 0, result.Failed)
+	assert.Equal(t, 1, result.SoftFailed)
+	assert.Equal(t, 1, result.Passed)
+	for _, res := range result.Results {
+		if res.Name == "Soft" {
+			assert.True(t, res.SoftFailed)
+			assert.Len(t, res.Assertions, 2)
+		}
+	}
+}
+
+func TestRunner_PreHookTemplate(t *testing.T) {
+	var gotSignature, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"abc"}`))
+			return
+		}
+		gotSignature = r.Header.Get("X-Signature")
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `@secret = s3cr3t
+
+### Login
+# @name login
+GET ` + server.URL + `/login
+
+>>>capture
+token from body.token
+<<<
+
+### Signed
+# @name signed
+# @depends login
+# @pre signature = {{ hmacSHA256 .secret (index . "login.token") }}
+# @pre auth = Bearer {{ upper .token }}
+GET ` + server.URL + `/signed
+X-Signature: {{signature}}
+Authorization: {{auth}}
+
+>>>
+expect status 200
+<<<`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, "e7b80919c51385b9e86c3363c73f85cd015222e4d4eb945082d61d7b21eb8241", gotSignature)
+	assert.Equal(t, "Bearer ABC", gotAuth)
+}
+
+func TestRunner_PreHookVariableIsRequestScoped(t *testing.T) {
+	var gotSignatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignatures = append(gotSignatures, r.Header.Get("X-Signature"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Signed
+# @name signed
+# @pre signature = {{ sha256 "payload" }}
+GET ` + server.URL + `/signed
+X-Signature: {{signature}}
+
+### Unsigned
+# @name unsigned
+# @depends signed
+GET ` + server.URL + `/unsigned
+X-Signature: {{signature}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, []string{
+		"239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5",
+		"{{signature}}",
+	}, gotSignatures, "the @pre variable stays unresolved in the next request")
+}
+
+func TestRunner_PreHookTemplateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Broken
+# @pre value = {{ unknownFunc }}
+GET ` + server.URL + `/broken`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Error(t, result.Results[0].Error)
+	assert.Contains(t, result.Results[0].Error.Error(), "@pre value")
+}
+
+func TestRunner_VariableOverrides(t *testing.T) {
+	var gotPath, gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotToken = r.Header.Get("X-Token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `@path = /default
+@token = file-token
+
+### Override
+GET ` + server.URL + `{{path}}
+X-Token: {{token}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	r := NewRunner(&Config{Variables: map[string]string{"path": "/override"}})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, "/override", gotPath)
+	assert.Equal(t, "file-token", gotToken)
+}
+
+func TestRunner_RequiredVariables(t *testing.T) {
+	var gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			gotToken = r.Header.Get("X-Token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	content := `### Create
+# @name create
+# @require token, apiKey
+POST ` + server.URL + `/items
+X-Token: {{token}}
+
+>>>capture
+id from body.id
+<<<
+
+### Get
+# @require id, apiKey
+GET ` + server.URL + `/items/{{id}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	err := os.WriteFile(testFile, []byte(content), 0644)
+	require.NoError(t, err)
+
+	t.Run("missing without prompt", func(t *testing.T) {
+		r := NewRunner(&Config{})
+		_, err := r.RunFile(testFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing required variables: token, apiKey")
+	})
+
+	t.Run("provided by override", func(t *testing.T) {
+		r := NewRunner(&Config{Variables: map[string]string{"token": "t", "apiKey": "k"}})
+		result, err := r.RunFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Passed)
+	})
+
+	t.Run("prompted", func(t *testing.T) {
+		var prompted []string
+		r := NewRunner(&Config{
+			Variables: map[string]string{"apiKey": "k"},
+			PromptVariable: func(name string) (string, error) {
+				prompted = append(prompted, name)
+				return "entered", nil
+			},
+		})
+		result, err := r.RunFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"token"}, prompted)
+		assert.Equal(t, 2, result.Passed)
+		assert.Equal(t, "entered", gotToken)
+	})
+}
+
+func TestRunner_DependsOnImportedRequest(t *testing.T) {
+	var gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"abc123"}`))
+		case "/me":
+			gotToken = r.Header.Get("Authorization")
+			w.Write([]byte(`{"id":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	common := `@baseUrl = ` + server.URL + `
+
+### Login
+# @name login
+POST {{baseUrl}}/login
+
+>>>capture
+token from body.token
+<<<
+
+### Unused
+# @name unused
+GET {{baseUrl}}/unused`
+
+	content := `# @import common.http
+
+### Profile
+# @name profile
+# @depends login
+GET {{baseUrl}}/me
+Authorization: Bearer {{login.token}}
+
+>>>
+expect status 200
+<<<`
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "common.http"), []byte(common), 0644))
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, "login", result.Results[0].Name)
+	assert.Equal(t, "profile", result.Results[1].Name)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, "Bearer abc123", gotToken)
+}
+
+func TestRunner_ImportedDependencyOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot"}
+	var common strings.Builder
+	for _, name := range names {
+		common.WriteString("### " + name + "\n# @name " + name + "\nGET " + server.URL + "/" + name + "\n\n")
+	}
+	content := "# @import common.http\n\n### Main\n# @name main\n# @depends foxtrot, echo, delta, charlie, bravo, alpha\nGET " + server.URL + "/main"
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "common.http"), []byte(common.String()), 0644))
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	for range 5 {
+		result, err := NewRunner(&Config{}).RunFile(testFile)
+		require.NoError(t, err)
+
+		var order []string
+		for _, res := range result.Results {
+			order = append(order, res.Name)
+		}
+		assert.Equal(t, append(append([]string(nil), names...), "main"), order)
+	}
+}
+
+func TestRunner_QualifiedImportReference(t *testing.T) {
+	var gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/admin/login":
+			w.Write([]byte(`{"token":"admin-token"}`))
+		case "/login":
+			w.Write([]byte(`{"token":"user-token"}`))
+		case "/me":
+			gotToken = r.Header.Get("Authorization")
+			w.Write([]byte(`{"id":1}`))
+		}
+	}))
+	defer server.Close()
+
+	admin := `### Login
+# @name login
+POST ` + server.URL + `/admin/login
+
+>>>capture
+token from body.token
+<<<`
+
+	content := `# @import admin.http
+
+### Login
+# @name login
+POST ` + server.URL + `/login
+
+>>>capture
+token from body.token
+<<<
+
+### Profile
+# @name profile
+# @depends login, admin.login
+GET ` + server.URL + `/me
+Authorization: Bearer {{admin.login.token}}
+
+>>>
+expect status 200
+<<<`
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "admin.http"), []byte(admin), 0644))
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, 3, result.Passed)
+	assert.Equal(t, "profile", result.Results[2].Name)
+	assert.Equal(t, "Bearer admin-token", gotToken)
+}
+
+func TestRunner_AmbiguousImportReference(t *testing.T) {
+	tmpDir := t.TempDir()
+	login := "### Login\n# @name login\nGET http://127.0.0.1:1/login"
+	content := "# @import admin.http\n# @import users.http\n\n### Me\n# @depends login\nGET http://127.0.0.1:1/me"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "admin.http"), []byte(login), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "users.http"), []byte(login), 0644))
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	_, err := NewRunner(&Config{}).RunFile(testFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `dependency "login" is ambiguous`)
+	assert.Contains(t, err.Error(), "admin.login or users.login")
+}
+
+func TestRunner_ExtensionMethod(t *testing.T) {
+	var gotMethod string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		w.WriteHeader(207)
+	}))
+	defer server.Close()
+
+	content := "### List\nPROPFIND " + server.URL + "/dav/\nDepth: 1\n\n>>>\nexpect status 207\n<<<"
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, "PROPFIND", gotMethod)
+}
+
+func TestRunner_RetryNetworkErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			// Drop the connection without a response, like a flaky load balancer
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	run := func(annotations string) *RunResult {
+		attempts.Store(0)
+		content := "### Flaky\n# @name flaky\n" + annotations + "# @retryDelay 1\nGET " + server.URL + "/flaky"
+		testFile := filepath.Join(t.TempDir(), "test.http")
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+		result, err := NewRunner(&Config{}).RunFile(testFile)
+		require.NoError(t, err)
+		return result
+	}
+
+	result := run("# @retry-network\n")
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	result = run("# @retry 3\n# @retryOn 503, network\n")
+	assert.Equal(t, 1, result.Passed)
+
+	// @retryOn only filters responses; failures without one are retried
+	result = run("# @retry 3\n# @retryOn 503\n")
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestRunner_RetryNetworkIgnoresResponses(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	run := func(annotations string) int32 {
+		attempts.Store(0)
+		content := "### Failing\n# @name failing\n" + annotations + "# @retryDelay 1\nGET " + server.URL + "/\n\n>>>\nexpect status 200\n<<<"
+		testFile := filepath.Join(t.TempDir(), "test.http")
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+		result, err := NewRunner(&Config{}).RunFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Failed)
+		return attempts.Load()
+	}
+
+	assert.Equal(t, int32(1), run("# @retry-network\n"), "a 500 isn't a network error")
+	assert.Equal(t, int32(DefaultNetworkRetries+1), run("# @retry-network\n# @retryOn 500\n"))
+	assert.Equal(t, int32(3), run("# @retry 2\n# @retry-network\n"))
+}
+
+func TestRunner_RetryOnDialError(t *testing.T) {
+	// Nothing listens on the port once the listener is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	run := func(annotations string) int {
+		content := "### Down\n# @name down\n" + annotations + "# @retryDelay 1\nGET http://" + addr + "/"
+		testFile := filepath.Join(t.TempDir(), "test.http")
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+		budget := NewRetryBudget(100)
+		result, err := NewRunner(&Config{RetryBudget: budget}).RunFile(testFile)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Failed)
+		return budget.Used()
+	}
+
+	assert.Equal(t, 2, run("# @retry 2\n# @retryOn 503\n"), "@retryOn keeps retrying failures without a response")
+	assert.Equal(t, 2, run("# @retry 2\n# @retryOn 503, network\n"))
+	assert.Equal(t, 2, run("# @retry 2\n"))
+
+	// @retry-network leaves other failures without a response alone, such
+	// as a body that can't be built
+	content := "### Broken\n# @retry 2\n# @retry-network\nPOST http://" + addr + "/\n\n{{> ./missing.json}}"
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	budget := NewRetryBudget(100)
+	_, err = NewRunner(&Config{RetryBudget: budget}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Zero(t, budget.Used())
+}
+
+func TestRunner_ImportCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := "# @import b.http\n\n### A\n# @name a\n# @depends b\nGET http://127.0.0.1:1/a"
+	b := "# @import a.http\n\n### B\n# @name b\n# @skip\nGET http://127.0.0.1:1/b"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.http"), []byte(a), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.http"), []byte(b), 0644))
+
+	r := NewRunner(&Config{})
+	_, err := r.RunFile(filepath.Join(tmpDir, "a.http"))
+	require.NoError(t, err)
+}
+
+func TestRunner_NoEnv(t *testing.T) {
+	t.Setenv("HITSPEC_TEST_TOKEN", "from-process")
+
+	var gotToken, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotToken = r.Header.Get("X-Token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### NoEnv
+GET ` + server.URL + `/{{path}}
+X-Token: {{$env(HITSPEC_TEST_TOKEN, default-token)}}`
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("path=from-dotenv\n"), 0644))
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{NoEnv: true, Variables: map[string]string{"path": "from-var"}})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, "/from-var", gotPath)
+	assert.Equal(t, "default-token", gotToken)
+}
+
+func TestRunner_ResponseReference(t *testing.T) {
+	var gotPath, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Request-Id", "req-42")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"user":{"id":7}}`))
+			return
+		}
+		gotPath = r.URL.Path
+		gotHeader = r.Header.Get("X-Previous")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Create
+# @name create
+POST ` + server.URL + `/users
+
+### Get
+# @depends create
+GET ` + server.URL + `/users/{{create.$response.body.user.id}}
+X-Previous: {{create.$response.status}} {{create.$response.headers.X-Request-Id}}`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, "/users/7", gotPath)
+	assert.Equal(t, "201 req-42", gotHeader)
+}
+
+func TestRunner_RequestFilter(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	content := `### Login
+# @name login
+POST ` + server.URL + `/login
+
+### Profile
+# @name profile
+# @depends login
+GET ` + server.URL + `/profile
+
+### Other
+# @name other
+GET ` + server.URL + `/other`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{
+		RequestFilter: func(file, name string) bool {
+			return file == testFile && name == "profile"
+		},
+	})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Equal(t, []string{"/login", "/profile"}, paths)
+}
+
+func TestRunner_ExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	content := `### Accepted
+# @name accepted
+# @expect-status 2xx, 404
+GET ` + server.URL + `/missing
+
+### Default
+# @name default
+GET ` + server.URL + `/missing`
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	r := NewRunner(&Config{})
+	result, err := r.RunFile(testFile)
+
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+	passed := make(map[string]bool)
+	for _, res := range result.Results {
+		passed[res.Name] = res.Passed
+	}
+	assert.True(t, passed["accepted"])
+	assert.False(t, passed["default"])
+}
+
+func TestRunner_FileDefaultHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"key":    r.Header.Get("X-Api-Key"),
+			"accept": r.Header.Get("Accept"),
+		})
+	}))
+	defer server.Close()
+
+	content := `@apiKey = secret
+
+@headers
+X-Api-Key: {{apiKey}}
+Accept: application/json
+
+### Default
+GET ` + server.URL + `/
+
+>>>
+expect body.key == "secret"
+expect body.accept == "application/json"
+<<<
+
+### Override
+GET ` + server.URL + `/
+Accept: text/plain
+
+>>>
+expect body.key == "secret"
+expect body.accept == "text/plain"
+<<<`
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Passed)
+}
+
+func TestRunner_StdinBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer server.Close()
+
+	write := func(content string) string {
+		testFile := filepath.Join(t.TempDir(), "test.http")
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+		return testFile
+	}
+	one := write("### Fuzz\n# @name fuzz\nPOST " + server.URL + "/\nContent-Type: text/plain\n\n< -\n")
+
+	result, err := NewRunner(&Config{Stdin: NewStdinBody(strings.NewReader("{\"a\": 1}\n"))}).RunFile(one)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, []string{"{\"a\": 1}\n"}, bodies)
+
+	// Without --stdin-body the request fails rather than sending "< -"
+	result, err = NewRunner(&Config{}).RunFile(one)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Failed)
+	assert.Contains(t, result.Results[0].Error.Error(), "--stdin-body")
+
+	two := write("### A\nPOST " + server.URL + "/\n\n< -\n\n### B\nPOST " + server.URL + "/\n\n< -\n")
+	result, err = NewRunner(&Config{Stdin: NewStdinBody(strings.NewReader("x"))}).RunFile(two)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	require.Equal(t, 1, result.Failed)
+	assert.Contains(t, result.Results[1].Error.Error(), "only one request can use < -")
+}
+
+func TestRunner_RequestSchema(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	schema := `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.schema.json"), []byte(schema), 0644))
+
+	content := `@name = John
+
+### Valid
+# @request-schema ./user.schema.json
+POST ` + server.URL + `/users
+Content-Type: application/json
+
+{"name": "{{name}}"}
+
+### Invalid
+# @request-schema ./user.schema.json
+POST ` + server.URL + `/users
+Content-Type: application/json
+
+{"name": 42}
+
+### Not JSON
+# @request-schema ./user.schema.json
+POST ` + server.URL + `/users
+Content-Type: text/plain
+
+name=John
+
+### Missing partial
+# @request-schema ./user.schema.json
+POST ` + server.URL + `/users
+Content-Type: application/json
+
+{{> ./missing.json}}`
+	testFile := filepath.Join(dir, "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Passed)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, int32(1), hits.Load(), "invalid bodies must not be sent")
+
+	require.Error(t, result.Results[1].Error)
+	assert.Contains(t, result.Results[1].Error.Error(), "request body doesn't match ./user.schema.json")
+	assert.Contains(t, result.Results[1].Error.Error(), "name")
+	require.Error(t, result.Results[2].Error)
+	assert.Contains(t, result.Results[2].Error.Error(), "isn't valid JSON")
+	require.Error(t, result.Results[3].Error)
+	assert.Contains(t, result.Results[3].Error.Error(), "partial ./missing.json", "the partial error comes before schema validation")
+}
+
+func TestRunner_PersistCaptures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token": "abc", "refreshToken": "def", "id": 7}`))
+	}))
+	defer server.Close()
+
+	content := `### Login
+# @persist token
+POST ` + server.URL + `/login
+
+>>>capture
+token from body.token
+refreshToken from body.refreshToken
+<<<
+
+### Profile
+# @persist
+GET ` + server.URL + `/me
+
+>>>capture
+id from body.id
+<<<
+
+### Other
+GET ` + server.URL + `/other
+
+>>>capture
+other from body.token
+<<<`
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, map[string]any{"token": "abc"}, result.Results[0].Persist)
+	assert.Nil(t, result.Results[2].Persist)
+
+	persisted := PersistedCaptures([]*RunResult{result})
+	assert.Equal(t, map[string]any{"token": "abc", "id": float64(7)}, persisted)
+}
+
+func TestRunner_TeardownAfterBail(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	content := `### Cleanup
+# @teardown
+DELETE ` + server.URL + `/users/1
+
+### Create
+POST ` + server.URL + `/users
+
+### Broken
+GET ` + server.URL + `/broken
+
+### Never runs
+GET ` + server.URL + `/later`
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{Bail: true}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"POST /users", "GET /broken", "DELETE /users/1"}, paths)
+	assert.Equal(t, 2, result.Passed)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, "Cleanup", result.Results[2].Name)
+}
+
+func TestRunner_Group(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/orders" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	content := `### Pay
+# @group checkout
+POST ` + server.URL + `/payments
+
+### Health
+GET ` + server.URL + `/health
+
+### Create cart
+# @group checkout
+# @setup
+POST ` + server.URL + `/carts
+
+### Create order
+# @group orders
+# @setup
+POST ` + server.URL + `/orders
+
+### List orders
+# @group orders
+GET ` + server.URL + `/orders/list`
+	testFile := filepath.Join(t.TempDir(), "test.http")
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	result, err := NewRunner(&Config{}).RunFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"POST /carts", "POST /payments", "GET /health", "POST /orders"}, paths)
+
+	require.Len(t, result.Groups, 2)
+	assert.Equal(t, "checkout", result.Groups[0].Name)
+	assert.True(t, result.Groups[0].Passed)
+	assert.Len(t, result.Groups[0].Results, 2)
+	assert.Equal(t, "orders", result.Groups[1].Name)
+	assert.False(t, result.Groups[1].Passed)
+	require.Len(t, result.Groups[1].Results, 2)
+	assert.True(t, result.Groups[1].Results[1].Skipped)
+	assert.Equal(t, "group setup failed", result.Groups[1].Results[1].SkipReason)
+}
+
+func TestNewDependencyGraph(t *testing.T) {
+	content := `### login
+POST https://api.example.com/login
+
+### profile
+# @depends login
+GET https://api.example.com/me
+
+### update
+# @depends profile, login, common.auth
+PUT https://api.example.com/me`
+	file, err := parser.Parse(content, "test.http")
+	require.NoError(t, err)
+
+	graph := NewDependencyGraph(file.Requests)
+	assert.Equal(t, []string{"profile", "update"}, graph.Dependents["login"])
+	assert.Equal(t, []string{"update"}, graph.Dependents["profile"])
+	assert.Equal(t, map[string][]string{"update": {"common.auth"}}, graph.Missing)
+	assert.Equal(t, map[string]int{"login": 0, "profile": 1, "update": 2}, graph.InDegree)
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+// StdinBody supplies the body of the request written with "< -". Stdin can
+// only be read once, so a single request may use it; retries of that request
+// resend the same bytes. One StdinBody is shared by every runner of a run.
+type StdinBody struct {
+	mu    sync.Mutex
+	r     io.Reader
+	owner string
+	data  []byte
+	err   error
+}
+
+// NewStdinBody returns a StdinBody reading from r, usually os.Stdin
+func NewStdinBody(r io.Reader) *StdinBody {
+	return &StdinBody{r: r}
+}
+
+// Read returns the body for the request identified by owner, reading it on
+// first use. It fails when another request already consumed stdin.
+func (s *StdinBody) Read(owner string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.owner == "" {
+		s.owner = owner
+		s.data, s.err = io.ReadAll(s.r)
+	} else if s.owner != owner {
+		return "", fmt.Errorf("%s reads its body from stdin, but %s already did; only one request can use < -", owner, s.owner)
+	}
+	if s.err != nil {
+		return "", fmt.Errorf("reading body from stdin: %w", s.err)
+	}
+	return string(s.data), nil
+}
+
+// stdinBody returns the stdin body of a request written with "< -"
+func (r *Runner) stdinBody(req *parser.Request, filePath string) (string, error) {
+	owner := fmt.Sprintf("%s:%d", filePath, req.Line)
+	if req.Name != "" {
+		owner = fmt.Sprintf("request %q (%s)", req.Name, owner)
+	}
+	if r.config.Stdin == nil {
+		return "", fmt.Errorf("%s reads its body from stdin (< -); run with --stdin-body", owner)
+	}
+	return r.config.Stdin.Read(owner)
+}
@@ -42,6 +42,7 @@ type EndpointStatus struct {
 // Analyzer analyzes API coverage against an OpenAPI spec.
 type Analyzer struct {
 	endpoints []Endpoint
+	patterns  map[string]*regexp.Regexp // Compiled endpoint paths; nil when invalid
 }
 
 // Endpoint represents an API endpoint from the OpenAPI spec.
@@ -210,13 +211,30 @@ func (a *Analyzer) matchEndpoint(req ExecutedRequest, endpoint Endpoint) bool {
 		return false
 	}
 
+	re := a.pathPattern(endpoint.Path)
+	return re != nil && re.MatchString(req.Path)
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// pathPattern returns the regex matching an OpenAPI path, compiling it on
+// first use so each endpoint is compiled once rather than once per request
+func (a *Analyzer) pathPattern(path string) *regexp.Regexp {
+	if re, ok := a.patterns[path]; ok {
+		return re
+	}
+	if a.patterns == nil {
+		a.patterns = make(map[string]*regexp.Regexp)
+	}
+
 	// Convert OpenAPI path parameters to regex
 	// e.g., /users/{id} -> /users/[^/]+
-	pathPattern := regexp.MustCompile(`\{[^}]+\}`).ReplaceAllString(endpoint.Path, `[^/]+`)
-	pathPattern = "^" + pathPattern + "$"
-
-	matched, _ := regexp.MatchString(pathPattern, req.Path)
-	return matched
+	re, err := regexp.Compile("^" + pathParam.ReplaceAllString(path, `[^/]+`) + "$")
+	if err != nil {
+		re = nil
+	}
+	a.patterns[path] = re
+	return re
 }
 
 // FormatConsole formats the report for console output.
@@ -1,6 +1,7 @@
 package coverage
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -244,3 +245,17 @@ func TestReport_FormatHTML(t *testing.T) {
 		t.Error("expected HTML to contain tag")
 	}
 }
+
+func BenchmarkAnalyzer_Analyze(b *testing.B) {
+	analyzer := NewAnalyzer()
+	var requests []ExecutedRequest
+	for i := 0; i < 200; i++ {
+		analyzer.endpoints = append(analyzer.endpoints, Endpoint{Method: "GET", Path: fmt.Sprintf("/resource%d/{id}", i)})
+		requests = append(requests, ExecutedRequest{Method: "GET", Path: fmt.Sprintf("/resource%d/42", i)})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		analyzer.Analyze(requests)
+	}
+}
@@ -38,6 +38,11 @@ func SignAWSRequest(req *Request) (string, error) {
 
 	// Calculate payload hash
 	payloadHash := sha256Hash(req.Body)
+	if req.BodyFile != "" {
+		if payloadHash, err = bodyFileHash(req.BodyFile, req.BaseDir); err != nil {
+			return "", err
+		}
+	}
 
 	// Create canonical URI
 	canonicalURI := parsedURL.Path
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// setBodyFile uses the file at path, relative to the request's base
+// directory, as the body. Files without {{ are streamed by the client so large
+// payloads aren't held in memory; templated files are read and interpolated.
+// A file that can't be opened is reported by the client when it sends.
+func (r *Request) setBodyFile(path string, resolver func(string) string, charset string) {
+	if r.Headers["Content-Type"] == "" {
+		if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+			r.SetHeader("Content-Type", ct)
+		}
+	}
+
+	r.Body = ""
+	r.BodyFile = path
+
+	filePath := partFilePath(path, r.BaseDir)
+	if err := validatePathWithinBase(filePath, r.BaseDir); err != nil {
+		return
+	}
+	if templated, err := fileHasVariables(filePath); err != nil || !templated {
+		return
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+
+	content, err := expandPartials(string(data), filepath.Dir(filePath), r.BaseDir)
+	if err != nil {
+		r.buildErr = err
+		return
+	}
+
+	r.BodyFile = ""
+	r.SetBody(resolver(content))
+	if charset != "" {
+		r.encodeBody(charset)
+	}
+}
+
+// openBodyFile opens a streamed body file and returns its size
+func openBodyFile(path, baseDir string) (*os.File, int64, error) {
+	filePath := partFilePath(path, baseDir)
+	if err := validatePathWithinBase(filePath, baseDir); err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// ReadBody returns the body that will be sent, reading a streamed body file
+func (r *Request) ReadBody() ([]byte, error) {
+	if r.BodyFile == "" {
+		return []byte(r.Body), nil
+	}
+	f, _, err := openBodyFile(r.BodyFile, r.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// bodyFileHash returns the hex SHA-256 of a streamed body file, for signing
+func bodyFileHash(path, baseDir string) (string, error) {
+	f, _, err := openBodyFile(path, baseDir)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type scannedFile struct {
+	modTime   time.Time
+	size      int64
+	templated bool
+}
+
+// scannedFiles caches fileHasVariables by path, so stress tests sending the
+// same file don't rescan it for every request
+var scannedFiles sync.Map
+
+// fileHasVariables reports whether the file contains a {{ variable reference,
+// reading it in chunks rather than all at once
+func fileHasVariables(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if cached, ok := scannedFiles.Load(path); ok {
+		s := cached.(scannedFile)
+		if s.modTime.Equal(info.ModTime()) && s.size == info.Size() {
+			return s.templated, nil
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	templated, err := containsVariable(f)
+	if err != nil {
+		return false, err
+	}
+	scannedFiles.Store(path, scannedFile{modTime: info.ModTime(), size: info.Size(), templated: templated})
+	return templated, nil
+}
+
+func containsVariable(r io.Reader) (bool, error) {
+	buf := make([]byte, 64*1024)
+	var last byte
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			// A {{ can straddle two reads
+			if (last == '{' && chunk[0] == '{') || bytes.Contains(chunk, []byte("{{")) {
+				return true, nil
+			}
+			last = chunk[n-1]
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
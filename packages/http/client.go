@@ -6,11 +6,16 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	neturl "net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -38,6 +43,14 @@ type Client struct {
 	validateSSL    bool
 	proxyURL       string
 	defaultHeaders map[string]string
+	hostHeaders    map[string]map[string]string // Host glob -> headers
+	insecureHosts  map[string]bool              // Hosts whose certificates are not verified
+	userAgent      *string                      // nil sends Go's default; "" sends no User-Agent
+	fixtureMode    FixtureMode                  // Record or replay responses (0 uses the network as is)
+	fixtureDir     string
+	fixtureIgnore  []string // Request parts left out of fixture keys (see WithFixtureIgnore)
+	connObserver   ConnObserver
+	logger         *slog.Logger // Logs the redirects followed; nil logs nothing
 }
 
 // DigestAuthCredentials holds credentials for digest auth
@@ -65,6 +78,13 @@ func NewClient(opts ...ClientOption) *Client {
 		MaxIdleConns:        DefaultMaxIdleConns,
 		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
 		IdleConnTimeout:     DefaultIdleConnTimeout,
+		DialContext:         c.dialContext(),
+		// A custom DialContext turns off automatic HTTP/2; keep it on as
+		// before, except when TLS settings are customized below
+		ForceAttemptHTTP2: c.validateSSL,
+		// Responses are decompressed in doRequest, which keeps their
+		// Content-Encoding and compressed size for assertions
+		DisableCompression: true,
 	}
 
 	// Configure TLS verification
@@ -83,17 +103,49 @@ func NewClient(opts ...ClientOption) *Client {
 	}
 
 	redirectPolicy := func(req *http.Request, via []*http.Request) error {
-		if !c.followRedirect {
+		follow := c.followRedirect
+		if v, ok := req.Context().Value(followRedirectsKey{}).(bool); ok {
+			follow = v
+		}
+		if !follow {
 			return http.ErrUseLastResponse
 		}
-		if len(via) >= c.maxRedirects {
+		limit := c.maxRedirects
+		if max, ok := req.Context().Value(maxRedirectsKey{}).(int); ok {
+			limit = max
+		}
+		// via holds the requests made so far, one more than the redirects
+		// already followed
+		if len(via) > limit {
+			c.log().Info("redirect limit reached, returning the redirect response",
+				"url", via[len(via)-1].URL.String(), "limit", limit)
 			return http.ErrUseLastResponse
 		}
+		c.log().Debug("following redirect",
+			"from", via[len(via)-1].URL.String(), "to", req.URL.String(), "redirects", len(via))
 		return nil
 	}
 
+	var roundTripper http.RoundTripper = transport
+	if c.validateSSL && len(c.insecureHosts) > 0 {
+		insecure := transport.Clone()
+		insecure.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+		insecure.ForceAttemptHTTP2 = false
+		roundTripper = &hostTransport{
+			secure:   transport,
+			insecure: insecure,
+			hosts:    c.insecureHosts,
+		}
+	}
+
+	if c.fixtureMode != 0 {
+		roundTripper = newFixtureTransport(roundTripper, c.fixtureMode, c.fixtureDir, c.fixtureIgnore)
+	}
+
 	c.httpClient = &http.Client{
-		Transport:     transport,
+		Transport:     roundTripper,
 		Timeout:       c.timeout,
 		CheckRedirect: redirectPolicy,
 	}
@@ -107,12 +159,38 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithLogger sets the logger the client reports its decisions to, such as
+// the redirects it follows
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// discardLogger is used by clients without a logger
+var discardLogger = slog.New(slog.DiscardHandler)
+
 func WithFollowRedirects(follow bool) ClientOption {
 	return func(c *Client) {
 		c.followRedirect = follow
 	}
 }
 
+// followRedirectsKey carries a request's @follow-redirects override to the
+// redirect policy
+type followRedirectsKey struct{}
+
+// maxRedirectsKey carries a request's @max-redirects override to the
+// redirect policy
+type maxRedirectsKey struct{}
+
 func WithMaxRedirects(max int) ClientOption {
 	return func(c *Client) {
 		c.maxRedirects = max
@@ -134,6 +212,35 @@ func WithDefaultHeaders(headers map[string]string) ClientOption {
 	}
 }
 
+// WithHostHeaders sets default headers for requests whose host matches a glob
+// pattern such as "api.staging.example.com" or "*.staging.example.com". They
+// override global default headers and are overridden by request headers.
+func WithHostHeaders(headers map[string]map[string]string) ClientOption {
+	return func(c *Client) {
+		if c.hostHeaders == nil {
+			c.hostHeaders = make(map[string]map[string]string)
+		}
+		for pattern, h := range headers {
+			c.hostHeaders[pattern] = h
+		}
+	}
+}
+
+// WithInsecureHosts disables certificate verification for the given hosts
+// only; certificates of every other host are still verified.
+func WithInsecureHosts(hosts []string) ClientOption {
+	return func(c *Client) {
+		if c.insecureHosts == nil {
+			c.insecureHosts = make(map[string]bool)
+		}
+		for _, h := range hosts {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				c.insecureHosts[h] = true
+			}
+		}
+	}
+}
+
 // WithValidateSSL enables or disables SSL certificate validation
 func WithValidateSSL(validate bool) ClientOption {
 	return func(c *Client) {
@@ -148,7 +255,28 @@ func WithProxy(proxyURL string) ClientOption {
 	}
 }
 
+// WithUserAgent sets the User-Agent sent instead of Go's default. An empty
+// string sends no User-Agent at all. Default, host and request headers
+// setting User-Agent override it.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = &userAgent
+	}
+}
+
+// setUserAgent applies the client's User-Agent to req; net/http omits the
+// header when its value is empty
+func (c *Client) setUserAgent(req *http.Request) {
+	if c.userAgent != nil {
+		req.Header.Set("User-Agent", *c.userAgent)
+	}
+}
+
 func (c *Client) Do(req *Request) (*Response, error) {
+	if req.buildErr != nil {
+		return nil, req.buildErr
+	}
+
 	ctx := context.Background()
 	if req.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -182,6 +310,7 @@ func (c *Client) doRequest(ctx context.Context, req *Request, authHeader string)
 
 	var body io.Reader
 	var contentType string
+	var bodyFileSize int64
 
 	// Check if this is a multipart request
 	if len(req.Multipart) > 0 {
@@ -191,19 +320,51 @@ func (c *Client) doRequest(ctx context.Context, req *Request, authHeader string)
 		}
 		body = multipartBody
 		contentType = ct
+	} else if req.BodyFile != "" {
+		f, size, err := openBodyFile(req.BodyFile, req.BaseDir)
+		if err != nil {
+			return nil, fmt.Errorf("body file: %w", err)
+		}
+		defer f.Close()
+		body = f
+		bodyFileSize = size
 	} else if req.Body != "" {
 		body = bytes.NewBufferString(req.Body)
 	}
 
+	if req.FollowRedirects != nil {
+		ctx = context.WithValue(ctx, followRedirectsKey{}, *req.FollowRedirects)
+	}
+	if req.MaxRedirects != nil {
+		ctx = context.WithValue(ctx, maxRedirectsKey{}, *req.MaxRedirects)
+	}
+
+	trace := &timingTrace{}
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace(c.connObserver))
+
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
 	if err != nil {
 		return nil, err
 	}
 
+	// Streamed files aren't sent chunked, and can be reopened for redirects
+	if req.BodyFile != "" && len(req.Multipart) == 0 {
+		httpReq.ContentLength = bodyFileSize
+		httpReq.GetBody = func() (io.ReadCloser, error) {
+			f, _, err := openBodyFile(req.BodyFile, req.BaseDir)
+			return f, err
+		}
+	}
+
+	c.setUserAgent(httpReq)
 	for k, v := range c.defaultHeaders {
 		httpReq.Header.Set(k, v)
 	}
 
+	for k, v := range c.headersForHost(httpReq.URL) {
+		httpReq.Header.Set(k, v)
+	}
+
 	for k, v := range req.Headers {
 		httpReq.Header.Set(k, v)
 	}
@@ -218,6 +379,11 @@ func (c *Client) doRequest(ctx context.Context, req *Request, authHeader string)
 		httpReq.Header.Set("Authorization", authHeader)
 	}
 
+	// Ask for gzip as net/http does when it handles compression itself
+	if httpReq.Header.Get("Accept-Encoding") == "" && httpReq.Header.Get("Range") == "" && httpReq.Method != http.MethodHead {
+		httpReq.Header.Set("Accept-Encoding", "gzip")
+	}
+
 	start := time.Now()
 	httpResp, err := c.httpClient.Do(httpReq)
 	duration := time.Since(start)
@@ -231,21 +397,54 @@ func (c *Client) doRequest(ctx context.Context, req *Request, authHeader string)
 	if err != nil {
 		return nil, err
 	}
+	timing := trace.finish()
+	wireSize := len(respBody)
+	encoding := httpResp.Header.Get("Content-Encoding")
+	if decoded, ok := decompressBody(respBody, encoding); ok {
+		respBody = decoded
+	}
+	rawBody := respBody
+	respBody = decodeBody(respBody, httpResp.Header.Get("Content-Type"))
 
 	headers := make(map[string]string)
-	for k := range httpResp.Header {
+	multiHeaders := make(map[string][]string)
+	for k, v := range httpResp.Header {
 		headers[k] = httpResp.Header.Get(k)
+		multiHeaders[k] = append([]string(nil), v...)
 	}
 
+	redirects, finalURL := redirectChain(httpResp)
+
 	return &Response{
-		StatusCode: httpResp.StatusCode,
-		Status:     httpResp.Status,
-		Headers:    headers,
-		Body:       respBody,
-		Duration:   duration,
+		StatusCode:   httpResp.StatusCode,
+		Status:       httpResp.Status,
+		Headers:      headers,
+		MultiHeaders: multiHeaders,
+		Body:         respBody,
+		RawBody:      rawBody,
+		Encoding:     strings.ToLower(encoding),
+		WireSize:     wireSize,
+		Duration:     duration,
+		Timing:       timing,
+		Redirects:    redirects,
+		FinalURL:     finalURL,
 	}, nil
 }
 
+// redirectChain returns the URLs that redirected on the way to resp, oldest
+// first, and the URL that finally produced resp. net/http links each followed
+// request to the redirect response that caused it.
+func redirectChain(resp *http.Response) ([]string, string) {
+	if resp.Request == nil {
+		return nil, ""
+	}
+	var redirects []string
+	for req := resp.Request; req.Response != nil && req.Response.Request != nil; req = req.Response.Request {
+		redirects = append([]string{req.Response.Request.URL.String()}, redirects...)
+	}
+	return redirects, resp.Request.URL.String()
+}
+
 func (c *Client) doWithDigestAuth(ctx context.Context, req *Request) (*Response, error) {
 	// First request without auth to get the challenge
 	resp, err := c.doRequest(ctx, req, "")
@@ -344,6 +543,7 @@ func (c *Client) fetchOAuth2Token(auth *OAuth2AuthCredentials) (string, error) {
 		return "", err
 	}
 
+	c.setUserAgent(tokenReq)
 	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Add client credentials via Basic auth
@@ -489,13 +689,8 @@ func BuildMultipartBody(fields []*parser.MultipartField, baseDir string) (*bytes
 
 	for _, field := range fields {
 		if field.Type == parser.MultipartFieldFile {
-			// Resolve file path relative to base directory
-			filePath := field.Path
-			if !filepath.IsAbs(filePath) && baseDir != "" {
-				filePath = filepath.Join(baseDir, filePath)
-			}
-
 			// Validate path doesn't escape base directory (prevent path traversal)
+			filePath := partFilePath(field.Path, baseDir)
 			if err := validatePathWithinBase(filePath, baseDir); err != nil {
 				return nil, "", err
 			}
@@ -505,7 +700,7 @@ func BuildMultipartBody(fields []*parser.MultipartField, baseDir string) (*bytes
 				return nil, "", err
 			}
 
-			part, err := writer.CreateFormFile(field.Name, filepath.Base(filePath))
+			part, err := createFilePart(writer, field, filepath.Base(filePath))
 			if err != nil {
 				file.Close()
 				return nil, "", err
@@ -516,12 +711,35 @@ func BuildMultipartBody(fields []*parser.MultipartField, baseDir string) (*bytes
 			if err != nil {
 				return nil, "", err
 			}
-		} else {
-			// Regular form field
-			err := writer.WriteField(field.Name, field.Value)
+			continue
+		}
+
+		// Regular form field, optionally read from a file
+		value := field.Value
+		if field.Path != "" {
+			data, err := readPartFile(field.Path, baseDir)
 			if err != nil {
 				return nil, "", err
 			}
+			value = string(data)
+		}
+
+		if field.ContentType == "" {
+			if err := writer.WriteField(field.Name, value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(field.Name)))
+		h.Set("Content-Type", field.ContentType)
+		part, err := writer.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.WriteString(part, value); err != nil {
+			return nil, "", err
 		}
 	}
 
@@ -532,3 +750,93 @@ func BuildMultipartBody(fields []*parser.MultipartField, baseDir string) (*bytes
 
 	return body, writer.FormDataContentType(), nil
 }
+
+// partFilePath resolves a multipart file path relative to the base directory
+func partFilePath(path, baseDir string) string {
+	if !filepath.IsAbs(path) && baseDir != "" {
+		return filepath.Join(baseDir, path)
+	}
+	return path
+}
+
+// readPartFile reads a file referenced by a multipart part, rejecting paths
+// that escape the base directory
+func readPartFile(path, baseDir string) ([]byte, error) {
+	filePath := partFilePath(path, baseDir)
+	if err := validatePathWithinBase(filePath, baseDir); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filePath)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// createFilePart creates a file part like multipart.Writer.CreateFormFile,
+// honoring the field's content type and filename overrides
+func createFilePart(writer *multipart.Writer, field *parser.MultipartField, defaultFilename string) (io.Writer, error) {
+	filename := defaultFilename
+	if field.Filename != "" {
+		filename = field.Filename
+	}
+	contentType := "application/octet-stream"
+	if field.ContentType != "" {
+		contentType = field.ContentType
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field.Name), quoteEscaper.Replace(filename)))
+	h.Set("Content-Type", contentType)
+	return writer.CreatePart(h)
+}
+
+// headersForHost returns the host-scoped default headers matching u. Patterns
+// are applied in sorted order, so the result is the same on every run.
+func (c *Client) headersForHost(u *neturl.URL) map[string]string {
+	if len(c.hostHeaders) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, 0, len(c.hostHeaders))
+	for pattern := range c.hostHeaders {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	headers := make(map[string]string)
+	for _, pattern := range patterns {
+		if matchesHost(pattern, u) {
+			for k, v := range c.hostHeaders[pattern] {
+				headers[k] = v
+			}
+		}
+	}
+	return headers
+}
+
+// matchesHost reports whether pattern matches the URL's hostname, or its
+// host:port when the pattern includes a port
+func matchesHost(pattern string, u *neturl.URL) bool {
+	pattern = strings.ToLower(pattern)
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(pattern, ":") {
+		host = strings.ToLower(u.Host)
+	}
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
+
+// hostTransport sends requests to insecure hosts through a transport that
+// skips certificate verification, and everything else through the default one
+type hostTransport struct {
+	secure   http.RoundTripper
+	insecure http.RoundTripper
+	hosts    map[string]bool
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.hosts[strings.ToLower(req.URL.Hostname())] {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.secure.RoundTrip(req)
+}
@@ -1,13 +1,24 @@
 package http
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
 )
 
 func TestClient_Get(t *testing.T) {
@@ -94,6 +105,554 @@ func TestClient_WithDefaultHeaders(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 }
 
+func TestClient_WithHostHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "staging", r.Header.Get("X-Env"))
+		assert.Equal(t, "local", r.Header.Get("X-Scope"))
+		assert.Empty(t, r.Header.Get("X-Other"))
+		assert.Equal(t, "request", r.Header.Get("X-Override"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithDefaultHeader("X-Env", "default"),
+		WithHostHeaders(map[string]map[string]string{
+			"127.0.0.*":     {"X-Env": "staging", "X-Override": "host"},
+			"127.0.0.1:*":   {"X-Scope": "local"},
+			"*.example.com": {"X-Other": "other"},
+		}),
+	)
+	resp, err := client.Get(server.URL, map[string]string{"X-Override": "request"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestClient_WithUserAgent(t *testing.T) {
+	var got []string
+	var sent []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Header["User-Agent"]
+		got = append(got, r.Header.Get("User-Agent"))
+		sent = append(sent, ok)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		client  *Client
+		headers map[string]string
+		want    string
+		sent    bool
+	}{
+		{"go default", NewClient(), nil, "Go-http-client/1.1", true},
+		{"custom", NewClient(WithUserAgent("hitspec-ci")), nil, "hitspec-ci", true},
+		{"omitted", NewClient(WithUserAgent("")), nil, "", false},
+		{"request header wins", NewClient(WithUserAgent("hitspec-ci")), map[string]string{"User-Agent": "request"}, "request", true},
+		{"default header wins", NewClient(WithUserAgent(""), WithDefaultHeader("User-Agent", "config")), nil, "config", true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.client.Get(server.URL, tt.headers)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got[i])
+			assert.Equal(t, tt.sent, sent[i])
+		})
+	}
+}
+
+func TestClient_Fixtures(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Call", fmt.Sprint(calls))
+		if r.URL.Path == "/binary" {
+			w.Write([]byte{0xff, 0x00, 0xfe})
+			return
+		}
+		fmt.Fprintf(w, `{"call":%d,"body":%q}`, calls, body)
+	}))
+	dir := t.TempDir()
+
+	recorder := NewClient(WithFixtures(FixtureRecord, dir))
+	var recorded []string
+	for _, send := range []func() (*Response, error){
+		func() (*Response, error) { return recorder.Get(server.URL+"/items", nil) },
+		func() (*Response, error) { return recorder.Get(server.URL+"/items", nil) },
+		func() (*Response, error) { return recorder.Post(server.URL+"/items", `{"a":1}`, nil) },
+		func() (*Response, error) { return recorder.Post(server.URL+"/items", `{"a":2}`, nil) },
+		func() (*Response, error) { return recorder.Get(server.URL+"/binary", nil) },
+	} {
+		resp, err := send()
+		require.NoError(t, err)
+		recorded = append(recorded, resp.BodyString())
+	}
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 5)
+
+	replayer := NewClient(WithFixtures(FixtureReplay, dir))
+	resp, err := replayer.Get(server.URL+"/items", nil)
+	require.NoError(t, err)
+	assert.Equal(t, recorded[0], resp.BodyString())
+	assert.Equal(t, "1", resp.Header("X-Call"))
+	assert.True(t, resp.IsJSON())
+
+	// The same request is replayed in recorded order, then from the start
+	resp, err = replayer.Get(server.URL+"/items", nil)
+	require.NoError(t, err)
+	assert.Equal(t, recorded[1], resp.BodyString())
+	resp, err = replayer.Get(server.URL+"/items", nil)
+	require.NoError(t, err)
+	assert.Equal(t, recorded[0], resp.BodyString())
+
+	// Bodies tell requests apart
+	resp, err = replayer.Post(server.URL+"/items", `{"a":2}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, recorded[3], resp.BodyString())
+
+	resp, err = replayer.Get(server.URL+"/binary", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00, 0xfe}, resp.Body)
+
+	_, err = replayer.Get(server.URL+"/missing", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded fixture for GET")
+	assert.Equal(t, 5, calls)
+}
+
+func TestClient_FixtureIgnore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("created"))
+	}))
+	dir := t.TempDir()
+
+	recorder := NewClient(WithFixtures(FixtureRecord, dir), WithFixtureIgnore("body.meta.requestId", "query.ts"))
+	_, err := recorder.Post(server.URL+"/orders?ts=1&page=2", `{"item":"book","meta":{"requestId":"r-1"}}`, nil)
+	require.NoError(t, err)
+	bodyDir := t.TempDir()
+	_, err = NewClient(WithFixtures(FixtureRecord, bodyDir), WithFixtureIgnore("body")).Post(server.URL+"/tokens", `{"nonce":"1"}`, nil)
+	require.NoError(t, err)
+	server.Close()
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "no temporary files are left behind")
+	info, err := files[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	replayer := NewClient(WithFixtures(FixtureReplay, dir), WithFixtureIgnore("body.meta.requestId", "query.ts"))
+	resp, err := replayer.Post(server.URL+"/orders?ts=2&page=2", `{"meta":{"requestId":"r-2"},"item":"book"}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "created", resp.BodyString())
+
+	// Parts that aren't ignored still tell requests apart
+	_, err = replayer.Post(server.URL+"/orders?ts=2&page=3", `{"item":"book","meta":{"requestId":"r-2"}}`, nil)
+	assert.ErrorContains(t, err, "no recorded fixture")
+	_, err = replayer.Post(server.URL+"/orders?ts=2&page=2", `{"item":"pen","meta":{"requestId":"r-2"}}`, nil)
+	assert.ErrorContains(t, err, "no recorded fixture")
+
+	// Without ignored fields the dynamic parts are part of the key
+	strict := NewClient(WithFixtures(FixtureReplay, dir))
+	_, err = strict.Post(server.URL+"/orders?ts=2&page=2", `{"item":"book","meta":{"requestId":"r-2"}}`, nil)
+	assert.ErrorContains(t, err, "no recorded fixture")
+
+	resp, err = NewClient(WithFixtures(FixtureReplay, bodyDir), WithFixtureIgnore("body")).Post(server.URL+"/tokens", `{"nonce":"2"}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "created", resp.BodyString())
+}
+
+func TestValidFixtureIgnore(t *testing.T) {
+	for field, want := range map[string]bool{
+		"body":           true,
+		"body.requestId": true,
+		"query.ts":       true,
+		"body.":          false,
+		"query":          false,
+		"header.Date":    false,
+	} {
+		assert.Equal(t, want, ValidFixtureIgnore(field), field)
+	}
+}
+
+func TestClient_WithInsecureHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("listed host skips verification", func(t *testing.T) {
+		client := NewClient(WithInsecureHosts([]string{"127.0.0.1"}))
+		resp, err := client.Get(server.URL, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("other hosts are still verified", func(t *testing.T) {
+		client := NewClient(WithInsecureHosts([]string{"internal.example.com"}))
+		_, err := client.Get(server.URL, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate")
+	})
+}
+
+func TestBuildMultipartBody_FileOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.bin"), []byte("%PDF"), 0644))
+
+	body, contentType, err := BuildMultipartBody([]*parser.MultipartField{
+		{Type: parser.MultipartFieldValue, Name: "title", Value: "Q3"},
+		{Type: parser.MultipartFieldFile, Name: "document", Path: "report.bin", ContentType: "application/pdf", Filename: "q3.pdf"},
+		{Type: parser.MultipartFieldFile, Name: "raw", Path: "report.bin"},
+	}, dir)
+	require.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	form, err := multipart.NewReader(body, params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Q3"}, form.Value["title"])
+	doc := form.File["document"][0]
+	assert.Equal(t, "q3.pdf", doc.Filename)
+	assert.Equal(t, "application/pdf", doc.Header.Get("Content-Type"))
+	raw := form.File["raw"][0]
+	assert.Equal(t, "report.bin", raw.Filename)
+	assert.Equal(t, "application/octet-stream", raw.Header.Get("Content-Type"))
+}
+
+func TestBuildMultipartBody_FieldFromFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "meta.json"), []byte(`{"owner":"{{user}}"}`), 0644))
+
+	req := BuildRequestFromASTWithBaseDir(&parser.Request{
+		Method: "POST",
+		URL:    "https://api.example.com/upload",
+		Body: &parser.Body{
+			ContentType: parser.BodyMultipart,
+			Multipart: []*parser.MultipartField{
+				{Type: parser.MultipartFieldValue, Name: "meta", Path: "./meta.json", ContentType: "application/json"},
+				{Type: parser.MultipartFieldValue, Name: "note", Value: "by {{user}}"},
+			},
+		},
+	}, func(s string) string { return strings.ReplaceAll(s, "{{user}}", "ada") }, dir)
+
+	body, contentType, err := BuildMultipartBody(req.Multipart, dir)
+	require.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	reader := multipart.NewReader(body, params["boundary"])
+
+	part, err := reader.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "meta", part.FormName())
+	assert.Equal(t, "application/json", part.Header.Get("Content-Type"))
+	data, err := io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Equal(t, `{"owner":"ada"}`, string(data))
+
+	part, err = reader.NextPart()
+	require.NoError(t, err)
+	data, err = io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Equal(t, "by ada", string(data))
+
+	_, _, err = BuildMultipartBody([]*parser.MultipartField{
+		{Type: parser.MultipartFieldValue, Name: "meta", Path: "../outside.json"},
+	}, dir)
+	assert.Error(t, err)
+}
+
+func TestBuildRequest_Encoding(t *testing.T) {
+	build := func(contentType string) *Request {
+		req := &parser.Request{
+			Method:   "POST",
+			URL:      "https://legacy.example.com/orders",
+			Body:     &parser.Body{ContentType: parser.BodyRaw, Raw: "名前=テスト"},
+			Metadata: &parser.RequestMetadata{Encoding: "shift_jis"},
+		}
+		if contentType != "" {
+			req.Headers = []*parser.Header{{Key: "Content-Type", Value: contentType}}
+		}
+		return BuildRequestFromAST(req, func(s string) string { return s })
+	}
+
+	want, err := japanese.ShiftJIS.NewEncoder().String("名前=テスト")
+	require.NoError(t, err)
+
+	r := build("application/x-www-form-urlencoded")
+	assert.Equal(t, want, r.Body)
+	assert.Equal(t, "application/x-www-form-urlencoded; charset=shift_jis", r.Headers["Content-Type"])
+
+	r = build("")
+	assert.Equal(t, want, r.Body)
+	assert.Equal(t, "text/plain; charset=shift_jis", r.Headers["Content-Type"])
+}
+
+func TestBuildRequest_JSONPatch(t *testing.T) {
+	resolver := func(s string) string {
+		return strings.NewReplacer("{{name}}", "Jane", "{{id}}", "42").Replace(s)
+	}
+	r := BuildRequestFromAST(&parser.Request{
+		Method: "PATCH",
+		URL:    "https://api.example.com/users/1",
+		Body: &parser.Body{ContentType: parser.BodyJSONPatch, Patch: []*parser.PatchOperation{
+			{Op: "replace", Path: "/name", Value: `"{{name}}"`},
+			{Op: "add", Path: "/tags/-", Value: "admin"},
+			{Op: "add", Path: "/manager", Value: `{"id": {{id}}}`},
+			{Op: "test", Path: "/deleted", Value: "null"},
+			{Op: "remove", Path: "/legacy"},
+			{Op: "move", From: "/old", Path: "/new"},
+		}},
+	}, resolver)
+
+	assert.Equal(t, "application/json-patch+json", r.Headers["Content-Type"])
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/name", "value": "Jane"},
+		{"op": "add", "path": "/tags/-", "value": "admin"},
+		{"op": "add", "path": "/manager", "value": {"id": 42}},
+		{"op": "test", "path": "/deleted", "value": null},
+		{"op": "remove", "path": "/legacy"},
+		{"op": "move", "from": "/old", "path": "/new"}
+	]`, r.Body)
+}
+
+func TestBodyFile(t *testing.T) {
+	var gotBody, gotType string
+	var gotLength int64
+	var chunked bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		gotType = r.Header.Get("Content-Type")
+		gotLength = r.ContentLength
+		chunked = len(r.TransferEncoding) > 0
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	payload := strings.Repeat(`{"id":1}`, 20000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.json"), []byte(payload), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.json"), []byte(`{"name":"{{name}}"}`), 0644))
+
+	resolver := func(s string) string { return strings.ReplaceAll(s, "{{name}}", "Ada") }
+	build := func(file string) *Request {
+		return BuildRequestFromASTWithBaseDir(&parser.Request{
+			Method:   "POST",
+			URL:      server.URL,
+			Metadata: &parser.RequestMetadata{BodyFile: file},
+		}, resolver, dir)
+	}
+
+	t.Run("streams files without variables", func(t *testing.T) {
+		req := build("large.json")
+		assert.Equal(t, "large.json", req.BodyFile)
+		assert.Empty(t, req.Body)
+
+		_, err := NewClient().Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, payload, gotBody)
+		assert.Equal(t, int64(len(payload)), gotLength)
+		assert.False(t, chunked)
+		assert.Equal(t, "application/json", gotType)
+	})
+
+	t.Run("interpolates templated files", func(t *testing.T) {
+		req := build("user.json")
+		assert.Empty(t, req.BodyFile)
+		assert.Equal(t, `{"name":"Ada"}`, req.Body)
+	})
+
+	t.Run("rejects paths outside the base directory", func(t *testing.T) {
+		_, err := NewClient().Do(build("../secret.json"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "path traversal")
+	})
+}
+
+func TestBodyPartials(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "partials"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials", "address.json"), []byte(`{"city": "{{city}}", "geo": {{> geo.json}}}`+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials", "geo.json"), []byte(`[1, 2]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{{> b.json}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{{> a.json}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.json"), []byte(`{"ship": {{> ./partials/address.json}}}`), 0644))
+
+	resolver := func(s string) string { return strings.ReplaceAll(s, "{{city}}", "Lisbon") }
+	build := func(raw string, metadata *parser.RequestMetadata) *Request {
+		return BuildRequestFromASTWithBaseDir(&parser.Request{
+			Method:   "POST",
+			URL:      "http://example.com",
+			Body:     &parser.Body{ContentType: parser.BodyJSON, Raw: raw},
+			Metadata: metadata,
+		}, resolver, dir)
+	}
+
+	t.Run("inlines and interpolates partials", func(t *testing.T) {
+		req := build(`{"name": "Ada", "address": {{> ./partials/address.json}}}`, nil)
+		require.NoError(t, req.buildErr)
+		assert.Equal(t, `{"name": "Ada", "address": {"city": "Lisbon", "geo": [1, 2]}}`, req.Body)
+	})
+
+	t.Run("expands partials in body files", func(t *testing.T) {
+		req := build("", &parser.RequestMetadata{BodyFile: "order.json"})
+		require.NoError(t, req.buildErr)
+		assert.Equal(t, `{"ship": {"city": "Lisbon", "geo": [1, 2]}}`, req.Body)
+	})
+
+	t.Run("rejects paths outside the base directory", func(t *testing.T) {
+		_, err := NewClient().Do(build(`{{> ../secret.json}}`, nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "path traversal")
+	})
+
+	t.Run("detects cycles", func(t *testing.T) {
+		_, err := NewClient().Do(build(`{{> a.json}}`, nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "partial include cycle: a.json -> b.json -> a.json")
+	})
+
+	t.Run("reports missing partials", func(t *testing.T) {
+		_, err := NewClient().Do(build(`{{> missing.json}}`, nil))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "partial missing.json")
+	})
+}
+
+func TestClient_DecompressesResponse(t *testing.T) {
+	body := strings.Repeat(`{"name":"widget"},`, 100)
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		if r.URL.Path == "/plain" {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(body))
+		_ = zw.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.Get(server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", acceptEncoding)
+	assert.Equal(t, body, resp.BodyString())
+	assert.Equal(t, "gzip", resp.Encoding)
+	assert.Less(t, resp.CompressionRatio(), 0.3)
+
+	resp, err = client.Get(server.URL+"/plain", nil)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Encoding)
+	assert.Equal(t, 1.0, resp.CompressionRatio())
+}
+
+func TestClient_DecodesResponseCharset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=ISO-8859-1")
+		// "café" in latin-1
+		_, _ = w.Write([]byte{'{', '"', 'n', '"', ':', '"', 'c', 'a', 'f', 0xe9, '"', '}'})
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.Get(server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"n":"café"}`, resp.BodyString())
+	assert.Equal(t, []byte{'{', '"', 'n', '"', ':', '"', 'c', 'a', 'f', 0xe9, '"', '}'}, resp.Raw())
+}
+
+func TestBuildRequest_EncodesQueryVariables(t *testing.T) {
+	vars := map[string]string{
+		"{{baseUrl}}": "https://api.example.com/v1",
+		"{{term}}":    "fish & chips=yes",
+		"{{id}}":      "a b",
+		"{{$uuid}}":   "1234",
+	}
+	resolver := func(s string) string {
+		for k, v := range vars {
+			s = strings.ReplaceAll(s, k, v)
+		}
+		return s
+	}
+
+	r := BuildRequestFromAST(&parser.Request{
+		Method: "GET",
+		URL:    "{{baseUrl}}/items/{{id}}?q={{term}}&req={{$uuid}}&missing={{nope}}",
+	}, resolver)
+
+	assert.Equal(t, "https://api.example.com/v1/items/a b?q=fish+%26+chips%3Dyes&req=1234&missing={{nope}}", r.URL)
+}
+
+type connCounter struct {
+	opened, closed, fresh, reused int
+}
+
+func (c *connCounter) ConnOpened() { c.opened++ }
+func (c *connCounter) ConnClosed() { c.closed++ }
+func (c *connCounter) ConnUsed(reused bool) {
+	if reused {
+		c.reused++
+	} else {
+		c.fresh++
+	}
+}
+
+func TestClient_Timing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.Get(server.URL, nil)
+	require.NoError(t, err)
+	assert.Greater(t, resp.Timing.Connect, time.Duration(0))
+	assert.GreaterOrEqual(t, resp.Timing.TTFB, 20*time.Millisecond)
+
+	// A reused connection skips connecting
+	resp, err = client.Get(server.URL, nil)
+	require.NoError(t, err)
+	assert.Zero(t, resp.Timing.Connect)
+	assert.GreaterOrEqual(t, resp.Timing.TTFB, 20*time.Millisecond)
+}
+
+func TestClient_ConnObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	counter := &connCounter{}
+	client := NewClient(WithConnObserver(counter))
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(server.URL, nil)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, counter.opened)
+	assert.Equal(t, 1, counter.fresh)
+	assert.Equal(t, 2, counter.reused)
+
+	client.httpClient.CloseIdleConnections()
+	assert.Eventually(t, func() bool { return counter.closed == 1 }, time.Second, 10*time.Millisecond)
+}
+
 func TestClient_FollowRedirects(t *testing.T) {
 	redirectCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -114,6 +673,80 @@ func TestClient_FollowRedirects(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 	assert.Equal(t, "final", resp.BodyString())
 	assert.Equal(t, 1, redirectCount)
+	assert.Equal(t, []string{server.URL + "/redirect"}, resp.Redirects)
+	assert.Equal(t, server.URL+"/final", resp.FinalURL)
+}
+
+func TestClient_RedirectChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/login", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	resp, err := client.Get(server.URL+"/a", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/a", server.URL + "/b"}, resp.Redirects)
+	assert.Equal(t, server.URL+"/login", resp.FinalURL)
+}
+
+func TestClient_RequestMaxRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	// A per-request limit overrides the client's
+	for limit, status := range map[int]int{0: http.StatusFound, 2: http.StatusOK} {
+		req := NewRequest("GET", server.URL+"/a")
+		req.MaxRedirects = &limit
+		resp, err := NewClient(WithMaxRedirects(1)).Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, "limit %d", limit)
+		assert.Len(t, resp.Redirects, limit)
+	}
+}
+
+func TestClient_RequestFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// A per-request setting overrides the client's, either way
+	for _, clientFollows := range []bool{true, false} {
+		for _, follow := range []bool{true, false} {
+			req := NewRequest("GET", server.URL+"/login")
+			req.FollowRedirects = &follow
+			resp, err := NewClient(WithFollowRedirects(clientFollows)).Do(req)
+			require.NoError(t, err)
+
+			want := http.StatusFound
+			if follow {
+				want = http.StatusOK
+			}
+			assert.Equal(t, want, resp.StatusCode, "client %v, request %v", clientFollows, follow)
+		}
+	}
 }
 
 func TestClient_NoFollowRedirects(t *testing.T) {
@@ -288,3 +921,44 @@ func TestResponse_IsJSON(t *testing.T) {
 		assert.Equal(t, tt.expected, resp.IsJSON(), "Content-Type: %s", tt.contentType)
 	}
 }
+
+func TestResponse_JSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		ndjson      bool
+		body        string
+		expected    string
+	}{
+		{"json unchanged", "application/json", false, `{"a":1}`, `{"a":1}`},
+		{"ndjson", "application/x-ndjson", false, "{\"a\":1}\n\n{\"a\":2}\n", `[{"a":1},{"a":2}]`},
+		{"jsonl with charset", "application/jsonl; charset=utf-8", false, "1\r\n\"two\"", `[1,"two"]`},
+		{"json-seq", "application/json-seq", false, "\x1e{\"a\":1}\n\x1e{\"a\":2}\n", `[{"a":1},{"a":2}]`},
+		{"annotation", "text/plain", true, "{\"a\":1}\n{\"a\":2}", `[{"a":1},{"a":2}]`},
+		{"empty", "application/x-ndjson", false, "", `[]`},
+		{"invalid line", "application/x-ndjson", false, "{\"a\":1}\nnot json", "{\"a\":1}\nnot json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{Headers: map[string]string{"Content-Type": tt.contentType}, Body: []byte(tt.body), NDJSON: tt.ndjson}
+			assert.Equal(t, tt.expected, string(resp.JSONBody()))
+		})
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := server.URL
+	server.Close()
+
+	_, err := NewClient().Get(closedURL, nil)
+	require.Error(t, err)
+	assert.True(t, IsNetworkError(err), "connection refused: %v", err)
+
+	_, err = NewClient().Get("ftp://example.com/file", nil)
+	require.Error(t, err)
+	assert.False(t, IsNetworkError(err), "invalid scheme: %v", err)
+
+	assert.False(t, IsNetworkError(nil))
+}
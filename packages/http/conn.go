@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// ConnObserver is notified about the connections a Client opens and uses, e.g.
+// to tell whether keep-alive connections are being reused
+type ConnObserver interface {
+	ConnOpened()
+	ConnClosed()
+	ConnUsed(reused bool)
+}
+
+// WithConnObserver reports connection events to o
+func WithConnObserver(o ConnObserver) ClientOption {
+	return func(c *Client) {
+		c.connObserver = o
+	}
+}
+
+// SetConnObserver reports connection events of an existing client to o. It
+// must be called before the client sends requests.
+func (c *Client) SetConnObserver(o ConnObserver) {
+	c.connObserver = o
+}
+
+// dialContext dials like the default transport and reports opened and closed
+// connections to the client's observer
+func (c *Client) dialContext() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil || c.connObserver == nil {
+			return conn, err
+		}
+		observer := c.connObserver
+		observer.ConnOpened()
+		return &observedConn{Conn: conn, onClose: observer.ConnClosed}, nil
+	}
+}
+
+// observedConn calls onClose once when the connection is closed
+type observedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *observedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
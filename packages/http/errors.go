@@ -0,0 +1,37 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// IsNetworkError reports whether err is a transient transport failure, such
+// as a refused or reset connection, a DNS failure or a timeout, rather than a
+// problem with the request itself like an invalid URL
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	// *url.Error implements net.Error itself, so look at what it wraps
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,350 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FixtureMode selects whether a client records responses to a fixtures
+// directory or replays them from it instead of using the network
+type FixtureMode int
+
+const (
+	FixtureRecord FixtureMode = iota + 1
+	FixtureReplay
+)
+
+// Fixture is a recorded response, stored as JSON in the fixtures directory
+type Fixture struct {
+	Method     string              `json:"method"`
+	URL        string              `json:"url"`
+	BodySHA256 string              `json:"bodySha256,omitempty"` // Hash of the request body
+	Status     int                 `json:"status"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body,omitempty"`
+	BodyBase64 string              `json:"bodyBase64,omitempty"` // Set instead of Body when the body isn't UTF-8
+}
+
+// WithFixtures records every response to dir (FixtureRecord), or serves
+// responses from dir without touching the network (FixtureReplay). Responses
+// are keyed by method, URL and a hash of the request body (see
+// WithFixtureIgnore); the same request sent again within a run is recorded and
+// replayed in order.
+func WithFixtures(mode FixtureMode, dir string) ClientOption {
+	return func(c *Client) {
+		c.fixtureMode = mode
+		c.fixtureDir = dir
+	}
+}
+
+// WithFixtureIgnore leaves parts of a request out of its fixture key, so
+// requests carrying values that change between runs, such as $uuid() or
+// $timestamp(), still replay. Each field is "body" for the whole body,
+// "body.<path>" for a field of a JSON body (e.g. body.meta.requestId), or
+// "query.<name>" for a query parameter.
+func WithFixtureIgnore(fields ...string) ClientOption {
+	return func(c *Client) {
+		c.fixtureIgnore = append(c.fixtureIgnore, fields...)
+	}
+}
+
+// ValidFixtureIgnore reports whether field is a WithFixtureIgnore field
+func ValidFixtureIgnore(field string) bool {
+	if field == "body" {
+		return true
+	}
+	for _, prefix := range []string{"body.", "query."} {
+		if strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fixtureTransport records or replays the responses of next
+type fixtureTransport struct {
+	next http.RoundTripper
+	mode FixtureMode
+	dir  string
+
+	// Left out of keys: the whole body, JSON body paths and query parameters
+	ignoreBody   bool
+	ignoreFields [][]string
+	ignoreQuery  []string
+
+	mu   sync.Mutex
+	seen map[string]int // Times each key was requested in this run
+}
+
+func newFixtureTransport(next http.RoundTripper, mode FixtureMode, dir string, ignore []string) *fixtureTransport {
+	t := &fixtureTransport{next: next, mode: mode, dir: dir, seen: make(map[string]int)}
+	for _, field := range ignore {
+		switch {
+		case field == "body":
+			t.ignoreBody = true
+		case strings.HasPrefix(field, "body."):
+			t.ignoreFields = append(t.ignoreFields, strings.Split(strings.TrimPrefix(field, "body."), "."))
+		case strings.HasPrefix(field, "query."):
+			t.ignoreQuery = append(t.ignoreQuery, strings.TrimPrefix(field, "query."))
+		}
+	}
+	return t
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	keyURL, keyBody := t.keyParts(req, body)
+	bodyHash := ""
+	if len(keyBody) > 0 {
+		sum := sha256.Sum256(keyBody)
+		bodyHash = hex.EncodeToString(sum[:])
+	}
+	key := fixtureKey(req.Method, keyURL, bodyHash)
+	n := t.occurrence(key)
+
+	if t.mode == FixtureReplay {
+		return t.replay(req, key, n)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		BodySHA256: bodyHash,
+		Status:     resp.StatusCode,
+		Headers:    resp.Header,
+	}
+	if utf8.Valid(data) {
+		fixture.Body = string(data)
+	} else {
+		fixture.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	if err := writeFixture(filepath.Join(t.dir, fixtureFile(key, n)), fixture); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// keyParts returns the URL and body of req that its fixture key is built
+// from, without the parts the transport ignores
+func (t *fixtureTransport) keyParts(req *http.Request, body []byte) (string, []byte) {
+	url := req.URL.String()
+	if len(t.ignoreQuery) > 0 && req.URL.RawQuery != "" {
+		u := *req.URL
+		query := u.Query()
+		for _, name := range t.ignoreQuery {
+			query.Del(name)
+		}
+		u.RawQuery = query.Encode()
+		url = u.String()
+	}
+
+	if t.ignoreBody {
+		return url, nil
+	}
+	if len(t.ignoreFields) == 0 || !json.Valid(body) {
+		return url, body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return url, body
+	}
+	for _, path := range t.ignoreFields {
+		deleteJSONPath(value, path)
+	}
+	stripped, err := json.Marshal(value)
+	if err != nil {
+		return url, body
+	}
+	return url, stripped
+}
+
+// deleteJSONPath removes the value at path from a decoded JSON value. Path
+// elements are object keys or array indexes; missing paths are ignored.
+func deleteJSONPath(value any, path []string) {
+	for i, elem := range path {
+		last := i == len(path)-1
+		switch v := value.(type) {
+		case map[string]any:
+			if last {
+				delete(v, elem)
+				return
+			}
+			value = v[elem]
+		case []any:
+			idx, err := strconv.Atoi(elem)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return
+			}
+			if last {
+				v[idx] = nil
+				return
+			}
+			value = v[idx]
+		default:
+			return
+		}
+	}
+}
+
+// occurrence counts the requests with key sent so far in this run, including
+// this one
+func (t *fixtureTransport) occurrence(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen[key]++
+	return t.seen[key]
+}
+
+// replay serves the recorded response of the nth request with key. A request
+// sent more often than it was recorded gets the first recording again.
+func (t *fixtureTransport) replay(req *http.Request, key string, n int) (*http.Response, error) {
+	path := filepath.Join(t.dir, fixtureFile(key, n))
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && n > 1 {
+		path = filepath.Join(t.dir, fixtureFile(key, 1))
+		data, err = os.ReadFile(path)
+	}
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded fixture for %s %s in %s (record it with --record)", req.Method, req.URL, t.dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	body := []byte(fixture.Body)
+	if fixture.BodyBase64 != "" {
+		if body, err = base64.StdEncoding.DecodeString(fixture.BodyBase64); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+	}
+
+	header := http.Header(fixture.Headers)
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// fixtureKey names the fixture of a request: the method and a readable part
+// of the URL, followed by a hash of the method, full URL and body hash that
+// tells apart requests differing only in query or body
+func fixtureKey(method, url, bodyHash string) string {
+	sum := sha256.Sum256([]byte(method + " " + url + "\n" + bodyHash))
+	readable := strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+	readable, _, _ = strings.Cut(readable, "?")
+	readable = strings.Trim(unsafeFileChars.ReplaceAllString(readable, "_"), "_")
+	if len(readable) > 60 {
+		readable = readable[:60]
+	}
+	return fmt.Sprintf("%s_%s_%s", strings.ToUpper(method), readable, hex.EncodeToString(sum[:])[:12])
+}
+
+// fixtureFile returns the file of the nth request with key: key.json for the
+// first, key-2.json, key-3.json... for the ones after it
+func fixtureFile(key string, n int) string {
+	if n > 1 {
+		return fmt.Sprintf("%s-%d.json", key, n)
+	}
+	return key + ".json"
+}
+
+// requestBody returns the body of req without consuming it
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// writeFixture writes fixture to path through a temporary file, so runs
+// recording the same fixture concurrently (--parallel-files) never leave a
+// partial file behind. Fixtures may hold tokens, so only the owner can read
+// them.
+func writeFixture(path string, fixture *Fixture) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".fixture-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
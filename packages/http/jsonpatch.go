@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+)
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	From  string          `json:"from,omitempty"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// buildJSONPatch renders the operations of a patch block as a JSON Patch
+// document. Values that aren't valid JSON once resolved, such as a bare word,
+// are sent as strings.
+func buildJSONPatch(ops []*parser.PatchOperation, resolver func(string) string) string {
+	doc := make([]jsonPatchOperation, 0, len(ops))
+	for _, op := range ops {
+		out := jsonPatchOperation{
+			Op:   op.Op,
+			From: resolver(op.From),
+			Path: resolver(op.Path),
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			value := resolver(op.Value)
+			if json.Valid([]byte(value)) {
+				out.Value = json.RawMessage(value)
+			} else {
+				out.Value, _ = json.Marshal(value)
+			}
+		}
+		doc = append(doc, out)
+	}
+	data, _ := json.Marshal(doc)
+	return string(data)
+}
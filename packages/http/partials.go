@@ -0,0 +1,73 @@
+package http
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// partialPattern matches a {{> path}} include in a body
+var partialPattern = regexp.MustCompile(`\{\{>\s*([^}]+?)\s*\}\}`)
+
+// expandPartials replaces each {{> path}} in body with the contents of the
+// file at path, which is relative to dir. Partials may include partials of
+// their own, relative to their directory. Paths may not escape baseDir, and
+// a partial including itself, directly or not, is an error. Variables are
+// left for the caller to resolve, in the body and the partials alike.
+func expandPartials(body, dir, baseDir string) (string, error) {
+	return expandPartialsFrom(body, dir, baseDir, nil)
+}
+
+func expandPartialsFrom(body, dir, baseDir string, stack []string) (string, error) {
+	if !strings.Contains(body, "{{>") {
+		return body, nil
+	}
+
+	var expandErr error
+	expanded := partialPattern.ReplaceAllStringFunc(body, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		path := partialPattern.FindStringSubmatch(match)[1]
+		content, err := readPartial(path, dir, baseDir, stack)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+		return content
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
+
+// readPartial returns the expanded contents of the partial at path
+func readPartial(path, dir, baseDir string, stack []string) (string, error) {
+	filePath := partFilePath(path, dir)
+	if err := validatePathWithinBase(filePath, baseDir); err != nil {
+		return "", fmt.Errorf("partial %s: %w", path, err)
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("partial %s: %w", path, err)
+	}
+	for i, included := range stack {
+		if included == absPath {
+			cycle := append(append([]string{}, stack[i:]...), absPath)
+			for j := range cycle {
+				cycle[j] = filepath.Base(cycle[j])
+			}
+			return "", fmt.Errorf("partial include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("partial %s: %w", path, err)
+	}
+	content := strings.TrimRight(string(data), "\r\n")
+	return expandPartialsFrom(content, filepath.Dir(filePath), baseDir, append(stack, absPath))
+}
@@ -2,26 +2,34 @@ package http
 
 import (
 	"encoding/base64"
+	"mime"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 type Request struct {
-	Method      string
-	URL         string
-	Headers     map[string]string
-	Body        string
-	Timeout     time.Duration
-	Auth        *parser.AuthConfig
-	QueryParams map[string]string
-	Multipart   []*parser.MultipartField
-	BaseDir     string // Base directory for resolving relative file paths
-	DigestAuth  *DigestAuthCredentials
-	AWSAuth     *AWSAuthCredentials
-	OAuth2Auth  *OAuth2AuthCredentials
+	Method          string
+	URL             string
+	Headers         map[string]string
+	Body            string
+	BodyFile        string // File streamed as the body instead of Body (@body-file)
+	Timeout         time.Duration
+	MaxRedirects    *int  // Overrides the client's redirect limit for this request
+	FollowRedirects *bool // Overrides whether the client follows redirects for this request
+	Auth            *parser.AuthConfig
+	QueryParams     map[string]string
+	Multipart       []*parser.MultipartField
+	BaseDir         string // Base directory for resolving relative file paths
+	DigestAuth      *DigestAuthCredentials
+	AWSAuth         *AWSAuthCredentials
+	OAuth2Auth      *OAuth2AuthCredentials
+	buildErr        error // Why the body couldn't be built, such as a missing partial; returned by Do
 }
 
 // OAuth2AuthCredentials holds OAuth2 authentication configuration
@@ -52,6 +60,12 @@ func NewRequest(method, requestURL string) *Request {
 	}
 }
 
+// BuildErr returns why the request couldn't be built, such as a missing body
+// partial, or nil. Do returns it without sending the request.
+func (r *Request) BuildErr() error {
+	return r.buildErr
+}
+
 func (r *Request) SetHeader(key, value string) *Request {
 	r.Headers[key] = value
 	return r
@@ -165,12 +179,74 @@ func (r *Request) ApplyAuth() {
 	}
 }
 
+// resolveURL resolves the variables of a request URL. Variables in the query
+// string are percent-encoded so values with spaces, & or = can't corrupt it;
+// built-in functions ({{$...}}) and unresolved variables are left as is.
+func resolveURL(raw string, resolver func(string) string) string {
+	queryStart := -1
+	depth := 0
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case strings.HasPrefix(raw[i:], "{{"):
+			depth++
+			i++
+		case strings.HasPrefix(raw[i:], "}}") && depth > 0:
+			depth--
+			i++
+		case raw[i] == '?' && depth == 0:
+			queryStart = i
+		}
+		if queryStart >= 0 {
+			break
+		}
+	}
+	if queryStart < 0 {
+		return resolver(raw)
+	}
+
+	query := env.ReplaceVariables(raw[queryStart:], func(match string) string {
+		name := strings.TrimSpace(match[2 : len(match)-2])
+		resolved := resolver(match)
+		if strings.HasPrefix(name, "$") || resolved == match {
+			return resolved
+		}
+		return url.QueryEscape(resolved)
+	})
+	return resolver(raw[:queryStart]) + query
+}
+
+// encodeBody transcodes the UTF-8 body to the named charset and declares it
+// in the Content-Type header (text/plain when none is set). Characters the
+// charset can't represent are replaced. Unknown charsets leave the body as is.
+func (r *Request) encodeBody(charset string) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return
+	}
+	encoded, err := encoding.ReplaceUnsupported(enc.NewEncoder()).String(r.Body)
+	if err != nil {
+		return
+	}
+	r.Body = encoded
+
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		name = charset
+	}
+	mediaType, params, err := mime.ParseMediaType(r.Headers["Content-Type"])
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	params["charset"] = name
+	r.SetHeader("Content-Type", mime.FormatMediaType(mediaType, params))
+}
+
 func BuildRequestFromAST(req *parser.Request, resolver func(string) string) *Request {
 	return BuildRequestFromASTWithBaseDir(req, resolver, "")
 }
 
 func BuildRequestFromASTWithBaseDir(req *parser.Request, resolver func(string) string, baseDir string) *Request {
-	r := NewRequest(req.Method, resolver(req.URL))
+	r := NewRequest(req.Method, resolveURL(req.URL, resolver))
 	r.BaseDir = baseDir
 
 	for _, h := range req.Headers {
@@ -186,17 +262,39 @@ func BuildRequestFromASTWithBaseDir(req *parser.Request, resolver func(string) s
 			// Handle multipart form data
 			resolvedFields := make([]*parser.MultipartField, len(req.Body.Multipart))
 			for i, field := range req.Body.Multipart {
-				resolvedFields[i] = &parser.MultipartField{
-					Type:  field.Type,
-					Name:  field.Name,
-					Value: resolver(field.Value),
-					Path:  resolver(field.Path),
+				resolved := &parser.MultipartField{
+					Type:        field.Type,
+					Name:        field.Name,
+					Value:       resolver(field.Value),
+					Path:        resolver(field.Path),
+					ContentType: resolver(field.ContentType),
+					Filename:    resolver(field.Filename),
 				}
+				// Interpolate variables in a value read from a file. If the file
+				// can't be read, the path is kept so building the body reports it.
+				if resolved.Type == parser.MultipartFieldValue && resolved.Path != "" {
+					if data, err := readPartFile(resolved.Path, baseDir); err == nil {
+						resolved.Value = resolver(string(data))
+						resolved.Path = ""
+					}
+				}
+				resolvedFields[i] = resolved
 			}
 			r.Multipart = resolvedFields
 			// Content-Type will be set by the client when building the multipart body
+		} else if req.Body.ContentType == parser.BodyStdin {
+			// The runner sets the body it reads from stdin
+		} else if req.Body.ContentType == parser.BodyJSONPatch {
+			r.SetBody(buildJSONPatch(req.Body.Patch, resolver))
+			if r.Headers["Content-Type"] == "" {
+				r.SetHeader("Content-Type", "application/json-patch+json")
+			}
 		} else {
-			body := resolver(req.Body.Raw)
+			raw, err := expandPartials(req.Body.Raw, baseDir, baseDir)
+			if err != nil {
+				r.buildErr = err
+			}
+			body := resolver(raw)
 			r.SetBody(body)
 
 			if req.Body.ContentType == parser.BodyJSON && r.Headers["Content-Type"] == "" {
@@ -204,9 +302,17 @@ func BuildRequestFromASTWithBaseDir(req *parser.Request, resolver func(string) s
 			} else if (req.Body.ContentType == parser.BodyForm || req.Body.ContentType == parser.BodyFormBlock) && r.Headers["Content-Type"] == "" {
 				r.SetHeader("Content-Type", "application/x-www-form-urlencoded")
 			}
+
+			if req.Metadata != nil && req.Metadata.Encoding != "" {
+				r.encodeBody(req.Metadata.Encoding)
+			}
 		}
 	}
 
+	if req.Metadata != nil && req.Metadata.BodyFile != "" {
+		r.setBodyFile(resolver(req.Metadata.BodyFile), resolver, req.Metadata.Encoding)
+	}
+
 	if req.Metadata != nil && req.Metadata.Auth != nil {
 		auth := &parser.AuthConfig{
 			Type:   req.Metadata.Auth.Type,
@@ -220,7 +326,14 @@ func BuildRequestFromASTWithBaseDir(req *parser.Request, resolver func(string) s
 	}
 
 	if req.Metadata != nil && req.Metadata.Timeout > 0 {
-		r.SetTimeout(time.Duration(req.Metadata.Timeout) * time.Millisecond)
+		r.SetTimeout(req.Metadata.Timeout)
+	}
+
+	if req.Metadata != nil && req.Metadata.FollowRedirects != nil {
+		r.FollowRedirects = req.Metadata.FollowRedirects
+	}
+	if req.Metadata != nil && req.Metadata.MaxRedirects != nil {
+		r.MaxRedirects = req.Metadata.MaxRedirects
 	}
 
 	r.URL = r.BuildURL()
@@ -1,38 +1,160 @@
 package http
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 type Response struct {
-	StatusCode int
-	Status     string
-	Headers    map[string]string
-	Body       []byte
-	Duration   time.Duration
+	StatusCode   int
+	Status       string
+	Headers      map[string]string
+	MultiHeaders map[string][]string // All values of each header, in received order
+	Body         []byte
+	RawBody      []byte // Body before charset decoding, after decompression
+	Encoding     string // Content-Encoding of the body, such as gzip; gzip and deflate are decompressed
+	WireSize     int    // Body size as received, before decompression
+	Duration     time.Duration
+	Timing       Timing   // Phase breakdown of the request
+	Redirects    []string // URLs that answered with a followed redirect, in order
+	FinalURL     string   // URL of the request that produced this response
+	NDJSON       bool     // Body is newline-delimited JSON whatever its content type (@ndjson)
+}
+
+// decodeBody converts a body in the charset declared by contentType to UTF-8.
+// Bodies without a charset, in UTF-8, or in an unknown charset are returned
+// unchanged.
+func decodeBody(body []byte, contentType string) []byte {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return body
+	}
+	enc, err := htmlindex.Get(params["charset"])
+	if err != nil {
+		return body
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return body
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// decompressBody decodes a body sent with a gzip or deflate Content-Encoding.
+// It reports false, leaving the body alone, for other encodings or a body
+// that doesn't decode.
+func decompressBody(body []byte, encoding string) ([]byte, bool) {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return body, false
+		}
+		reader = zr
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send it raw
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			reader = zr
+		} else {
+			reader = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return body, false
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return body, false
+	}
+	return decoded, true
 }
 
 func (r *Response) BodyString() string {
 	return string(r.Body)
 }
 
+// Raw returns the body exactly as received, before charset decoding
+func (r *Response) Raw() []byte {
+	if r.RawBody != nil {
+		return r.RawBody
+	}
+	return r.Body
+}
+
+// CompressionRatio returns the size of the body as received divided by its
+// decompressed size: 1 for a body that wasn't decompressed, and lower the
+// better it compressed
+func (r *Response) CompressionRatio() float64 {
+	size := len(r.Raw())
+	if r.WireSize == 0 || size == 0 {
+		return 1
+	}
+	return float64(r.WireSize) / float64(size)
+}
+
 func (r *Response) BodyJSON() (any, error) {
 	var result any
-	if err := json.Unmarshal(r.Body, &result); err != nil {
+	if err := json.Unmarshal(r.JSONBody(), &result); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
 func (r *Response) Header(key string) string {
+	v, _ := r.LookupHeader(key)
+	return v
+}
+
+// LookupHeader returns the value of a header (case-insensitive) and whether
+// the response has it, telling an empty header apart from a missing one
+func (r *Response) LookupHeader(key string) (string, bool) {
 	for k, v := range r.Headers {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// HeaderValues returns every value of a header (case-insensitive). It falls
+// back to the single value in Headers when multi-value headers weren't retained.
+func (r *Response) HeaderValues(key string) []string {
+	for k, v := range r.MultiHeaders {
 		if strings.EqualFold(k, key) {
 			return v
 		}
 	}
-	return ""
+	if v := r.Header(key); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+// Cookie returns the value of the cookie set by the response with the given
+// name. When a cookie is set more than once, the last value wins.
+func (r *Response) Cookie(name string) (string, bool) {
+	resp := &http.Response{Header: http.Header{"Set-Cookie": r.HeaderValues("Set-Cookie")}}
+	value, found := "", false
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			value, found = c.Value, true
+		}
+	}
+	return value, found
 }
 
 func (r *Response) ContentType() string {
@@ -44,6 +166,50 @@ func (r *Response) IsJSON() bool {
 	return strings.Contains(ct, "application/json")
 }
 
+// ndjsonTypes are the content types of newline-delimited JSON bodies
+var ndjsonTypes = []string{"application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines", "application/json-seq"}
+
+// IsNDJSON reports whether the body is newline-delimited JSON, one value per
+// line, by its content type or because the request was marked @ndjson
+func (r *Response) IsNDJSON() bool {
+	if r.NDJSON {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(r.ContentType())
+	if err != nil {
+		return false
+	}
+	return slices.Contains(ndjsonTypes, strings.ToLower(mediaType))
+}
+
+// JSONBody returns the body as a single JSON document. An NDJSON body becomes
+// an array of its lines; it's returned unchanged when a line isn't valid JSON.
+func (r *Response) JSONBody() []byte {
+	if !r.IsNDJSON() {
+		return r.Body
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	n := 0
+	for line := range bytes.Lines(r.Body) {
+		// application/json-seq prefixes each value with a record separator
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte{0x1e}))
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return r.Body
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(line)
+		n++
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
 func (r *Response) IsSuccess() bool {
 	return r.StatusCode >= 200 && r.StatusCode < 300
 }
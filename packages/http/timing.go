@@ -0,0 +1,100 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks a request down into phases. Phases that didn't happen, such
+// as DNS and connect on a reused connection, are zero; with redirects, each
+// phase is summed over every request in the chain.
+type Timing struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration // Request written to first response byte
+	Transfer time.Duration // First response byte to the end of the body
+}
+
+// timingTrace records phase timings through httptrace. Dials can run
+// concurrently, so hooks are guarded by a mutex.
+type timingTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	timing       Timing
+}
+
+// clientTrace returns the hooks recording t, also reporting connection use
+// to observer when it isn't nil
+func (t *timingTrace) clientTrace(observer ConnObserver) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.DNS += since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connectStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			t.timing.Connect += since(t.connectStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.TLS += since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if observer != nil {
+				observer.ConnUsed(info.Reused)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			t.wroteRequest = time.Now()
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.timing.TTFB += since(t.wroteRequest)
+			t.mu.Unlock()
+		},
+	}
+}
+
+// finish returns the timings once the body has been read
+func (t *timingTrace) finish() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timing.Transfer = since(t.firstByte)
+	return t.timing
+}
+
+// since is time.Since that ignores phases whose start wasn't seen
+func since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
@@ -75,6 +75,15 @@ func NewConverter(opts ...Option) *Converter {
 
 // ConvertFile converts an OpenAPI file to hitspec format
 func (c *Converter) ConvertFile(path string) (string, error) {
+	doc, err := LoadSpec(path)
+	if err != nil {
+		return "", err
+	}
+	return c.Convert(doc)
+}
+
+// LoadSpec loads an OpenAPI spec from a file path or URL
+func LoadSpec(path string) (*openapi3.T, error) {
 	loader := openapi3.NewLoader()
 	loader.IsExternalRefsAllowed = true
 
@@ -86,21 +95,21 @@ func (c *Converter) ConvertFile(path string) (string, error) {
 		doc, err = loader.LoadFromURI(&url.URL{Scheme: "https", Host: strings.TrimPrefix(strings.TrimPrefix(path, "https://"), "http://"), Path: ""})
 		if err != nil {
 			// Try loading from URL directly
-			doc, err = c.loadFromURL(path)
+			doc, err = loadFromURL(path)
 		}
 	} else {
 		doc, err = loader.LoadFromFile(path)
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("failed to load OpenAPI spec: %w", err)
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
 	}
 
-	return c.Convert(doc)
+	return doc, nil
 }
 
 // loadFromURL loads an OpenAPI spec from a URL
-func (c *Converter) loadFromURL(urlStr string) (*openapi3.T, error) {
+func loadFromURL(urlStr string) (*openapi3.T, error) {
 	resp, err := http.Get(urlStr)
 	if err != nil {
 		return nil, err
@@ -186,24 +195,7 @@ func (c *Converter) Convert(doc *openapi3.T) (string, error) {
 			continue
 		}
 
-		operations := []struct {
-			method string
-			op     *openapi3.Operation
-		}{
-			{"GET", pathItem.Get},
-			{"POST", pathItem.Post},
-			{"PUT", pathItem.Put},
-			{"PATCH", pathItem.Patch},
-			{"DELETE", pathItem.Delete},
-			{"HEAD", pathItem.Head},
-			{"OPTIONS", pathItem.Options},
-		}
-
-		for _, op := range operations {
-			if op.op == nil {
-				continue
-			}
-
+		for _, op := range pathOperations(pathItem) {
 			if !c.shouldInclude(op.op) {
 				continue
 			}
@@ -217,6 +209,33 @@ func (c *Converter) Convert(doc *openapi3.T) (string, error) {
 	return sb.String(), nil
 }
 
+// pathOperation is an operation of a path item with its HTTP method
+type pathOperation struct {
+	method string
+	op     *openapi3.Operation
+}
+
+// pathOperations returns the operations a path item defines, in a fixed
+// method order
+func pathOperations(item *openapi3.PathItem) []pathOperation {
+	all := []pathOperation{
+		{"GET", item.Get},
+		{"POST", item.Post},
+		{"PUT", item.Put},
+		{"PATCH", item.Patch},
+		{"DELETE", item.Delete},
+		{"HEAD", item.Head},
+		{"OPTIONS", item.Options},
+	}
+	operations := all[:0]
+	for _, op := range all {
+		if op.op != nil {
+			operations = append(operations, op)
+		}
+	}
+	return operations
+}
+
 func (c *Converter) getBaseURL(doc *openapi3.T) string {
 	if len(doc.Servers) > 0 && doc.Servers[0].URL != "" {
 		return doc.Servers[0].URL
@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// MockRoute is an operation of a spec with the example response a mock
+// server answers it with
+type MockRoute struct {
+	Method      string
+	Path        string // Path with {{param}} parameters, under the base path of the spec's server
+	Name        string
+	Status      int
+	ContentType string
+	Body        string
+}
+
+// pathParam matches the {param} parameters of an OpenAPI path
+var pathParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// nonWord matches what can't be part of a mock path parameter name
+var nonWord = regexp.MustCompile(`\W`)
+
+// MockRoutes returns a route for each operation of doc the converter
+// includes. The response is the operation's first 2xx response, or its
+// default response, with the body from the media type's example, its first
+// named example, or generated from its schema.
+func (c *Converter) MockRoutes(doc *openapi3.T) []*MockRoute {
+	basePath := ""
+	if u, err := url.Parse(c.getBaseURL(doc)); err == nil {
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	paths := make([]string, 0, len(doc.Paths.Map()))
+	for path := range doc.Paths.Map() {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var routes []*MockRoute
+	for _, path := range paths {
+		pathItem := doc.Paths.Map()[path]
+		if pathItem == nil {
+			continue
+		}
+		mockPath := basePath + pathParam.ReplaceAllStringFunc(path, func(m string) string {
+			return "{{" + nonWord.ReplaceAllString(m[1:len(m)-1], "_") + "}}"
+		})
+
+		for _, op := range pathOperations(pathItem) {
+			if !c.shouldInclude(op.op) {
+				continue
+			}
+			route := &MockRoute{
+				Method: op.method,
+				Path:   mockPath,
+				Name:   op.op.OperationID,
+				Status: 200,
+			}
+			code, resp := mockResponse(op.op)
+			if code != 0 {
+				route.Status = code
+			}
+			if resp != nil {
+				route.ContentType, route.Body = c.exampleBody(resp)
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// mockResponse picks the response a mock serves for op: the lowest 2xx one,
+// else the default one. The status is 0 for the default response.
+func mockResponse(op *openapi3.Operation) (int, *openapi3.Response) {
+	if op.Responses == nil {
+		return 0, nil
+	}
+	best, response := 0, (*openapi3.Response)(nil)
+	for code, ref := range op.Responses.Map() {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		// A 2XX range counts as 200
+		n, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(code), "XX", "00"))
+		if err == nil && n >= 200 && n < 300 && (best == 0 || n < best) {
+			best, response = n, ref.Value
+		}
+	}
+	if response != nil {
+		return best, response
+	}
+	if ref := op.Responses.Default(); ref != nil && ref.Value != nil {
+		return 0, ref.Value
+	}
+	return 0, nil
+}
+
+// exampleBody returns the content type and example body of a response,
+// preferring a JSON media type
+func (c *Converter) exampleBody(resp *openapi3.Response) (string, string) {
+	if len(resp.Content) == 0 {
+		return "", ""
+	}
+	contentTypes := make([]string, 0, len(resp.Content))
+	for contentType := range resp.Content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.SliceStable(contentTypes, func(i, j int) bool {
+		return strings.Contains(contentTypes[i], "json") && !strings.Contains(contentTypes[j], "json")
+	})
+	contentType := contentTypes[0]
+	media := resp.Content[contentType]
+
+	example := media.Example
+	if example == nil && len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if ref := media.Examples[names[0]]; ref != nil && ref.Value != nil {
+			example = ref.Value.Value
+		}
+	}
+	if example != nil {
+		if s, ok := example.(string); ok && !strings.Contains(contentType, "json") {
+			return contentType, s
+		}
+		if data, err := json.MarshalIndent(example, "", "  "); err == nil {
+			return contentType, string(data)
+		}
+	}
+
+	if media.Schema != nil && media.Schema.Value != nil {
+		return contentType, c.generateJSONFromSchema(media.Schema.Value, 0)
+	}
+	return contentType, ""
+}
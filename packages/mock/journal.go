@@ -0,0 +1,131 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminPrefix is the path prefix of the endpoints for verifying the requests
+// a mock server received. Requests to them are not recorded.
+const AdminPrefix = "/__hitspec"
+
+// DefaultJournalSize is how many received requests a mock server keeps by
+// default
+const DefaultJournalSize = 10000
+
+// ReceivedRequest is a request recorded by the mock server
+type ReceivedRequest struct {
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Matched bool      `json:"matched"`
+	Time    time.Time `json:"time"`
+}
+
+// journal records the requests a mock server received. Once it holds size
+// requests, each new one replaces the oldest.
+type journal struct {
+	mu       sync.Mutex
+	size     int // Requests kept (0 keeps all)
+	requests []ReceivedRequest
+	oldest   int // Index of the oldest request once the journal is full
+}
+
+func (j *journal) record(req ReceivedRequest) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.size > 0 && len(j.requests) >= j.size {
+		j.requests[j.oldest] = req
+		j.oldest = (j.oldest + 1) % len(j.requests)
+		return
+	}
+	j.requests = append(j.requests, req)
+}
+
+// list returns a copy of the recorded requests, oldest first
+func (j *journal) list() []ReceivedRequest {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	requests := make([]ReceivedRequest, 0, len(j.requests))
+	requests = append(requests, j.requests[j.oldest:]...)
+	return append(requests, j.requests[:j.oldest]...)
+}
+
+// Verify returns how many received requests match method and path. An empty
+// method or * matches any method. The path may be a route pattern such as
+// /users/{{id}}, which matches any value of its parameters.
+func (s *Server) Verify(method, path string) int {
+	pathRegex := createPathRegex(normalizePath(path))
+
+	count := 0
+	for _, req := range s.journal.list() {
+		if method != "" && method != "*" && !strings.EqualFold(req.Method, method) {
+			continue
+		}
+		p := normalizePath(req.Path)
+		if p == path || pathRegex.MatchString(p) {
+			count++
+		}
+	}
+	return count
+}
+
+// Requests returns the requests received so far, oldest first
+func (s *Server) Requests() []ReceivedRequest {
+	return s.journal.list()
+}
+
+// Reset forgets the requests received so far
+func (s *Server) Reset() {
+	s.journal.mu.Lock()
+	defer s.journal.mu.Unlock()
+	s.journal.requests = nil
+	s.journal.oldest = 0
+}
+
+// handleAdmin serves the verification endpoints:
+//
+//	GET    /__hitspec/verify?method=POST&path=/payments  {"count": N, ...}
+//	GET    /__hitspec/requests                           the received requests
+//	DELETE /__hitspec/requests                           forget them
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	var result any
+	switch strings.TrimPrefix(r.URL.Path, AdminPrefix) {
+	case "/verify":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		path := query.Get("path")
+		if path == "" {
+			http.Error(w, "missing path parameter", http.StatusBadRequest)
+			return
+		}
+		result = map[string]any{
+			"method": query.Get("method"),
+			"path":   path,
+			"count":  s.Verify(query.Get("method"), path),
+		}
+	case "/requests":
+		switch r.Method {
+		case http.MethodGet:
+			result = s.Requests()
+		case http.MethodDelete:
+			s.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,110 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s := NewServer()
+	s.router.AddRoute(&Route{Method: "POST", PathPattern: "/payments", PathRegex: createPathRegex("/payments"), Response: &MockResponse{StatusCode: 201}})
+	s.router.AddRoute(&Route{Method: "GET", PathPattern: "/users/{{id}}", PathRegex: createPathRegex("/users/{{id}}"), Response: &MockResponse{StatusCode: 200}})
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func TestServer_Verify(t *testing.T) {
+	s, ts := newVerifyServer(t)
+
+	resp, err := http.Post(ts.URL+"/payments", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 1, s.Verify("POST", "/payments"))
+	assert.Equal(t, 0, s.Verify("GET", "/payments"))
+	assert.Equal(t, 1, s.Verify("GET", "/users/1"))
+	assert.Equal(t, 2, s.Verify("get", "/users/{{id}}"))
+	assert.Equal(t, 1, s.Verify("*", "/missing"))
+	assert.Len(t, s.Requests(), 4)
+	assert.False(t, s.Requests()[3].Matched)
+
+	s.Reset()
+	assert.Equal(t, 0, s.Verify("", "/payments"))
+	assert.Empty(t, s.Requests())
+}
+
+func TestServer_VerifyEndpoint(t *testing.T) {
+	_, ts := newVerifyServer(t)
+
+	resp, err := http.Post(ts.URL+"/payments", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + AdminPrefix + "/verify?method=POST&path=/payments")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 1, result.Count)
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+AdminPrefix+"/requests", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + AdminPrefix + "/requests")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var requests []ReceivedRequest
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+	assert.Empty(t, requests, "admin requests are not recorded")
+
+	resp, err = http.Get(ts.URL + AdminPrefix + "/verify")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_JournalSize(t *testing.T) {
+	s := NewServer(WithJournalSize(3))
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		s.journal.record(ReceivedRequest{Method: "GET", Path: path})
+	}
+
+	var paths []string
+	for _, req := range s.Requests() {
+		paths = append(paths, req.Path)
+	}
+	assert.Equal(t, []string{"/c", "/d", "/e"}, paths)
+	assert.Equal(t, 0, s.Verify("GET", "/a"))
+	assert.Equal(t, 1, s.Verify("GET", "/e"))
+
+	s.Reset()
+	assert.Empty(t, s.Requests())
+	s.journal.record(ReceivedRequest{Method: "GET", Path: "/f"})
+	require.Len(t, s.Requests(), 1)
+	assert.Equal(t, "/f", s.Requests()[0].Path)
+
+	unlimited := NewServer(WithJournalSize(0))
+	for range DefaultJournalSize + 1 {
+		unlimited.journal.record(ReceivedRequest{Method: "GET", Path: "/x"})
+	}
+	assert.Equal(t, DefaultJournalSize+1, unlimited.Verify("GET", "/x"))
+}
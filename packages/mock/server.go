@@ -14,6 +14,7 @@ import (
 
 	"github.com/abdul-hamid-achik/hitspec/packages/builtin"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
+	"github.com/abdul-hamid-achik/hitspec/packages/import/openapi"
 )
 
 // Server is a mock HTTP server based on hitspec files
@@ -23,6 +24,7 @@ type Server struct {
 	delay    time.Duration
 	verbose  bool
 	registry *builtin.Registry
+	journal  journal
 }
 
 // Option is a functional option for Server
@@ -49,12 +51,21 @@ func WithVerbose(verbose bool) Option {
 	}
 }
 
+// WithJournalSize sets how many received requests the server keeps for
+// Verify and Requests; older ones are dropped. 0 keeps every request.
+func WithJournalSize(size int) Option {
+	return func(s *Server) {
+		s.journal.size = size
+	}
+}
+
 // NewServer creates a new mock server
 func NewServer(opts ...Option) *Server {
 	s := &Server{
 		router:   NewRouter(),
 		port:     3000,
 		registry: builtin.NewRegistry(),
+		journal:  journal{size: DefaultJournalSize},
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -100,6 +111,31 @@ func (s *Server) LoadFiles(paths []string) error {
 	return nil
 }
 
+// LoadOpenAPI loads a route for each operation of an OpenAPI spec, answering
+// with the example response of the spec or one generated from its schema
+func (s *Server) LoadOpenAPI(path string) error {
+	doc, err := openapi.LoadSpec(path)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range openapi.NewConverter().MockRoutes(doc) {
+		s.router.AddRoute(&Route{
+			Method:      r.Method,
+			PathPattern: r.Path,
+			PathRegex:   createPathRegex(r.Path),
+			Name:        r.Name,
+			Response: &MockResponse{
+				StatusCode:  r.Status,
+				ContentType: r.ContentType,
+				Headers:     make(map[string]string),
+				Body:        s.resolveVariables(r.Body, nil),
+			},
+		})
+	}
+	return nil
+}
+
 func (s *Server) createRoute(req *parser.Request, vars map[string]string) *Route {
 	// Resolve URL with variables
 	url := s.resolveVariables(req.URL, vars)
@@ -118,11 +154,13 @@ func (s *Server) createRoute(req *parser.Request, vars map[string]string) *Route
 	return route
 }
 
+// varPattern matches {{variable}} references in routes and responses
+var varPattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+
 func (s *Server) resolveVariables(input string, vars map[string]string) string {
 	result := input
 
 	// Replace {{variable}} patterns
-	varPattern := regexp.MustCompile(`\{\{([^}]+)\}\}`)
 	result = varPattern.ReplaceAllStringFunc(result, func(match string) string {
 		// Extract variable name (without braces)
 		name := strings.TrimPrefix(strings.TrimSuffix(match, "}}"), "{{")
@@ -132,6 +170,10 @@ func (s *Server) resolveVariables(input string, vars map[string]string) string {
 		if strings.HasPrefix(name, "$") {
 			funcExpr := strings.TrimPrefix(name, "$")
 			if val, ok := s.registry.Call(funcExpr); ok {
+				if err, isErr := val.(error); isErr {
+					log.Printf("warning: %s: %v", name[:strings.Index(name, "(")], err)
+					return match
+				}
 				return fmt.Sprintf("%v", val)
 			}
 		}
@@ -179,7 +221,7 @@ func extractPathPattern(url string) string {
 
 func createPathRegex(pattern string) *regexp.Regexp {
 	// Convert {{param}} to named capture groups
-	regexPattern := regexp.MustCompile(`\{\{([^}]+)\}\}`).ReplaceAllString(pattern, `(?P<$1>[^/]+)`)
+	regexPattern := varPattern.ReplaceAllString(pattern, `(?P<$1>[^/]+)`)
 
 	// Escape other special chars but preserve capture groups
 	// Simple approach: just compile as-is since we converted params
@@ -254,16 +296,22 @@ func (s *Server) generateBodyFromAssertions(assertions []*parser.Assertion) stri
 	return string(data)
 }
 
+// Handler returns the handler serving the mock routes and the verification
+// endpoints under AdminPrefix
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRequest)
+	mux.HandleFunc(AdminPrefix+"/", s.handleAdmin)
+	return mux
+}
+
 // Start starts the mock server
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleRequest)
-
 	server := &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: s.Handler(),
 	}
 
 	log.Printf("Mock server starting on http://localhost:%d", s.port)
@@ -282,12 +330,9 @@ func (s *Server) Start() error {
 func (s *Server) StartWithContext(ctx context.Context) error {
 	addr := fmt.Sprintf(":%d", s.port)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleRequest)
-
 	server := &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: s.Handler(),
 	}
 
 	go func() {
@@ -311,6 +356,7 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 
 	// Find matching route
 	route, params := s.router.Match(r.Method, r.URL.Path)
+	s.journal.record(ReceivedRequest{Method: r.Method, Path: r.URL.Path, Matched: route != nil, Time: start})
 
 	if route == nil {
 		if s.verbose {
@@ -327,7 +373,9 @@ func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
 	for key, value := range resp.Headers {
 		w.Header().Set(key, value)
 	}
-	w.Header().Set("Content-Type", resp.ContentType)
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
 
 	// Resolve body with params
 	body := s.resolveBodyParams(resp.Body, params)
@@ -0,0 +1,17 @@
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_ResolveVariables(t *testing.T) {
+	s := NewServer()
+	vars := map[string]string{"id": "42"}
+
+	assert.Equal(t, "/users/42", s.resolveVariables("/users/{{id}}", vars))
+	assert.Equal(t, "[1,2]", s.resolveVariables("{{$json([1, 2])}}", nil))
+	assert.Equal(t, "{{$json([1,)}}", s.resolveVariables("{{$json([1,)}}", nil))
+	assert.Equal(t, "{{missing}}", s.resolveVariables("{{missing}}", nil))
+}
@@ -1,18 +1,49 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"reflect"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
 	"github.com/fatih/color"
 )
 
+// maskedCaptures returns the captures of a result for reports, masking the
+// secrets among its @persist captures since those are long-lived credentials
+func maskedCaptures(r *runner.RequestResult) map[string]any {
+	if len(r.Persist) == 0 {
+		return r.Captures
+	}
+	masked := make(map[string]any, len(r.Captures))
+	for name, value := range r.Captures {
+		if _, ok := r.Persist[name]; ok && env.IsSensitiveName(name) {
+			value = env.MaskedValue
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// assertionCounts returns how many assertions of a result passed and failed
+func assertionCounts(r *runner.RequestResult) (passed, failed int) {
+	for _, a := range r.Assertions {
+		if a.Passed {
+			passed++
+		} else {
+			failed++
+		}
+	}
+	return passed, failed
+}
+
 // formatValue formats a value for display, truncating or summarizing large values
 func formatValue(v any, maxLen int) string {
 	switch val := v.(type) {
@@ -33,9 +64,18 @@ func formatValue(v any, maxLen int) string {
 }
 
 type ConsoleFormatter struct {
-	writer  io.Writer
-	verbose bool
-	noColor bool
+	writer       io.Writer
+	verbose      bool
+	noColor      bool
+	summaryOnly  bool
+	onlyFailures bool
+	quiet        bool
+
+	// Aggregated counts for the summary printed on Flush
+	passed     int
+	failed     int
+	softFailed int
+	skipped    int
 }
 
 type ConsoleOption func(*ConsoleFormatter)
@@ -71,68 +111,91 @@ func WithNoColor(nc bool) ConsoleOption {
 	}
 }
 
+// WithSummaryOnly suppresses per-test lines. Only failures are printed as they
+// happen, followed by a single aggregated summary on Flush.
+func WithSummaryOnly(s bool) ConsoleOption {
+	return func(f *ConsoleFormatter) {
+		f.summaryOnly = s
+	}
+}
+
+// WithOnlyFailures prints failed, errored and soft-failed requests with the
+// usual detail, hiding passing and skipped ones; files without failures print
+// nothing. A single aggregated summary is printed on Flush.
+func WithOnlyFailures(o bool) ConsoleOption {
+	return func(f *ConsoleFormatter) {
+		f.onlyFailures = o
+	}
+}
+
+// WithQuiet prints nothing but errors as the tests run, and a single summary
+// line such as "PASS 48 FAIL 2 SKIP 1 in 3.2s" on Flush
+func WithQuiet(q bool) ConsoleOption {
+	return func(f *ConsoleFormatter) {
+		f.quiet = q
+	}
+}
+
 func (f *ConsoleFormatter) FormatResult(result *runner.RunResult) {
+	if f.quiet {
+		f.count(result)
+		return
+	}
+	if f.summaryOnly {
+		f.formatFailuresOnly(result)
+		return
+	}
+	if f.onlyFailures {
+		f.count(result)
+		if result.Failed == 0 && result.SoftFailed == 0 {
+			return
+		}
+	}
+
 	green := color.New(color.FgGreen).SprintFunc()
 	red := color.New(color.FgRed).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
-	cyan := color.New(color.FgCyan).SprintFunc()
 	bold := color.New(color.Bold).SprintFunc()
 
-	fmt.Fprintf(f.writer, "\n%s\n", bold("Running: "+result.File))
+	heading := "Running: " + result.File
+	if result.Environment != "" {
+		heading += " [" + result.Environment + "]"
+	}
+	fmt.Fprintf(f.writer, "\n%s\n", bold(heading))
 	fmt.Fprintf(f.writer, "\n")
 
+	groups := make(map[string]*runner.GroupResult, len(result.Groups))
+	for _, g := range result.Groups {
+		groups[g.Name] = g
+	}
 	for _, r := range result.Results {
-		if r.Skipped {
-			fmt.Fprintf(f.writer, "  %s %s", yellow("-"), r.Name)
-			if r.SkipReason != "" && r.SkipReason != "filtered out" {
-				fmt.Fprintf(f.writer, " (%s)", r.SkipReason)
-			}
-			fmt.Fprintf(f.writer, "\n")
+		if r.Group == "" {
+			f.formatRequest(r)
 			continue
 		}
-
-		if r.Error != nil {
-			fmt.Fprintf(f.writer, "  %s %s %s\n", red("x"), r.Name, red(fmt.Sprintf("(%v)", r.Error)))
+		// A group is printed as a unit where its first request appears
+		g := groups[r.Group]
+		if g == nil || g.Results[0] != r {
+			continue
+		}
+		if !f.showsAny(g.Results) {
 			continue
 		}
-
 		symbol := green("✓")
-		if !r.Passed {
+		if !g.Passed {
 			symbol = red("✗")
 		}
-
-		fmt.Fprintf(f.writer, "  %s %s %s\n", symbol, r.Name, cyan(fmt.Sprintf("(%dms)", r.Duration.Milliseconds())))
-
-		if f.verbose && r.Response != nil {
-			fmt.Fprintf(f.writer, "    Status: %d\n", r.Response.StatusCode)
-		}
-
-		if !r.Passed && len(r.Assertions) > 0 {
-			for _, a := range r.Assertions {
-				if !a.Passed {
-					fmt.Fprintf(f.writer, "    %s %s %s\n", red("→"), a.Subject, a.Operator)
-					fmt.Fprintf(f.writer, "      Expected: %s\n", formatValue(a.Expected, 100))
-					fmt.Fprintf(f.writer, "      Actual:   %s\n", formatValue(a.Actual, 100))
-					if a.Message != "" {
-						fmt.Fprintf(f.writer, "      %s\n", a.Message)
-					}
-					// Show diff for complex objects when verbose is enabled
-					if f.verbose {
-						diff := f.formatDiff(a.Expected, a.Actual)
-						if diff != "" {
-							fmt.Fprint(f.writer, diff)
-						}
-					}
-				}
-			}
+		fmt.Fprintf(f.writer, "  %s %s\n", symbol, bold(g.Name))
+		writer := f.writer
+		f.writer = &indentWriter{w: writer, prefix: "  "}
+		for _, gr := range g.Results {
+			f.formatRequest(gr)
 		}
+		f.writer = writer
+	}
 
-		if f.verbose && len(r.Captures) > 0 {
-			fmt.Fprintf(f.writer, "    Captures:\n")
-			for name, value := range r.Captures {
-				fmt.Fprintf(f.writer, "      %s = %v\n", name, value)
-			}
-		}
+	if f.onlyFailures {
+		return // The summary is printed on Flush
 	}
 
 	fmt.Fprintf(f.writer, "\n")
@@ -143,147 +206,264 @@ func (f *ConsoleFormatter) FormatResult(result *runner.RunResult) {
 	if result.Failed > 0 {
 		fmt.Fprintf(f.writer, "%s, ", red(fmt.Sprintf("%d failed", result.Failed)))
 	}
+	if result.SoftFailed > 0 {
+		fmt.Fprintf(f.writer, "%s, ", yellow(fmt.Sprintf("%d soft failed", result.SoftFailed)))
+	}
 	if result.Skipped > 0 {
 		fmt.Fprintf(f.writer, "%s, ", yellow(fmt.Sprintf("%d skipped", result.Skipped)))
 	}
-	total := result.Passed + result.Failed + result.Skipped
+	total := result.Passed + result.Failed + result.SoftFailed + result.Skipped
 	fmt.Fprintf(f.writer, "%d total\n", total)
 	fmt.Fprintf(f.writer, "Time:  %dms\n", result.Duration.Milliseconds())
 	fmt.Fprintf(f.writer, "\n")
 }
 
-func (f *ConsoleFormatter) FormatError(err error) {
+// formatRequest prints the result line of a request with its failures and,
+// when verbose, its status and captures
+func (f *ConsoleFormatter) formatRequest(r *runner.RequestResult) {
+	green := color.New(color.FgGreen).SprintFunc()
 	red := color.New(color.FgRed).SprintFunc()
-	fmt.Fprintf(f.writer, "%s %v\n", red("Error:"), err)
-}
-
-func (f *ConsoleFormatter) FormatHeader(version string) {
-	bold := color.New(color.Bold).SprintFunc()
-	fmt.Fprintf(f.writer, "%s %s\n", bold("hitspec"), version)
-}
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
 
-// DiffResult represents a single difference between expected and actual values.
-type DiffResult struct {
-	Path     string
-	Expected any
-	Actual   any
-	Type     DiffType
-}
+	if !f.shows(r) {
+		return
+	}
+	if r.Skipped {
+		fmt.Fprintf(f.writer, "  %s %s", yellow("-"), r.Name)
+		if r.SkipReason != "" && r.SkipReason != "filtered out" {
+			fmt.Fprintf(f.writer, " (%s)", r.SkipReason)
+		}
+		fmt.Fprintf(f.writer, "\n")
+		return
+	}
 
-// DiffType represents the type of difference.
-type DiffType int
+	if r.Error != nil {
+		fmt.Fprintf(f.writer, "  %s %s %s\n", red("x"), r.Name, red(fmt.Sprintf("(%v)", r.Error)))
+		return
+	}
 
-const (
-	DiffTypeChanged DiffType = iota
-	DiffTypeAdded
-	DiffTypeRemoved
-)
+	symbol := green("✓")
+	if r.SoftFailed {
+		symbol = yellow("!")
+	} else if !r.Passed {
+		symbol = red("✗")
+	}
 
-// computeJSONDiff compares two values and returns a list of differences.
-// It only returns differences, not the full structure.
-func computeJSONDiff(expected, actual any, path string) []DiffResult {
-	var diffs []DiffResult
+	fmt.Fprintf(f.writer, "  %s %s %s\n", symbol, r.Name, cyan(fmt.Sprintf("(%dms)", r.Duration.Milliseconds())))
 
-	// Handle nil cases
-	if expected == nil && actual == nil {
-		return diffs
-	}
-	if expected == nil {
-		return []DiffResult{{Path: path, Expected: nil, Actual: actual, Type: DiffTypeAdded}}
+	if f.verbose && r.Response != nil {
+		fmt.Fprintf(f.writer, "    Status: %d\n", r.Response.StatusCode)
 	}
-	if actual == nil {
-		return []DiffResult{{Path: path, Expected: expected, Actual: nil, Type: DiffTypeRemoved}}
+	if f.verbose && len(r.Assertions) > 0 {
+		fmt.Fprintf(f.writer, "    Assertions: %d in %s\n", len(r.Assertions), r.AssertTime.Round(time.Microsecond))
 	}
 
-	// Check types
-	expectedType := reflect.TypeOf(expected)
-	actualType := reflect.TypeOf(actual)
-
-	// Type mismatch
-	if expectedType != actualType {
-		return []DiffResult{{Path: path, Expected: expected, Actual: actual, Type: DiffTypeChanged}}
+	if !r.Passed {
+		f.formatFailedAssertions(r)
 	}
 
-	switch e := expected.(type) {
-	case map[string]any:
-		a := actual.(map[string]any)
-		diffs = append(diffs, compareObjects(e, a, path)...)
-	case []any:
-		a := actual.([]any)
-		diffs = append(diffs, compareArrays(e, a, path)...)
-	default:
-		if !reflect.DeepEqual(expected, actual) {
-			diffs = append(diffs, DiffResult{Path: path, Expected: expected, Actual: actual, Type: DiffTypeChanged})
+	if f.verbose && len(r.Captures) > 0 {
+		fmt.Fprintf(f.writer, "    Captures:\n")
+		for name, value := range maskedCaptures(r) {
+			fmt.Fprintf(f.writer, "      %s = %v\n", name, value)
 		}
 	}
+}
 
-	return diffs
+// shows reports whether a request is printed, which in only-failures mode
+// excludes passing and skipped requests
+func (f *ConsoleFormatter) shows(r *runner.RequestResult) bool {
+	return !f.onlyFailures || !(r.Skipped || (r.Passed && !r.SoftFailed && r.Error == nil))
 }
 
-func compareObjects(expected, actual map[string]any, path string) []DiffResult {
-	var diffs []DiffResult
+func (f *ConsoleFormatter) showsAny(results []*runner.RequestResult) bool {
+	for _, r := range results {
+		if f.shows(r) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Collect all keys
-	allKeys := make(map[string]bool)
-	for k := range expected {
-		allKeys[k] = true
+// indentWriter prefixes every line written through it, nesting the requests
+// of a group under its name
+type indentWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+func (iw *indentWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, b := range p {
+		if !iw.midLine {
+			buf.WriteString(iw.prefix)
+		}
+		buf.WriteByte(b)
+		iw.midLine = b != '\n'
 	}
-	for k := range actual {
-		allKeys[k] = true
+	if _, err := iw.w.Write(buf.Bytes()); err != nil {
+		return 0, err
 	}
+	return len(p), nil
+}
 
-	for key := range allKeys {
-		keyPath := key
-		if path != "" {
-			keyPath = path + "." + key
+// formatFailedAssertions prints the details of each failed assertion of a request
+func (f *ConsoleFormatter) formatFailedAssertions(r *runner.RequestResult) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	// A rollup first, so requests with many checks can be scanned
+	if _, failed := assertionCounts(r); failed > 0 && len(r.Assertions) > 1 {
+		summary := fmt.Sprintf("%d of %d assertions failed", failed, len(r.Assertions))
+		if r.SoftFailed {
+			summary = yellow(summary)
+		} else {
+			summary = red(summary)
 		}
+		fmt.Fprintf(f.writer, "    %s\n", summary)
+	}
 
-		expectedVal, expectedExists := expected[key]
-		actualVal, actualExists := actual[key]
+	for _, a := range r.Assertions {
+		if a.Passed {
+			continue
+		}
+		arrow := red("→")
+		if r.SoftFailed {
+			arrow = yellow("→")
+		}
+		fmt.Fprintf(f.writer, "    %s %s %s\n", arrow, a.Subject, a.Operator)
+		fmt.Fprintf(f.writer, "      Expected: %s\n", formatValue(a.Expected, 100))
+		fmt.Fprintf(f.writer, "      Actual:   %s\n", formatValue(a.Actual, 100))
+		if a.Message != "" {
+			fmt.Fprintf(f.writer, "      %s\n", a.Message)
+		}
+		// Show diff for complex objects when verbose is enabled
+		if f.verbose {
+			diff := f.formatDiff(a.Expected, a.Actual, a.ByKey)
+			if diff != "" {
+				fmt.Fprint(f.writer, diff)
+			}
+		}
+	}
+}
 
-		if !expectedExists {
-			diffs = append(diffs, DiffResult{Path: keyPath, Expected: nil, Actual: actualVal, Type: DiffTypeAdded})
-		} else if !actualExists {
-			diffs = append(diffs, DiffResult{Path: keyPath, Expected: expectedVal, Actual: nil, Type: DiffTypeRemoved})
-		} else {
-			diffs = append(diffs, computeJSONDiff(expectedVal, actualVal, keyPath)...)
+// formatFailuresOnly records the counts of a file and prints only its
+// failed and errored requests
+func (f *ConsoleFormatter) formatFailuresOnly(result *runner.RunResult) {
+	red := color.New(color.FgRed).SprintFunc()
+
+	f.count(result)
+
+	for _, r := range result.Results {
+		if r.Skipped || r.SoftFailed || (r.Passed && r.Error == nil) {
+			continue
+		}
+		name := r.Name
+		if r.Group != "" {
+			name = r.Group + " › " + name
+		}
+		if r.Error != nil {
+			fmt.Fprintf(f.writer, "  %s %s › %s %s\n", red("x"), result.File, name, red(fmt.Sprintf("(%v)", r.Error)))
+			continue
 		}
+		fmt.Fprintf(f.writer, "  %s %s › %s\n", red("✗"), result.File, name)
+		f.formatFailedAssertions(r)
 	}
+}
 
-	return diffs
+// count adds the counts of a file to the aggregated summary
+func (f *ConsoleFormatter) count(result *runner.RunResult) {
+	f.passed += result.Passed
+	f.failed += result.Failed
+	f.softFailed += result.SoftFailed
+	f.skipped += result.Skipped
 }
 
-func compareArrays(expected, actual []any, path string) []DiffResult {
-	var diffs []DiffResult
+// Flush prints the aggregated summary in quiet, summary-only and
+// only-failures modes
+func (f *ConsoleFormatter) Flush(totalDuration time.Duration) error {
+	if f.quiet {
+		f.formatQuietSummary(totalDuration)
+		return nil
+	}
+	if !f.summaryOnly && !f.onlyFailures {
+		return nil
+	}
 
-	maxLen := len(expected)
-	if len(actual) > maxLen {
-		maxLen = len(actual)
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Fprintf(f.writer, "\n")
+	fmt.Fprintf(f.writer, "Tests: ")
+	if f.passed > 0 {
+		fmt.Fprintf(f.writer, "%s, ", green(fmt.Sprintf("%d passed", f.passed)))
+	}
+	if f.failed > 0 {
+		fmt.Fprintf(f.writer, "%s, ", red(fmt.Sprintf("%d failed", f.failed)))
 	}
+	if f.softFailed > 0 {
+		fmt.Fprintf(f.writer, "%s, ", yellow(fmt.Sprintf("%d soft failed", f.softFailed)))
+	}
+	if f.skipped > 0 {
+		fmt.Fprintf(f.writer, "%s, ", yellow(fmt.Sprintf("%d skipped", f.skipped)))
+	}
+	total := f.passed + f.failed + f.softFailed + f.skipped
+	fmt.Fprintf(f.writer, "%d total\n", total)
+	fmt.Fprintf(f.writer, "Time:  %dms\n", totalDuration.Milliseconds())
 
-	for i := 0; i < maxLen; i++ {
-		indexPath := fmt.Sprintf("%s[%d]", path, i)
+	f.passed, f.failed, f.softFailed, f.skipped = 0, 0, 0, 0
+	return nil
+}
 
-		if i >= len(expected) {
-			diffs = append(diffs, DiffResult{Path: indexPath, Expected: nil, Actual: actual[i], Type: DiffTypeAdded})
-		} else if i >= len(actual) {
-			diffs = append(diffs, DiffResult{Path: indexPath, Expected: expected[i], Actual: nil, Type: DiffTypeRemoved})
-		} else {
-			diffs = append(diffs, computeJSONDiff(expected[i], actual[i], indexPath)...)
-		}
+// formatQuietSummary prints the aggregated counts on one line meant for
+// scripts and CI logs. SOFT is only included when there were soft failures.
+func (f *ConsoleFormatter) formatQuietSummary(totalDuration time.Duration) {
+	soft := ""
+	if f.softFailed > 0 {
+		soft = fmt.Sprintf(" SOFT %d", f.softFailed)
 	}
+	fmt.Fprintf(f.writer, "PASS %d FAIL %d%s SKIP %d in %s\n",
+		f.passed, f.failed, soft, f.skipped, totalDuration.Round(time.Millisecond))
+
+	f.passed, f.failed, f.softFailed, f.skipped = 0, 0, 0, 0
+}
 
-	return diffs
+func (f *ConsoleFormatter) FormatError(err error) {
+	red := color.New(color.FgRed).SprintFunc()
+	fmt.Fprintf(f.writer, "%s %v\n", red("Error:"), err)
 }
 
+func (f *ConsoleFormatter) FormatHeader(version string) {
+	if f.quiet {
+		return
+	}
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Fprintf(f.writer, "%s %s\n", bold("hitspec"), version)
+}
+
+// DiffResult represents a single difference between expected and actual values.
+type DiffResult = assertions.DiffResult
+
+// DiffType represents the type of difference.
+type DiffType = assertions.DiffType
+
+const (
+	DiffTypeChanged = assertions.DiffTypeChanged
+	DiffTypeAdded   = assertions.DiffTypeAdded
+	DiffTypeRemoved = assertions.DiffTypeRemoved
+)
+
 // formatDiff formats the diff output for console display.
-func (f *ConsoleFormatter) formatDiff(expected, actual any) string {
+func (f *ConsoleFormatter) formatDiff(expected, actual any, key string) string {
 	// Try to parse as JSON for structured diff
 	expectedJSON := parseToJSON(expected)
 	actualJSON := parseToJSON(actual)
 
 	if expectedJSON != nil && actualJSON != nil {
-		diffs := computeJSONDiff(expectedJSON, actualJSON, "")
+		diffs := assertions.ComputeJSONDiffByKey(expectedJSON, actualJSON, "", key)
 		if len(diffs) == 0 {
 			return ""
 		}
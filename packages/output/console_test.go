@@ -0,0 +1,153 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleFormatter_SummaryOnly(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true), WithSummaryOnly(true))
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	f.FormatResult(&runner.RunResult{
+		File:    "tests/orders.http",
+		Skipped: 1,
+		Failed:  1,
+		Results: []*runner.RequestResult{
+			{Name: "disabled", Skipped: true, SkipReason: "flaky"},
+			{Name: "broken", Error: assert.AnError},
+		},
+	})
+	require.NoError(t, f.Flush(1500*time.Millisecond))
+
+	out := buf.String()
+	assert.NotContains(t, out, "Running:")
+	assert.NotContains(t, out, "listUsers")
+	assert.NotContains(t, out, "disabled")
+	assert.Contains(t, out, "✗ tests/users.http › getUser")
+	assert.Contains(t, out, "expected 200, got 404")
+	assert.Contains(t, out, "x tests/orders.http › broken")
+	assert.Contains(t, out, "Tests: 2 passed, 2 failed, 1 skipped, 5 total")
+	assert.Contains(t, out, "Time:  1500ms")
+}
+
+func TestConsoleFormatter_OnlyFailures(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true), WithVerbose(true), WithOnlyFailures(true))
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	require.NoError(t, f.Flush(time.Second))
+
+	out := buf.String()
+	assert.Contains(t, out, "Running: tests/users.http")
+	assert.NotContains(t, out, "tests/health.http", "files without failures print nothing")
+	assert.NotContains(t, out, "listUsers")
+	assert.Contains(t, out, "✗ getUser (80ms)")
+	assert.Contains(t, out, "Expected: 200")
+	assert.Contains(t, out, "expected 200, got 404")
+	assert.Equal(t, 1, strings.Count(out, "Tests: "), "only the aggregated summary is printed")
+	assert.Contains(t, out, "Tests: 2 passed, 1 failed, 3 total")
+}
+
+func TestConsoleFormatter_Quiet(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true), WithQuiet(true), WithVerbose(true))
+	f.FormatHeader("1.0.0")
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	f.FormatResult(&runner.RunResult{File: "tests/orders.http", Skipped: 1, SoftFailed: 1})
+	f.FormatError(assert.AnError)
+	require.NoError(t, f.Flush(3210*time.Millisecond))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, buf.String())
+	assert.Contains(t, lines[0], "Error:")
+	assert.Equal(t, "PASS 2 FAIL 1 SOFT 1 SKIP 1 in 3.21s", lines[1])
+
+	// Counts start over for the next run, as in watch mode
+	buf.Reset()
+	require.NoError(t, f.Flush(time.Second))
+	assert.Equal(t, "PASS 0 FAIL 0 SKIP 0 in 1s\n", buf.String())
+}
+
+func TestConsoleFormatter_AssertionTime(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true), WithVerbose(true))
+	f.FormatResult(&runner.RunResult{
+		File:   "tests/users.http",
+		Passed: 1,
+		Results: []*runner.RequestResult{{
+			Name:       "getUser",
+			Passed:     true,
+			Duration:   20 * time.Millisecond,
+			AssertTime: 1500 * time.Microsecond,
+			Assertions: []*assertions.Result{{Subject: "status", Operator: "==", Passed: true}, {Subject: "body", Operator: "schema", Passed: true}},
+		}},
+	})
+
+	assert.Contains(t, buf.String(), "✓ getUser (20ms)\n    Assertions: 2 in 1.5ms\n")
+}
+
+func TestConsoleFormatter_AssertionRollup(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true))
+	f.FormatResult(&runner.RunResult{
+		File:   "tests/users.http",
+		Failed: 2,
+		Results: []*runner.RequestResult{
+			{Name: "getUser", Duration: 20 * time.Millisecond, Assertions: []*assertions.Result{
+				{Subject: "status", Operator: "==", Passed: true},
+				{Subject: "body.id", Operator: "==", Expected: 1, Actual: 2},
+				{Subject: "body.name", Operator: "==", Expected: "a", Actual: "b"},
+			}},
+			{Name: "health", Duration: 5 * time.Millisecond, Assertions: []*assertions.Result{
+				{Subject: "status", Operator: "==", Expected: 200, Actual: 500},
+			}},
+		},
+	})
+
+	out := buf.String()
+	assert.Contains(t, out, "✗ getUser (20ms)\n    2 of 3 assertions failed\n    → body.id ==\n")
+	// A single assertion needs no rollup
+	assert.Contains(t, out, "✗ health (5ms)\n    → status ==\n")
+}
+
+func TestConsoleFormatter_FlushWithoutSummaryOnly(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true))
+	require.NoError(t, f.Flush(time.Second))
+	assert.Empty(t, buf.String())
+}
+
+func TestConsoleFormatter_Groups(t *testing.T) {
+	cart := &runner.RequestResult{Name: "createCart", Group: "checkout"}
+	pay := &runner.RequestResult{Name: "pay", Group: "checkout", Skipped: true, SkipReason: "group setup failed"}
+	var buf bytes.Buffer
+	f := NewConsoleFormatter(WithWriter(&buf), WithNoColor(true))
+	f.FormatResult(&runner.RunResult{
+		File:    "tests/checkout.http",
+		Failed:  1,
+		Passed:  1,
+		Skipped: 1,
+		Results: []*runner.RequestResult{
+			cart,
+			{Name: "health", Passed: true},
+			pay,
+		},
+		Groups: []*runner.GroupResult{{Name: "checkout", Results: []*runner.RequestResult{cart, pay}}},
+	})
+
+	out := buf.String()
+	assert.Contains(t, out, "  ✗ checkout\n    ✗ createCart (0ms)\n    - pay (group setup failed)\n  ✓ health")
+}
@@ -0,0 +1,352 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+)
+
+// HAR 1.2 structures, trimmed to what a test run records
+
+// HAR is the root of a HAR file
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog holds the recorded entries
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the tool that wrote the file
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and its response
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"` // The test file and request name
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry. Requests that got no response
+// have status 0 and the error in Comment.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+// HARNameValue is a header or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is a response body
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARTimings breaks an entry's time into phases, in milliseconds; -1 when
+// a phase didn't happen
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARFilter selects the requests written to a HAR file
+type HARFilter func(r *runner.RequestResult) bool
+
+// ParseHARFilter parses --har-filter: comma-separated conditions, of which a
+// request has to match any. A condition is failure, success, or a comparison
+// of duration (ms) or status, such as duration>1000 or status>=500.
+func ParseHARFilter(expr string) (HARFilter, error) {
+	var conditions []HARFilter
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		condition, err := parseHARCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("empty HAR filter")
+	}
+	return func(r *runner.RequestResult) bool {
+		for _, condition := range conditions {
+			if condition(r) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// harOperators are the comparisons of a filter condition, longest first so
+// >= isn't read as >
+var harOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+func parseHARCondition(cond string) (HARFilter, error) {
+	switch strings.ToLower(cond) {
+	case "failure", "failed", "failures":
+		return func(r *runner.RequestResult) bool { return !r.Passed }, nil
+	case "success", "passed":
+		return func(r *runner.RequestResult) bool { return r.Passed }, nil
+	}
+
+	for _, op := range harOperators {
+		field, value, ok := strings.Cut(cond, op)
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HAR filter %q: %s is not a number", cond, strings.TrimSpace(value))
+		}
+		var get func(r *runner.RequestResult) float64
+		switch field {
+		case "duration":
+			get = func(r *runner.RequestResult) float64 { return float64(r.Duration.Milliseconds()) }
+		case "status":
+			get = func(r *runner.RequestResult) float64 {
+				if r.Response == nil {
+					return 0
+				}
+				return float64(r.Response.StatusCode)
+			}
+		default:
+			return nil, fmt.Errorf("invalid HAR filter %q: unknown field %q (use duration or status)", cond, field)
+		}
+		return func(r *runner.RequestResult) bool { return compareHAR(get(r), op, n) }, nil
+	}
+	return nil, fmt.Errorf("invalid HAR filter %q (use failure, success, duration>N or status>=N)", cond)
+}
+
+func compareHAR(actual float64, op string, expected float64) bool {
+	switch op {
+	case ">=":
+		return actual >= expected
+	case "<=":
+		return actual <= expected
+	case "!=":
+		return actual != expected
+	case ">":
+		return actual > expected
+	case "<":
+		return actual < expected
+	default:
+		return actual == expected
+	}
+}
+
+// BuildHAR returns a HAR of the requests of results accepted by filter, or
+// of every request sent when filter is nil. Skipped requests, which were
+// never sent, are left out.
+func BuildHAR(results []*runner.RunResult, filter HARFilter, version string) *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "hitspec", Version: version},
+		Entries: []HAREntry{},
+	}}
+	for _, result := range results {
+		for _, r := range result.Results {
+			if r.Skipped || r.Request == nil || (filter != nil && !filter(r)) {
+				continue
+			}
+			har.Log.Entries = append(har.Log.Entries, harEntry(result.File, r))
+		}
+	}
+	return har
+}
+
+// WriteHAR writes the HAR of results to w
+func WriteHAR(w io.Writer, results []*runner.RunResult, filter HARFilter, version string) error {
+	data, err := json.MarshalIndent(BuildHAR(results, filter, version), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func harEntry(file string, r *runner.RequestResult) HAREntry {
+	req := r.Request
+	fullURL := req.BuildURL()
+	entry := HAREntry{
+		StartedDateTime: r.StartedAt.Format(time.RFC3339Nano),
+		Time:            milliseconds(r.Duration),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         fullURL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Headers),
+			QueryString: harQuery(fullURL),
+			HeadersSize: -1,
+			BodySize:    len(req.Body),
+		},
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: milliseconds(r.Duration)},
+		Comment: file,
+	}
+	if r.Name != "" {
+		entry.Comment += ": " + r.Name
+	}
+	if req.Body != "" {
+		entry.Request.PostData = &HARPostData{MimeType: headerValue(req.Headers, "Content-Type"), Text: req.Body}
+	}
+
+	resp := r.Response
+	if resp == nil {
+		entry.Response = HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		if r.Error != nil {
+			entry.Response.Comment = r.Error.Error()
+		}
+		return entry
+	}
+
+	entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARNameValue{},
+		Headers:     harResponseHeaders(resp),
+		Content: HARContent{
+			Size:     len(resp.Body),
+			MimeType: resp.ContentType(),
+			Text:     resp.BodyString(),
+		},
+		RedirectURL: resp.Header("Location"),
+		HeadersSize: -1,
+		BodySize:    resp.WireSize,
+	}
+	entry.Timings = HARTimings{
+		Blocked: -1,
+		DNS:     harPhase(resp.Timing.DNS),
+		Connect: harPhase(resp.Timing.Connect),
+		SSL:     harPhase(resp.Timing.TLS),
+		Wait:    milliseconds(resp.Timing.TTFB),
+		Receive: milliseconds(resp.Timing.Transfer),
+	}
+	return entry
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// harPhase returns a connection phase in milliseconds, or -1 when a reused
+// connection skipped it
+func harPhase(d time.Duration) float64 {
+	if d == 0 {
+		return -1
+	}
+	return milliseconds(d)
+}
+
+func harHeaders(headers map[string]string) []HARNameValue {
+	list := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		list = append(list, HARNameValue{Name: name, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func harResponseHeaders(resp *http.Response) []HARNameValue {
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]HARNameValue, 0, len(names))
+	for _, name := range names {
+		for _, value := range resp.HeaderValues(name) {
+			list = append(list, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return list
+}
+
+func harQuery(rawURL string) []HARNameValue {
+	list := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return list
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			list = append(list, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func headerValue(headers map[string]string, key string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
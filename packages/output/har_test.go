@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func harRunResults() []*runner.RunResult {
+	return []*runner.RunResult{{
+		File: "tests/users.http",
+		Results: []*runner.RequestResult{
+			{
+				Name:     "listUsers",
+				Passed:   true,
+				Duration: 50 * time.Millisecond,
+				Request: &http.Request{
+					Method:      "GET",
+					URL:         "https://api.example.com/users",
+					Headers:     map[string]string{"Accept": "application/json"},
+					QueryParams: map[string]string{"page": "2"},
+				},
+				Response: &http.Response{
+					StatusCode: 200,
+					Status:     "200 OK",
+					Headers:    map[string]string{"Content-Type": "application/json"},
+					Body:       []byte(`[]`),
+					WireSize:   2,
+				},
+			},
+			{
+				Name:     "createUser",
+				Duration: 1500 * time.Millisecond,
+				Request: &http.Request{
+					Method:  "POST",
+					URL:     "https://api.example.com/users",
+					Headers: map[string]string{"Content-Type": "application/json"},
+					Body:    `{"name":"Ada"}`,
+				},
+				Response: &http.Response{StatusCode: 500, Status: "500 Internal Server Error"},
+			},
+			{
+				Name:    "timeout",
+				Request: &http.Request{Method: "GET", URL: "https://api.example.com/slow"},
+				Error:   errors.New("context deadline exceeded"),
+			},
+			{Name: "skipped", Skipped: true},
+		},
+	}}
+}
+
+func TestWriteHAR(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteHAR(&buf, harRunResults(), nil, "1.2.3"))
+
+	var har HAR
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &har))
+	assert.Equal(t, "1.2", har.Log.Version)
+	assert.Equal(t, HARCreator{Name: "hitspec", Version: "1.2.3"}, har.Log.Creator)
+	require.Len(t, har.Log.Entries, 3)
+
+	list := har.Log.Entries[0]
+	assert.Equal(t, "https://api.example.com/users?page=2", list.Request.URL)
+	assert.Equal(t, []HARNameValue{{Name: "page", Value: "2"}}, list.Request.QueryString)
+	assert.Equal(t, "tests/users.http: listUsers", list.Comment)
+	assert.Equal(t, 200, list.Response.Status)
+	assert.Equal(t, "OK", list.Response.StatusText)
+	assert.Equal(t, "[]", list.Response.Content.Text)
+
+	create := har.Log.Entries[1]
+	require.NotNil(t, create.Request.PostData)
+	assert.Equal(t, `{"name":"Ada"}`, create.Request.PostData.Text)
+	assert.Equal(t, "application/json", create.Request.PostData.MimeType)
+	assert.Equal(t, float64(1500), create.Time)
+
+	failed := har.Log.Entries[2]
+	assert.Equal(t, 0, failed.Response.Status)
+	assert.Equal(t, "context deadline exceeded", failed.Response.Comment)
+}
+
+func TestParseHARFilter(t *testing.T) {
+	names := func(filter HARFilter) []string {
+		var names []string
+		for _, e := range BuildHAR(harRunResults(), filter, "").Log.Entries {
+			names = append(names, e.Comment)
+		}
+		return names
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"failure", []string{"tests/users.http: createUser", "tests/users.http: timeout"}},
+		{"success", []string{"tests/users.http: listUsers"}},
+		{"duration>1000", []string{"tests/users.http: createUser"}},
+		{"status>=500", []string{"tests/users.http: createUser"}},
+		{"status=200, duration > 1000", []string{"tests/users.http: listUsers", "tests/users.http: createUser"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseHARFilter(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(filter))
+		})
+	}
+
+	for _, expr := range []string{"", "slow", "size>10", "duration>fast"} {
+		_, err := ParseHARFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -111,7 +111,7 @@ func (f *HTMLFormatter) FormatResult(result *runner.RunResult) {
 			Passed:   r.Passed,
 			Skipped:  r.Skipped,
 			Duration: float64(r.Duration.Milliseconds()),
-			Captures: r.Captures,
+			Captures: maskedCaptures(r),
 		}
 
 		// Set status class for CSS
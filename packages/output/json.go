@@ -7,32 +7,52 @@ import (
 	"time"
 
 	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
 )
 
 // JSONOutput represents the complete JSON output structure
 type JSONOutput struct {
 	Summary  JSONSummary  `json:"summary"`
 	Tests    []JSONTest   `json:"tests"`
+	Groups   []JSONGroup  `json:"groups,omitempty"`
 	Duration float64      `json:"duration"`
 	Time     string       `json:"time"`
 }
 
 // JSONSummary represents the test summary
 type JSONSummary struct {
-	Total   int `json:"total"`
-	Passed  int `json:"passed"`
-	Failed  int `json:"failed"`
-	Skipped int `json:"skipped"`
+	Total      int `json:"total"`
+	Passed     int `json:"passed"`
+	Failed     int `json:"failed"`
+	SoftFailed int `json:"softFailed,omitempty"`
+	Skipped    int `json:"skipped"`
+}
+
+// JSONGroup represents the tests of a @group, which passes when none failed.
+// The tests are also listed under "tests".
+type JSONGroup struct {
+	Name   string     `json:"name"`
+	File   string     `json:"file"`
+	Passed bool       `json:"passed"`
+	Tests  []JSONTest `json:"tests"`
 }
 
 // JSONTest represents a single test result
 type JSONTest struct {
 	Name       string          `json:"name"`
 	File       string          `json:"file"`
+	Environment string         `json:"environment,omitempty"` // Set when the run covers several environments
+	Group      string          `json:"group,omitempty"`
 	Passed     bool            `json:"passed"`
+	SoftFailed bool            `json:"softFailed,omitempty"`
 	Skipped    bool            `json:"skipped,omitempty"`
 	SkipReason string          `json:"skipReason,omitempty"`
 	Duration   float64         `json:"duration"`
+	Status     int             `json:"status,omitempty"`       // Response status code
+	ResponseSize int           `json:"responseSize,omitempty"` // Response body size in bytes
+	Timing     *JSONTiming     `json:"timing,omitempty"`
+	AssertionDuration float64  `json:"assertionDuration,omitempty"` // Milliseconds spent evaluating assertions
+	AssertionCounts *JSONAssertionCounts `json:"assertionCounts,omitempty"`
 	Error      string          `json:"error,omitempty"`
 	Request    *JSONRequest    `json:"request,omitempty"`
 	Response   *JSONResponse   `json:"response,omitempty"`
@@ -40,6 +60,13 @@ type JSONTest struct {
 	Captures   map[string]any  `json:"captures,omitempty"`
 }
 
+// JSONAssertionCounts rolls up the assertions of a test
+type JSONAssertionCounts struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
 // JSONRequest represents request details
 type JSONRequest struct {
 	Method  string            `json:"method"`
@@ -55,6 +82,16 @@ type JSONResponse struct {
 	Duration   float64           `json:"duration"`
 }
 
+// JSONTiming is the phase breakdown of a request in milliseconds
+type JSONTiming struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	TTFB     float64 `json:"ttfb"`
+	Transfer float64 `json:"transfer"`
+	Total    float64 `json:"total"`
+}
+
 // JSONAssertion represents an assertion result
 type JSONAssertion struct {
 	Subject  string `json:"subject"`
@@ -69,6 +106,7 @@ type JSONAssertion struct {
 type JSONFormatter struct {
 	writer  io.Writer
 	results []JSONTest
+	groups  []JSONGroup
 }
 
 type JSONOption func(*JSONFormatter)
@@ -91,13 +129,21 @@ func JSONWithWriter(w io.Writer) JSONOption {
 }
 
 func (f *JSONFormatter) FormatResult(result *runner.RunResult) {
+	groups := make(map[string]*JSONGroup, len(result.Groups))
+	for _, g := range result.Groups {
+		groups[g.Name] = &JSONGroup{Name: g.Name, File: result.File, Passed: g.Passed}
+	}
+
 	for _, r := range result.Results {
 		test := JSONTest{
 			Name:     r.Name,
 			File:     result.File,
-			Passed:   r.Passed,
-			Skipped:  r.Skipped,
-			Duration: float64(r.Duration.Milliseconds()),
+			Environment: result.Environment,
+			Group:      r.Group,
+			Passed:     r.Passed,
+			SoftFailed: r.SoftFailed,
+			Skipped:    r.Skipped,
+			Duration:   float64(r.Duration.Milliseconds()),
 		}
 
 		if r.SkipReason != "" && r.SkipReason != "filtered out" {
@@ -123,9 +169,15 @@ func (f *JSONFormatter) FormatResult(result *runner.RunResult) {
 				Headers:    r.Response.Headers,
 				Duration:   float64(r.Response.Duration.Milliseconds()),
 			}
+			test.Status = r.Response.StatusCode
+			test.ResponseSize = len(r.Response.Body)
+			test.Timing = jsonTiming(r.Response)
 		}
 
 		if len(r.Assertions) > 0 {
+			test.AssertionDuration = float64(r.AssertTime.Microseconds()) / 1000
+			passed, failed := assertionCounts(r)
+			test.AssertionCounts = &JSONAssertionCounts{Total: len(r.Assertions), Passed: passed, Failed: failed}
 			test.Assertions = make([]JSONAssertion, len(r.Assertions))
 			for i, a := range r.Assertions {
 				test.Assertions[i] = JSONAssertion{
@@ -140,10 +192,34 @@ func (f *JSONFormatter) FormatResult(result *runner.RunResult) {
 		}
 
 		if len(r.Captures) > 0 {
-			test.Captures = r.Captures
+			test.Captures = maskedCaptures(r)
 		}
 
 		f.results = append(f.results, test)
+		if g := groups[r.Group]; g != nil {
+			g.Tests = append(g.Tests, test)
+		}
+	}
+
+	for _, g := range result.Groups {
+		f.groups = append(f.groups, *groups[g.Name])
+	}
+}
+
+// jsonTiming converts a response's timings to fractional milliseconds, since
+// phases such as DNS on a local network are often well under 1ms
+func jsonTiming(resp *http.Response) *JSONTiming {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	t := resp.Timing
+	return &JSONTiming{
+		DNS:      ms(t.DNS),
+		Connect:  ms(t.Connect),
+		TLS:      ms(t.TLS),
+		TTFB:     ms(t.TTFB),
+		Transfer: ms(t.Transfer),
+		Total:    ms(resp.Duration + t.Transfer),
 	}
 }
 
@@ -157,12 +233,14 @@ func (f *JSONFormatter) FormatHeader(version string) {
 
 // Flush writes the accumulated JSON output
 func (f *JSONFormatter) Flush(totalDuration time.Duration) error {
-	var passed, failed, skipped int
+	var passed, failed, softFailed, skipped int
 	for _, t := range f.results {
 		if t.Skipped {
 			skipped++
 		} else if t.Passed {
 			passed++
+		} else if t.SoftFailed {
+			softFailed++
 		} else {
 			failed++
 		}
@@ -172,10 +250,12 @@ func (f *JSONFormatter) Flush(totalDuration time.Duration) error {
 		Summary: JSONSummary{
 			Total:   len(f.results),
 			Passed:  passed,
-			Failed:  failed,
-			Skipped: skipped,
+			Failed:     failed,
+			SoftFailed: softFailed,
+			Skipped:    skipped,
 		},
 		Tests:    f.results,
+		Groups:   f.groups,
 		Duration: float64(totalDuration.Milliseconds()),
 		Time:     time.Now().Format(time.RFC3339),
 	}
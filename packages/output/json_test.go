@@ -0,0 +1,128 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJSONFormatter_DiffSchema guards the fields `hitspec diff` reads from
+// `hitspec run --output json` result files
+func TestJSONFormatter_DiffSchema(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJSONFormatter(JSONWithWriter(&buf))
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	require.NoError(t, f.Flush(250*time.Millisecond))
+
+	var raw struct {
+		Summary  map[string]any   `json:"summary"`
+		Tests    []map[string]any `json:"tests"`
+		Duration float64          `json:"duration"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+
+	assert.Equal(t, float64(250), raw.Duration)
+	for _, key := range []string{"total", "passed", "failed", "skipped"} {
+		assert.Contains(t, raw.Summary, key)
+	}
+	require.Len(t, raw.Tests, 3)
+	for _, test := range raw.Tests {
+		for _, key := range []string{"name", "file", "passed", "duration"} {
+			assert.Contains(t, test, key)
+		}
+	}
+	assert.Equal(t, "getUser", raw.Tests[1]["name"])
+	assert.Equal(t, "tests/users.http", raw.Tests[1]["file"])
+	assert.Equal(t, float64(80), raw.Tests[1]["duration"])
+}
+
+func TestJSONFormatter_ResponseDetails(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJSONFormatter(JSONWithWriter(&buf))
+	f.FormatResult(&runner.RunResult{
+		File: "tests/users.http",
+		Results: []*runner.RequestResult{{
+			Name:   "getUser",
+			Passed: true,
+			Response: &http.Response{
+				StatusCode: 201,
+				Body:       []byte(`{"id":1}`),
+				Duration:   40 * time.Millisecond,
+				Timing: http.Timing{
+					DNS:      1500 * time.Microsecond,
+					Connect:  2 * time.Millisecond,
+					TTFB:     30 * time.Millisecond,
+					Transfer: 5 * time.Millisecond,
+				},
+			},
+		}},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	var out JSONOutput
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out.Tests, 1)
+
+	test := out.Tests[0]
+	assert.Equal(t, 201, test.Status)
+	assert.Equal(t, 8, test.ResponseSize)
+	require.NotNil(t, test.Timing)
+	assert.Equal(t, JSONTiming{DNS: 1.5, Connect: 2, TTFB: 30, Transfer: 5, Total: 45}, *test.Timing)
+}
+
+func TestJSONFormatter_Groups(t *testing.T) {
+	cart := &runner.RequestResult{Name: "createCart", Group: "checkout", Passed: true}
+	pay := &runner.RequestResult{Name: "pay", Group: "checkout", Passed: true}
+	var buf bytes.Buffer
+	f := NewJSONFormatter(JSONWithWriter(&buf))
+	f.FormatResult(&runner.RunResult{
+		File:    "tests/checkout.http",
+		Results: []*runner.RequestResult{cart, {Name: "health", Passed: true}, pay},
+		Groups:  []*runner.GroupResult{{Name: "checkout", Passed: true, Results: []*runner.RequestResult{cart, pay}}},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	var out JSONOutput
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out.Tests, 3)
+	assert.Equal(t, "checkout", out.Tests[0].Group)
+	assert.Empty(t, out.Tests[1].Group)
+	require.Len(t, out.Groups, 1)
+	assert.Equal(t, "checkout", out.Groups[0].Name)
+	assert.Equal(t, "tests/checkout.http", out.Groups[0].File)
+	assert.True(t, out.Groups[0].Passed)
+	require.Len(t, out.Groups[0].Tests, 2)
+	assert.Equal(t, "pay", out.Groups[0].Tests[1].Name)
+}
+
+func TestJSONFormatter_AssertionCounts(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJSONFormatter(JSONWithWriter(&buf))
+	f.FormatResult(&runner.RunResult{
+		File: "tests/users.http",
+		Results: []*runner.RequestResult{
+			{Name: "getUser", Assertions: []*assertions.Result{
+				{Subject: "status", Operator: "==", Passed: true},
+				{Subject: "body.id", Operator: "==", Passed: false},
+				{Subject: "body.name", Operator: "==", Passed: false},
+			}},
+			{Name: "health", Passed: true},
+		},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	var out JSONOutput
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out.Tests, 2)
+	assert.Equal(t, &JSONAssertionCounts{Total: 3, Passed: 1, Failed: 2}, out.Tests[0].AssertionCounts)
+	assert.Nil(t, out.Tests[1].AssertionCounts)
+}
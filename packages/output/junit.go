@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -48,6 +49,7 @@ type JUnitTestCase struct {
 	Failure   *JUnitFailure `xml:"failure,omitempty"`
 	Error     *JUnitError   `xml:"error,omitempty"`
 	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"` // Failed assertions of a @soft request
 }
 
 // JUnitFailure represents a test failure
@@ -71,8 +73,14 @@ type JUnitSkipped struct {
 
 // JUnitFormatter formats test results as JUnit XML
 type JUnitFormatter struct {
-	writer     io.Writer
-	testSuites []JUnitTestSuite
+	writer      io.Writer
+	testSuites  []JUnitTestSuite
+	singleSuite bool
+
+	// With JUnitWithDir, each file's suite is written to its own file in dir
+	dir      string
+	dirFiles map[string]bool // Report file names written so far
+	dirErr   error           // First write error, returned by Flush
 }
 
 type JUnitOption func(*JUnitFormatter)
@@ -94,12 +102,44 @@ func JUnitWithWriter(w io.Writer) JUnitOption {
 	}
 }
 
+// JUnitWithSuitePerFile emits one <testsuite> per .http file, the default.
+// It undoes an earlier JUnitWithSingleSuite. Test cases always carry the file
+// path as their classname so CI report ingesters can group them by file.
+func JUnitWithSuitePerFile() JUnitOption {
+	return func(f *JUnitFormatter) {
+		f.singleSuite = false
+	}
+}
+
+// JUnitWithSingleSuite merges every file into a single "hitspec"
+// <testsuite> instead of one suite per file
+func JUnitWithSingleSuite() JUnitOption {
+	return func(f *JUnitFormatter) {
+		f.singleSuite = true
+	}
+}
+
+// JUnitWithDir writes a self-contained JUnit XML file per .http file into dir,
+// named TEST-<path>.xml after the file's path, as each file finishes. Nothing
+// is written to the writer.
+func JUnitWithDir(dir string) JUnitOption {
+	return func(f *JUnitFormatter) {
+		f.dir = dir
+		f.singleSuite = false
+		f.dirFiles = make(map[string]bool)
+	}
+}
+
 func (f *JUnitFormatter) FormatResult(result *runner.RunResult) {
+	// Runs over several environments get a suite per file and environment
+	name := result.File
+	if result.Environment != "" {
+		name += " [" + result.Environment + "]"
+	}
+
 	suite := JUnitTestSuite{
-		Name:      result.File,
+		Name:      name,
 		Tests:     len(result.Results),
-		Failures:  result.Failed,
-		Skipped:   result.Skipped,
 		Time:      result.Duration.Seconds(),
 		Timestamp: time.Now().Format(time.RFC3339),
 		TestCases: make([]JUnitTestCase, 0, len(result.Results)),
@@ -107,14 +147,21 @@ func (f *JUnitFormatter) FormatResult(result *runner.RunResult) {
 
 	for _, r := range result.Results {
 		tc := JUnitTestCase{
-			Name:      r.Name,
-			ClassName: result.File,
+			Name:      junitTestName(r),
+			ClassName: name,
 			Time:      r.Duration.Seconds(),
 		}
 
 		if r.Skipped {
+			// Always emit a reason so CI reports show why the case didn't run
+			// (@skip, name/tag filters, failed dependencies)
+			reason := r.SkipReason
+			if reason == "" {
+				reason = "skipped"
+			}
+			suite.Skipped++
 			tc.Skipped = &JUnitSkipped{
-				Message: r.SkipReason,
+				Message: reason,
 			}
 		} else if r.Error != nil {
 			suite.Errors++
@@ -122,28 +169,77 @@ func (f *JUnitFormatter) FormatResult(result *runner.RunResult) {
 				Message: r.Error.Error(),
 				Type:    "Error",
 			}
+		} else if r.SoftFailed {
+			// Soft failures don't fail the run, so they aren't reported as
+			// <failure> elements that would fail the CI report
+			_, details := junitFailedAssertions(r)
+			tc.SystemOut = "soft failure (@soft):\n" + details
 		} else if !r.Passed {
-			// Collect failure messages from assertions
-			var failureMsg strings.Builder
-			for _, a := range r.Assertions {
-				if !a.Passed {
-					fmt.Fprintf(&failureMsg, "%s %s: expected %v, got %v. %s\n",
-						a.Subject, a.Operator, a.Expected, a.Actual, a.Message)
-				}
-			}
+			suite.Failures++
+			message, details := junitFailedAssertions(r)
 			tc.Failure = &JUnitFailure{
-				Message: "Assertion failed",
+				Message: message,
 				Type:    "AssertionError",
-				Content: failureMsg.String(),
+				Content: details,
 			}
 		}
 
 		suite.TestCases = append(suite.TestCases, tc)
 	}
 
+	if f.dir != "" {
+		if err := f.writeSuiteFile(suite); err != nil && f.dirErr == nil {
+			f.dirErr = err
+		}
+		return
+	}
+
+	if f.singleSuite && len(f.testSuites) > 0 {
+		merged := &f.testSuites[0]
+		merged.Tests += suite.Tests
+		merged.Failures += suite.Failures
+		merged.Errors += suite.Errors
+		merged.Skipped += suite.Skipped
+		merged.Time += suite.Time
+		merged.TestCases = append(merged.TestCases, suite.TestCases...)
+		return
+	}
+	if f.singleSuite {
+		suite.Name = "hitspec"
+	}
+
 	f.testSuites = append(f.testSuites, suite)
 }
 
+// junitFailedAssertions returns a message naming the first failed assertion
+// of r and a line for each failed assertion
+func junitFailedAssertions(r *runner.RequestResult) (string, string) {
+	message := "Assertion failed"
+	var details strings.Builder
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			if details.Len() == 0 && a.Message != "" {
+				message = fmt.Sprintf("%s %s: %s", a.Subject, a.Operator, a.Message)
+			}
+			fmt.Fprintf(&details, "%s %s: expected %v, got %v. %s\n",
+				a.Subject, a.Operator, a.Expected, a.Actual, a.Message)
+		}
+	}
+	return message, details.String()
+}
+
+// junitTestName returns the request name, falling back to the method and URL
+// for anonymous requests so every test case has a readable name.
+func junitTestName(r *runner.RequestResult) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Request != nil {
+		return r.Request.Method + " " + r.Request.URL
+	}
+	return "(unnamed)"
+}
+
 func (f *JUnitFormatter) FormatError(err error) {
 	// Errors are included in individual test cases
 }
@@ -152,10 +248,60 @@ func (f *JUnitFormatter) FormatHeader(version string) {
 	// No header needed for JUnit XML
 }
 
-// Flush writes the accumulated JUnit XML output
+// writeSuiteFile writes a suite to its own report file in the directory
+func (f *JUnitFormatter) writeSuiteFile(suite JUnitTestSuite) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return fmt.Errorf("cannot create JUnit directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(junitFileName(suite.Name), ".xml")
+	name := base + ".xml"
+	for i := 2; f.dirFiles[name]; i++ {
+		name = fmt.Sprintf("%s-%d.xml", base, i)
+	}
+	f.dirFiles[name] = true
+
+	file, err := os.Create(filepath.Join(f.dir, name))
+	if err != nil {
+		return fmt.Errorf("cannot create JUnit file: %w", err)
+	}
+	defer file.Close()
+	return writeJUnit(file, []JUnitTestSuite{suite}, time.Duration(suite.Time*float64(time.Second)))
+}
+
+// junitFileName turns a .http file path into a report file name, e.g.
+// tests/users.http -> TEST-tests_users.xml, or for the suite of an
+// environment, tests/users.http [staging] -> TEST-tests_users_staging.xml
+func junitFileName(path string) string {
+	path, env, _ := strings.Cut(path, " [")
+	path = filepath.ToSlash(filepath.Clean(path))
+	path = strings.TrimSuffix(path, filepath.Ext(path))
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ':' })
+	var kept []string
+	for _, part := range parts {
+		if part != "." && part != ".." {
+			kept = append(kept, part)
+		}
+	}
+	if env = strings.TrimSuffix(env, "]"); env != "" {
+		kept = append(kept, env)
+	}
+	return "TEST-" + strings.Join(kept, "_") + ".xml"
+}
+
+// Flush writes the accumulated JUnit XML output, or with JUnitWithDir reports
+// the first error writing a report file
 func (f *JUnitFormatter) Flush(totalDuration time.Duration) error {
+	if f.dir != "" {
+		return f.dirErr
+	}
+	return writeJUnit(f.writer, f.testSuites, totalDuration)
+}
+
+// writeJUnit writes suites as a <testsuites> document
+func writeJUnit(w io.Writer, testSuites []JUnitTestSuite, totalDuration time.Duration) error {
 	var totalTests, totalFailures, totalErrors, totalSkipped int
-	for _, suite := range f.testSuites {
+	for _, suite := range testSuites {
 		totalTests += suite.Tests
 		totalFailures += suite.Failures
 		totalErrors += suite.Errors
@@ -170,11 +316,11 @@ func (f *JUnitFormatter) Flush(totalDuration time.Duration) error {
 		Skipped:    totalSkipped,
 		Time:       totalDuration.Seconds(),
 		Timestamp:  time.Now().Format(time.RFC3339),
-		TestSuites: f.testSuites,
+		TestSuites: testSuites,
 	}
 
-	fmt.Fprintf(f.writer, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	encoder := xml.NewEncoder(f.writer)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	encoder := xml.NewEncoder(w)
 	encoder.Indent("", "  ")
 	return encoder.Encode(suites)
 }
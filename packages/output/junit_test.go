@@ -0,0 +1,213 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/abdul-hamid-achik/hitspec/packages/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func junitRunResults() []*runner.RunResult {
+	return []*runner.RunResult{
+		{
+			File:     "tests/users.http",
+			Duration: 200 * time.Millisecond,
+			Passed:   1,
+			Failed:   1,
+			Results: []*runner.RequestResult{
+				{Name: "listUsers", Passed: true, Duration: 50 * time.Millisecond},
+				{
+					Name:     "getUser",
+					Duration: 80 * time.Millisecond,
+					Assertions: []*assertions.Result{
+						{Subject: "status", Operator: "==", Expected: 200, Actual: 404, Message: "expected 200, got 404"},
+					},
+				},
+			},
+		},
+		{
+			File:     "tests/health.http",
+			Duration: 10 * time.Millisecond,
+			Passed:   1,
+			Results: []*runner.RequestResult{
+				{Passed: true, Request: &http.Request{Method: "GET", URL: "http://localhost/health"}},
+			},
+		},
+	}
+}
+
+func decodeJUnit(t *testing.T, data []byte) JUnitTestSuites {
+	t.Helper()
+	var suites JUnitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &suites))
+	return suites
+}
+
+func TestJUnitFormatter_SuitePerFile(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf), JUnitWithSuitePerFile())
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	require.NoError(t, f.Flush(time.Second))
+
+	suites := decodeJUnit(t, buf.Bytes())
+	require.Len(t, suites.TestSuites, 2)
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+
+	users := suites.TestSuites[0]
+	assert.Equal(t, "tests/users.http", users.Name)
+	require.Len(t, users.TestCases, 2)
+	assert.Equal(t, "getUser", users.TestCases[1].Name)
+	assert.Equal(t, "tests/users.http", users.TestCases[1].ClassName)
+	require.NotNil(t, users.TestCases[1].Failure)
+	assert.Equal(t, "status ==: expected 200, got 404", users.TestCases[1].Failure.Message)
+
+	health := suites.TestSuites[1]
+	assert.Equal(t, "GET http://localhost/health", health.TestCases[0].Name)
+	assert.Equal(t, "tests/health.http", health.TestCases[0].ClassName)
+}
+
+func TestJUnitFormatter_SuitePerFileByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf))
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	require.NoError(t, f.Flush(time.Second))
+
+	suites := decodeJUnit(t, buf.Bytes())
+	require.Len(t, suites.TestSuites, 2)
+	assert.Equal(t, "tests/users.http", suites.TestSuites[0].Name)
+	assert.Equal(t, "tests/health.http", suites.TestSuites[1].Name)
+}
+
+func TestJUnitFormatter_SingleSuite(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf), JUnitWithSingleSuite())
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	require.NoError(t, f.Flush(time.Second))
+
+	assert.True(t, strings.HasPrefix(buf.String(), "<?xml"))
+	suites := decodeJUnit(t, buf.Bytes())
+	require.Len(t, suites.TestSuites, 1)
+	assert.Equal(t, "hitspec", suites.TestSuites[0].Name)
+	assert.Equal(t, 3, suites.TestSuites[0].Tests)
+	assert.Len(t, suites.TestSuites[0].TestCases, 3)
+	assert.Equal(t, "tests/health.http", suites.TestSuites[0].TestCases[2].ClassName)
+}
+
+func TestJUnitFormatter_SkippedCases(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf), JUnitWithSuitePerFile())
+	f.FormatResult(&runner.RunResult{
+		File:    "tests/skip.http",
+		Skipped: 3,
+		Failed:  1,
+		Results: []*runner.RequestResult{
+			{Name: "disabled", Skipped: true, SkipReason: "flaky upstream"},
+			{Name: "filtered", Skipped: true, SkipReason: "filtered out"},
+			{Name: "dependent", Skipped: true, SkipReason: "dependency failed"},
+			{Name: "broken", Error: assert.AnError},
+		},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	suites := decodeJUnit(t, buf.Bytes())
+	require.Len(t, suites.TestSuites, 1)
+	suite := suites.TestSuites[0]
+	assert.Equal(t, 3, suite.Skipped)
+	assert.Equal(t, 1, suite.Errors)
+	assert.Equal(t, 0, suite.Failures)
+
+	reasons := make(map[string]string)
+	for _, tc := range suite.TestCases {
+		if tc.Skipped != nil {
+			reasons[tc.Name] = tc.Skipped.Message
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"disabled":  "flaky upstream",
+		"filtered":  "filtered out",
+		"dependent": "dependency failed",
+	}, reasons)
+}
+
+func TestJUnitFormatter_Dir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf), JUnitWithDir(dir))
+	for _, r := range junitRunResults() {
+		f.FormatResult(r)
+	}
+	f.FormatResult(&runner.RunResult{File: "tests/users.hitspec", Passed: 1, Results: []*runner.RequestResult{{Name: "me", Passed: true}}})
+	require.NoError(t, f.Flush(time.Second))
+	assert.Empty(t, buf.String())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"TEST-tests_health.xml", "TEST-tests_users-2.xml", "TEST-tests_users.xml"}, names)
+
+	data, err := os.ReadFile(filepath.Join(dir, "TEST-tests_users.xml"))
+	require.NoError(t, err)
+	suites := decodeJUnit(t, data)
+	assert.Equal(t, 2, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	require.Len(t, suites.TestSuites, 1)
+	assert.Equal(t, "tests/users.http", suites.TestSuites[0].Name)
+}
+
+func TestJUnitFileName(t *testing.T) {
+	assert.Equal(t, "TEST-tests_users.xml", junitFileName("tests/users.http"))
+	assert.Equal(t, "TEST-api_orders.xml", junitFileName("./api/orders.hitspec"))
+	assert.Equal(t, "TEST-tmp_suite_a.xml", junitFileName("/tmp/suite/a.http"))
+	assert.Equal(t, "TEST-tests_users_staging.xml", junitFileName("tests/users.http [staging]"))
+	assert.Equal(t, "TEST-shared_auth.xml", junitFileName("../shared/auth.http"))
+}
+
+func TestJUnitFormatter_SoftFailure(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewJUnitFormatter(JUnitWithWriter(&buf))
+	f.FormatResult(&runner.RunResult{
+		File:       "tests/soft.http",
+		Passed:     1,
+		SoftFailed: 1,
+		Results: []*runner.RequestResult{
+			{Name: "ok", Passed: true},
+			{
+				Name:       "flaky",
+				SoftFailed: true,
+				Assertions: []*assertions.Result{
+					{Subject: "duration", Operator: "<", Expected: 100, Actual: 250, Message: "expected < 100, got 250"},
+				},
+			},
+		},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	suites := decodeJUnit(t, buf.Bytes())
+	assert.Equal(t, 0, suites.Failures)
+	suite := suites.TestSuites[0]
+	assert.Equal(t, 0, suite.Failures)
+	require.Len(t, suite.TestCases, 2)
+	soft := suite.TestCases[1]
+	assert.Nil(t, soft.Failure)
+	assert.Contains(t, soft.SystemOut, "soft failure")
+	assert.Contains(t, soft.SystemOut, "duration <: expected 100, got 250")
+}
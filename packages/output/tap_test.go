@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
+	"github.com/abdul-hamid-achik/hitspec/packages/core/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTAPFormatter_Version14(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewTAPFormatter(TAPWithWriter(&buf), TAPWithVersion(14))
+	f.FormatResult(&runner.RunResult{
+		File: "tests/users.http",
+		Results: []*runner.RequestResult{
+			{Name: "listUsers", Passed: true},
+			{
+				Name: "getUser",
+				Assertions: []*assertions.Result{
+					{Subject: "status", Operator: "==", Expected: 200, Actual: 404, Message: "expected 200, got 404"},
+				},
+			},
+		},
+	})
+	f.FormatResult(&runner.RunResult{
+		File: "tests/health.http",
+		Results: []*runner.RequestResult{
+			{Name: "health", Passed: true},
+			{Name: "legacy", Skipped: true, SkipReason: "deprecated"},
+		},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	expected := `TAP version 14
+1..2
+# Subtest: tests/users.http
+    1..2
+    ok 1 - listUsers
+    not ok 2 - getUser
+      ---
+      message: 1 assertion(s) failed
+      severity: fail
+      failures:
+          - subject: status
+            operator: ==
+            expected: 200
+            actual: 404
+            message: expected 200, got 404
+      ...
+not ok 1 - tests/users.http
+# Subtest: tests/health.http
+    1..2
+    ok 1 - health
+    ok 2 - legacy # SKIP deprecated
+ok 2 - tests/health.http
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestTAPFormatter_DefaultVersion13(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewTAPFormatter(TAPWithWriter(&buf))
+	f.FormatResult(&runner.RunResult{
+		File:    "tests/health.http",
+		Results: []*runner.RequestResult{{Name: "health", Passed: true}},
+	})
+	require.NoError(t, f.Flush(time.Second))
+
+	assert.Equal(t, "TAP version 13\n1..1\nok 1 - health\n\n", buf.String())
+}
+
+func TestTAPFormatter_SkipWithoutReason(t *testing.T) {
+	result := &runner.RunResult{
+		File: "tests/health.http",
+		Results: []*runner.RequestResult{
+			{Name: "legacy", Skipped: true},
+			{Name: "other", Skipped: true, SkipReason: "filtered out"},
+			{Name: "flaky", Skipped: true, SkipReason: "upstream down"},
+		},
+	}
+
+	var buf bytes.Buffer
+	f := NewTAPFormatter(TAPWithWriter(&buf))
+	f.FormatResult(result)
+	require.NoError(t, f.Flush(time.Second))
+	assert.Equal(t, "TAP version 13\n1..3\nok 1 - legacy # SKIP\nok 2 - other # SKIP\nok 3 - flaky # SKIP upstream down\n\n", buf.String())
+
+	buf.Reset()
+	f = NewTAPFormatter(TAPWithWriter(&buf), TAPWithVersion(14))
+	f.FormatResult(result)
+	require.NoError(t, f.Flush(time.Second))
+	assert.Contains(t, buf.String(), "    ok 1 - legacy # SKIP\n")
+	assert.Contains(t, buf.String(), "    ok 3 - flaky # SKIP upstream down\n")
+	assert.NotContains(t, buf.String(), "SKIP SKIP")
+}
@@ -23,16 +23,17 @@ const (
 
 // Config holds all configuration for a stress test
 type Config struct {
-	Mode       ExecutionMode
-	Duration   time.Duration
-	Rate       float64       // requests per second (RateMode)
-	VUs        int           // number of virtual users (VUMode)
-	MaxVUs     int           // max concurrent requests
-	ThinkTime  time.Duration // time between requests per VU
-	RampUp     time.Duration // ramp-up time
-	Warmup     time.Duration // warmup time (not counted in metrics)
-	Phases     []Phase       // multi-phase execution
-	Thresholds Thresholds    // pass/fail thresholds
+	Mode         ExecutionMode
+	Duration     time.Duration
+	Rate         float64       // requests per second (RateMode)
+	VUs          int           // number of virtual users (VUMode)
+	MaxVUs       int           // max concurrent requests
+	ThinkTime    time.Duration // time between requests per VU
+	RampUp       time.Duration // ramp-up time
+	Warmup       time.Duration // warmup time (not counted in metrics)
+	Phases       []Phase       // multi-phase execution
+	Thresholds   Thresholds    // pass/fail thresholds
+	AssertSample float64       // fraction of responses whose assertions are evaluated (0 - 1)
 }
 
 // Phase defines a test phase with specific settings
@@ -92,6 +93,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("rampUp cannot exceed duration")
 	}
 
+	if c.AssertSample < 0 || c.AssertSample > 1 {
+		return fmt.Errorf("assert sample must be between 0 and 1")
+	}
+
 	return nil
 }
 
@@ -118,10 +123,11 @@ func ParseThresholds(s string) (Thresholds, error) {
 	return t, nil
 }
 
+// thresholdPart matches patterns like "p95<200ms", "errors<0.1%", "rps>50"
+var thresholdPart = regexp.MustCompile(`^(\w+)\s*([<>]=?)\s*(.+)$`)
+
 func parseThresholdPart(part string, t *Thresholds) error {
-	// Match patterns like "p95<200ms", "errors<0.1%", "rps>50"
-	re := regexp.MustCompile(`^(\w+)\s*([<>]=?)\s*(.+)$`)
-	matches := re.FindStringSubmatch(part)
+	matches := thresholdPart.FindStringSubmatch(part)
 	if len(matches) != 4 {
 		return fmt.Errorf("invalid threshold format: %s", part)
 	}
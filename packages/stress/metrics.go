@@ -19,6 +19,10 @@ type Metrics struct {
 	errorRequests   atomic.Int64
 	timeoutRequests atomic.Int64
 
+	// Responses whose assertions were evaluated, and those that failed them
+	assertionSamples  atomic.Int64
+	assertionFailures atomic.Int64
+
 	// Latency histogram (in microseconds for precision)
 	histogram *hdrhistogram.Histogram
 
@@ -35,6 +39,12 @@ type Metrics struct {
 
 	// Active VUs
 	activeVUs atomic.Int32
+
+	// Connections
+	newConns      atomic.Int64
+	reusedConns   atomic.Int64
+	openConns     atomic.Int64
+	peakOpenConns atomic.Int64
 }
 
 // RequestMetrics holds metrics for a specific request
@@ -57,6 +67,7 @@ type TimePoint struct {
 	P99         time.Duration
 	ActiveVUs   int32
 	RPS         float64
+	OpenConns   int64
 }
 
 // NewMetrics creates a new Metrics collector
@@ -141,6 +152,15 @@ func (m *Metrics) recordRequestMetrics(name string, duration time.Duration, err
 	rm.mu.Unlock()
 }
 
+// RecordAssertions records that a sampled response's assertions were
+// evaluated. The request itself is recorded by Record.
+func (m *Metrics) RecordAssertions(passed bool) {
+	m.assertionSamples.Add(1)
+	if !passed {
+		m.assertionFailures.Add(1)
+	}
+}
+
 // RecordTimeout records a timeout
 func (m *Metrics) RecordTimeout(name string) {
 	m.totalRequests.Add(1)
@@ -179,6 +199,31 @@ func (m *Metrics) DecrementActiveVUs() {
 	m.activeVUs.Add(-1)
 }
 
+// ConnOpened records a newly dialed connection
+func (m *Metrics) ConnOpened() {
+	open := m.openConns.Add(1)
+	for {
+		peak := m.peakOpenConns.Load()
+		if open <= peak || m.peakOpenConns.CompareAndSwap(peak, open) {
+			return
+		}
+	}
+}
+
+// ConnClosed records a closed connection
+func (m *Metrics) ConnClosed() {
+	m.openConns.Add(-1)
+}
+
+// ConnUsed records whether a request got a new or a reused keep-alive connection
+func (m *Metrics) ConnUsed(reused bool) {
+	if reused {
+		m.reusedConns.Add(1)
+	} else {
+		m.newConns.Add(1)
+	}
+}
+
 // Snapshot captures current metrics for time series
 func (m *Metrics) Snapshot() TimePoint {
 	now := time.Now()
@@ -208,6 +253,7 @@ func (m *Metrics) Snapshot() TimePoint {
 		P99:       time.Duration(m.histogram.ValueAtQuantile(99)) * time.Microsecond,
 		ActiveVUs: m.activeVUs.Load(),
 		RPS:       rps,
+		OpenConns: m.openConns.Load(),
 	}
 
 	return point
@@ -244,6 +290,16 @@ type Summary struct {
 	Mean    time.Duration
 	StdDev  time.Duration
 
+	// Connections
+	NewConns      int64 // Requests sent on a newly dialed connection
+	ReusedConns   int64 // Requests sent on a reused keep-alive connection
+	PeakOpenConns int64
+	ConnReuseRate float64
+
+	// Assertions evaluated on sampled responses (--assert-sample)
+	AssertionSamples  int64
+	AssertionFailures int64
+
 	// Per-request breakdown
 	RequestBreakdown map[string]*RequestSummary
 
@@ -307,6 +363,14 @@ func (m *Metrics) GetSummary() *Summary {
 		Mean:          time.Duration(m.histogram.Mean()) * time.Microsecond,
 		StdDev:        time.Duration(m.histogram.StdDev()) * time.Microsecond,
 		TimeSeries:    m.timeSeries,
+		NewConns:      m.newConns.Load(),
+		ReusedConns:   m.reusedConns.Load(),
+		PeakOpenConns: m.peakOpenConns.Load(),
+	}
+	summary.AssertionSamples = m.assertionSamples.Load()
+	summary.AssertionFailures = m.assertionFailures.Load()
+	if used := summary.NewConns + summary.ReusedConns; used > 0 {
+		summary.ConnReuseRate = float64(summary.ReusedConns) / float64(used)
 	}
 
 	// Per-request breakdown
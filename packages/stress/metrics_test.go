@@ -58,6 +58,29 @@ func TestMetricsActiveVUs(t *testing.T) {
 	assert.Equal(t, int32(10), m.GetCurrentStats().ActiveVUs)
 }
 
+func TestMetricsConnections(t *testing.T) {
+	m := NewMetrics()
+	m.Start()
+
+	m.ConnOpened()
+	m.ConnOpened()
+	m.ConnClosed()
+	m.ConnOpened()
+	m.ConnUsed(false)
+	m.ConnUsed(false)
+	m.ConnUsed(true)
+	m.ConnUsed(true)
+	m.ConnUsed(true)
+
+	assert.Equal(t, int64(2), m.Snapshot().OpenConns)
+
+	summary := m.GetSummary()
+	assert.Equal(t, int64(2), summary.NewConns)
+	assert.Equal(t, int64(3), summary.ReusedConns)
+	assert.Equal(t, int64(2), summary.PeakOpenConns)
+	assert.InDelta(t, 0.6, summary.ConnReuseRate, 0.001)
+}
+
 func TestMetricsSummary(t *testing.T) {
 	m := NewMetrics()
 	m.Start()
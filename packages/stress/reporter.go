@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
 	"github.com/fatih/color"
 )
 
@@ -17,6 +19,7 @@ type Reporter struct {
 	noColor    bool
 	noProgress bool
 	verbose    bool
+	showEnv    bool
 
 	// Colors
 	green  *color.Color
@@ -58,6 +61,14 @@ func WithVerbose(verbose bool) ReporterOption {
 	}
 }
 
+// WithShowEnvironment prints the active environment and its variables, with
+// secret values masked, below the header
+func WithShowEnvironment(show bool) ReporterOption {
+	return func(r *Reporter) {
+		r.showEnv = show
+	}
+}
+
 // NewReporter creates a new reporter
 func NewReporter(opts ...ReporterOption) *Reporter {
 	r := &Reporter{
@@ -112,6 +123,30 @@ func (r *Reporter) Header(version string, files []string, config *Config) {
 	_, _ = fmt.Fprintln(r.writer)
 }
 
+// Environment prints the active environment name and its variables. Values of
+// sensitive variables (tokens, passwords, keys, ...) are masked. It only prints
+// when the reporter was created with WithShowEnvironment.
+func (r *Reporter) Environment(name string, vars map[string]any) {
+	if !r.showEnv {
+		return
+	}
+
+	if name == "" {
+		name = "(none)"
+	}
+	_, _ = r.cyan.Fprintf(r.writer, "Environment: %s\n", name)
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(r.writer, "  %s = %s\n", k, env.MaskValue(k, vars[k]))
+	}
+	_, _ = fmt.Fprintln(r.writer)
+}
+
 // Progress prints real-time progress
 func (r *Reporter) Progress(stats CurrentStats, duration time.Duration) {
 	if r.noProgress {
@@ -197,6 +232,15 @@ func (r *Reporter) Summary(summary *Summary, thresholdResults []ThresholdResult)
 		_, _ = r.yellow.Fprintf(r.writer, "%s\n", formatNumber(summary.TimeoutCount))
 	}
 
+	if summary.AssertionSamples > 0 {
+		_, _ = fmt.Fprintf(r.writer, "Assertions: %s sampled, ", formatNumber(summary.AssertionSamples))
+		if summary.AssertionFailures > 0 {
+			_, _ = r.red.Fprintf(r.writer, "%s failed\n", formatNumber(summary.AssertionFailures))
+		} else {
+			_, _ = fmt.Fprintf(r.writer, "0 failed\n")
+		}
+	}
+
 	// Latency
 	_, _ = fmt.Fprintln(r.writer)
 	_, _ = r.bold.Fprintln(r.writer, "LATENCY (ms)")
@@ -210,6 +254,17 @@ func (r *Reporter) Summary(summary *Summary, thresholdResults []ThresholdResult)
 		formatLatencyMs(summary.Mean),
 		formatLatencyMs(summary.StdDev))
 
+	// Connections
+	if summary.NewConns+summary.ReusedConns > 0 {
+		_, _ = fmt.Fprintln(r.writer)
+		_, _ = r.bold.Fprintln(r.writer, "CONNECTIONS")
+		_, _ = fmt.Fprintf(r.writer, "  new: %s | reused: %s (%.1f%%) | peak open: %s\n",
+			formatNumber(summary.NewConns),
+			formatNumber(summary.ReusedConns),
+			summary.ConnReuseRate*100,
+			formatNumber(summary.PeakOpenConns))
+	}
+
 	// Per-request breakdown (if verbose)
 	if r.verbose && len(summary.RequestBreakdown) > 0 {
 		_, _ = fmt.Fprintln(r.writer)
@@ -249,64 +304,12 @@ func (r *Reporter) Summary(summary *Summary, thresholdResults []ThresholdResult)
 	_, _ = fmt.Fprintln(r.writer)
 }
 
-// JSONSummary outputs the summary as JSON
-func (r *Reporter) JSONSummary(summary *Summary, thresholdResults []ThresholdResult) error {
-	output := map[string]interface{}{
-		"duration": summary.Duration.String(),
-		"requests": map[string]interface{}{
-			"total":    summary.TotalRequests,
-			"success":  summary.SuccessCount,
-			"failed":   summary.ErrorCount,
-			"timeouts": summary.TimeoutCount,
-		},
-		"rates": map[string]interface{}{
-			"rps":         summary.RPS,
-			"successRate": summary.SuccessRate,
-			"errorRate":   summary.ErrorRate,
-		},
-		"latency": map[string]interface{}{
-			"p50":    summary.P50.Milliseconds(),
-			"p95":    summary.P95.Milliseconds(),
-			"p99":    summary.P99.Milliseconds(),
-			"min":    summary.Min.Milliseconds(),
-			"max":    summary.Max.Milliseconds(),
-			"mean":   summary.Mean.Milliseconds(),
-			"stddev": summary.StdDev.Milliseconds(),
-		},
-	}
-
-	if len(thresholdResults) > 0 {
-		thresholds := make([]map[string]interface{}, len(thresholdResults))
-		for i, tr := range thresholdResults {
-			thresholds[i] = map[string]interface{}{
-				"name":     tr.Name,
-				"passed":   tr.Passed,
-				"expected": tr.Expected,
-				"actual":   tr.Actual,
-			}
-		}
-		output["thresholds"] = thresholds
-	}
-
-	if len(summary.RequestBreakdown) > 0 {
-		breakdown := make(map[string]interface{})
-		for name, rs := range summary.RequestBreakdown {
-			breakdown[name] = map[string]interface{}{
-				"total":   rs.Total,
-				"success": rs.Success,
-				"errors":  rs.Errors,
-				"p50":     rs.P50.Milliseconds(),
-				"p95":     rs.P95.Milliseconds(),
-				"p99":     rs.P99.Milliseconds(),
-				"mean":    rs.Mean.Milliseconds(),
-			}
-		}
-		output["requestBreakdown"] = breakdown
-	}
-
+// JSONSummary outputs the summary as a JSONResult, with the time series
+// when timeSeries is true
+func (r *Reporter) JSONSummary(summary *Summary, thresholdResults []ThresholdResult, timeSeries bool) error {
 	encoder := json.NewEncoder(r.writer)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+	return encoder.Encode(NewJSONResult(summary, thresholdResults, timeSeries))
 }
 
 // Error prints an error message
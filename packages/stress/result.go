@@ -0,0 +1,196 @@
+package stress
+
+import (
+	_ "embed"
+	"time"
+)
+
+// ResultSchemaVersion is the version of the JSONResult shape. Adding a field
+// keeps the version; removing a field or changing its meaning bumps it.
+const ResultSchemaVersion = 1
+
+// ResultSchema is the JSON Schema (draft-07) of JSONResult, published as
+// packages/stress/result.schema.json
+//
+//go:embed result.schema.json
+var ResultSchema []byte
+
+// JSONResult is the machine-readable result of a stress test, written by
+// --stress-json. Latencies are in milliseconds.
+type JSONResult struct {
+	SchemaVersion    int                           `json:"schemaVersion"`
+	Passed           bool                          `json:"passed"` // Every threshold passed
+	Duration         string                        `json:"duration"`
+	DurationMs       int64                         `json:"durationMs"`
+	Requests         JSONRequests                  `json:"requests"`
+	Rates            JSONRates                     `json:"rates"`
+	Latency          JSONLatency                   `json:"latency"`
+	Connections      JSONConnections               `json:"connections"`
+	Assertions       *JSONAssertions               `json:"assertions,omitempty"` // Set with --assert-sample
+	Thresholds       []JSONThreshold               `json:"thresholds,omitempty"`
+	RequestBreakdown map[string]JSONRequestSummary `json:"requestBreakdown,omitempty"`
+	TimeSeries       []JSONTimePoint               `json:"timeSeries,omitempty"`
+}
+
+// JSONRequests counts the requests sent
+type JSONRequests struct {
+	Total    int64 `json:"total"`
+	Success  int64 `json:"success"`
+	Failed   int64 `json:"failed"`
+	Timeouts int64 `json:"timeouts"`
+}
+
+// JSONRates holds the throughput and the success and error rates, as
+// fractions between 0 and 1
+type JSONRates struct {
+	RPS         float64 `json:"rps"`
+	SuccessRate float64 `json:"successRate"`
+	ErrorRate   float64 `json:"errorRate"`
+}
+
+// JSONLatency holds the latency distribution in milliseconds
+type JSONLatency struct {
+	P50    int64 `json:"p50"`
+	P95    int64 `json:"p95"`
+	P99    int64 `json:"p99"`
+	Min    int64 `json:"min"`
+	Max    int64 `json:"max"`
+	Mean   int64 `json:"mean"`
+	StdDev int64 `json:"stddev"`
+}
+
+// JSONConnections holds connection reuse statistics
+type JSONConnections struct {
+	New       int64   `json:"new"`
+	Reused    int64   `json:"reused"`
+	ReuseRate float64 `json:"reuseRate"`
+	PeakOpen  int64   `json:"peakOpen"`
+}
+
+// JSONAssertions counts the sampled responses whose assertions were
+// evaluated, and those that failed them
+type JSONAssertions struct {
+	Sampled int64 `json:"sampled"`
+	Failed  int64 `json:"failed"`
+}
+
+// JSONThreshold is the outcome of a --threshold check
+type JSONThreshold struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// JSONRequestSummary holds the statistics of one request of the file
+type JSONRequestSummary struct {
+	Total   int64 `json:"total"`
+	Success int64 `json:"success"`
+	Errors  int64 `json:"errors"`
+	P50     int64 `json:"p50"`
+	P95     int64 `json:"p95"`
+	P99     int64 `json:"p99"`
+	Mean    int64 `json:"mean"`
+}
+
+// JSONTimePoint is one sample of the time series
+type JSONTimePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Requests  int64     `json:"requests"`
+	Errors    int64     `json:"errors"`
+	P50       int64     `json:"p50"`
+	P95       int64     `json:"p95"`
+	P99       int64     `json:"p99"`
+	ActiveVUs int32     `json:"activeVUs"`
+	RPS       float64   `json:"rps"`
+	OpenConns int64     `json:"openConns"`
+}
+
+// NewJSONResult converts a summary and its threshold results to a
+// JSONResult, including the time series when timeSeries is true
+func NewJSONResult(summary *Summary, thresholdResults []ThresholdResult, timeSeries bool) *JSONResult {
+	result := &JSONResult{
+		SchemaVersion: ResultSchemaVersion,
+		Passed:        true,
+		Duration:      summary.Duration.String(),
+		DurationMs:    summary.Duration.Milliseconds(),
+		Requests: JSONRequests{
+			Total:    summary.TotalRequests,
+			Success:  summary.SuccessCount,
+			Failed:   summary.ErrorCount,
+			Timeouts: summary.TimeoutCount,
+		},
+		Rates: JSONRates{
+			RPS:         summary.RPS,
+			SuccessRate: summary.SuccessRate,
+			ErrorRate:   summary.ErrorRate,
+		},
+		Latency: JSONLatency{
+			P50:    summary.P50.Milliseconds(),
+			P95:    summary.P95.Milliseconds(),
+			P99:    summary.P99.Milliseconds(),
+			Min:    summary.Min.Milliseconds(),
+			Max:    summary.Max.Milliseconds(),
+			Mean:   summary.Mean.Milliseconds(),
+			StdDev: summary.StdDev.Milliseconds(),
+		},
+		Connections: JSONConnections{
+			New:       summary.NewConns,
+			Reused:    summary.ReusedConns,
+			ReuseRate: summary.ConnReuseRate,
+			PeakOpen:  summary.PeakOpenConns,
+		},
+	}
+
+	if summary.AssertionSamples > 0 {
+		result.Assertions = &JSONAssertions{
+			Sampled: summary.AssertionSamples,
+			Failed:  summary.AssertionFailures,
+		}
+	}
+
+	for _, tr := range thresholdResults {
+		result.Thresholds = append(result.Thresholds, JSONThreshold{
+			Name:     tr.Name,
+			Passed:   tr.Passed,
+			Expected: tr.Expected,
+			Actual:   tr.Actual,
+		})
+		if !tr.Passed {
+			result.Passed = false
+		}
+	}
+
+	if len(summary.RequestBreakdown) > 0 {
+		result.RequestBreakdown = make(map[string]JSONRequestSummary, len(summary.RequestBreakdown))
+		for name, rs := range summary.RequestBreakdown {
+			result.RequestBreakdown[name] = JSONRequestSummary{
+				Total:   rs.Total,
+				Success: rs.Success,
+				Errors:  rs.Errors,
+				P50:     rs.P50.Milliseconds(),
+				P95:     rs.P95.Milliseconds(),
+				P99:     rs.P99.Milliseconds(),
+				Mean:    rs.Mean.Milliseconds(),
+			}
+		}
+	}
+
+	if timeSeries {
+		for _, tp := range summary.TimeSeries {
+			result.TimeSeries = append(result.TimeSeries, JSONTimePoint{
+				Timestamp: tp.Timestamp,
+				Requests:  tp.Requests,
+				Errors:    tp.Errors,
+				P50:       tp.P50.Milliseconds(),
+				P95:       tp.P95.Milliseconds(),
+				P99:       tp.P99.Milliseconds(),
+				ActiveVUs: tp.ActiveVUs,
+				RPS:       tp.RPS,
+				OpenConns: tp.OpenConns,
+			})
+		}
+	}
+
+	return result
+}
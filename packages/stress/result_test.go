@@ -0,0 +1,49 @@
+package stress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func TestJSONResultMatchesSchema(t *testing.T) {
+	m := NewMetrics()
+	m.Start()
+	m.Record("getUser", 100*time.Millisecond, nil)
+	m.Record("getUser", 150*time.Millisecond, errors.New("boom"))
+	m.RecordAssertions(false)
+	m.AddTimePoint(m.Snapshot())
+	m.Stop()
+
+	thresholds := []ThresholdResult{{Name: "p95", Passed: false, Expected: "< 100ms", Actual: "150ms"}}
+
+	for _, timeSeries := range []bool{false, true} {
+		var buf bytes.Buffer
+		reporter := NewReporter(WithWriter(&buf))
+		require.NoError(t, reporter.JSONSummary(m.GetSummary(), thresholds, timeSeries))
+
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(ResultSchema), gojsonschema.NewBytesLoader(buf.Bytes()))
+		require.NoError(t, err)
+		assert.True(t, result.Valid(), "%v", result.Errors())
+
+		var out JSONResult
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		assert.Equal(t, ResultSchemaVersion, out.SchemaVersion)
+		assert.False(t, out.Passed)
+		assert.Equal(t, int64(2), out.Requests.Total)
+		assert.Equal(t, int64(1), out.Requests.Failed)
+		assert.Contains(t, out.RequestBreakdown, "getUser")
+		assert.Equal(t, &JSONAssertions{Sampled: 1, Failed: 1}, out.Assertions)
+		if timeSeries {
+			assert.Len(t, out.TimeSeries, 1)
+		} else {
+			assert.Empty(t, out.TimeSeries)
+		}
+	}
+}
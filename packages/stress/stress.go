@@ -3,10 +3,12 @@ package stress
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/abdul-hamid-achik/hitspec/packages/assertions"
 	"github.com/abdul-hamid-achik/hitspec/packages/capture"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/env"
 	"github.com/abdul-hamid-achik/hitspec/packages/core/parser"
@@ -32,6 +34,9 @@ type Runner struct {
 	envName    string
 	envFile    string
 	configEnvs map[string]map[string]any
+	variables  map[string]string
+	envVars    map[string]any // Environment and --var values, for the header
+	noEnv      bool           // Ignore environments, dotenv files and the process environment
 
 	// Parsed requests with their base directories
 	requests     []requestWithBaseDir
@@ -87,6 +92,21 @@ func WithConfigEnvironments(envs map[string]map[string]any) RunnerOption {
 	}
 }
 
+// WithVariables sets variables that override file and environment values
+func WithVariables(vars map[string]string) RunnerOption {
+	return func(r *Runner) {
+		r.variables = vars
+	}
+}
+
+// WithNoEnv ignores environments, dotenv files and the process environment, so
+// only file variables and WithVariables values are used
+func WithNoEnv(noEnv bool) RunnerOption {
+	return func(r *Runner) {
+		r.noEnv = noEnv
+	}
+}
+
 // NewRunner creates a new stress test runner
 func NewRunner(config *Config, opts ...RunnerOption) *Runner {
 	r := &Runner{
@@ -103,6 +123,7 @@ func NewRunner(config *Config, opts ...RunnerOption) *Runner {
 	if r.client == nil {
 		r.client = http.NewClient()
 	}
+	r.client.SetConnObserver(r.metrics)
 
 	if r.resolver == nil {
 		r.resolver = env.NewResolver()
@@ -112,6 +133,10 @@ func NewRunner(config *Config, opts ...RunnerOption) *Runner {
 		r.reporter = NewReporter()
 	}
 
+	if r.noEnv {
+		r.resolver.SetHermetic(true)
+	}
+
 	return r
 }
 
@@ -150,19 +175,23 @@ func (r *Runner) loadFile(path string) error {
 	r.loadedFiles = append(r.loadedFiles, path)
 
 	// Load dotenv file if specified (only once, on first file)
-	if len(r.loadedFiles) == 1 && r.envFile != "" {
+	if len(r.loadedFiles) == 1 && r.envFile != "" && !r.noEnv {
 		if err := r.resolver.LoadDotEnv(r.envFile); err != nil {
 			r.reporter.Info("warning: failed to load env file: %v", err)
 		}
 	}
 
 	// Load environment variables - pass config environments for proper resolution
-	environment, err := env.LoadEnvironment(baseDir, r.envName, r.configEnvs)
-	if err != nil {
-		// Non-fatal, just log it
-		r.reporter.Info("warning: failed to load environment: %v", err)
-	} else {
-		r.resolver.SetVariables(environment.Variables)
+	if !r.noEnv {
+		environment, err := env.LoadEnvironment(baseDir, r.envName, r.configEnvs)
+		if err != nil {
+			// Non-fatal, just log it
+			r.reporter.Info("warning: failed to load environment: %v", err)
+		} else {
+			r.resolver.SetDotEnvLayers(environment.DotEnv)
+			r.resolver.SetVariables(environment.Variables)
+			r.envVars = env.MergeVariables(r.envVars, environment.Variables)
+		}
 	}
 
 	// Set file variables
@@ -170,6 +199,12 @@ func (r *Runner) loadFile(path string) error {
 		r.resolver.SetVariable(v.Name, v.Value)
 	}
 
+	// Apply --var overrides
+	for k, v := range r.variables {
+		r.resolver.SetVariable(k, v)
+		r.envVars = env.MergeVariables(r.envVars, map[string]any{k: v})
+	}
+
 	// Categorize requests
 	for _, req := range file.Requests {
 		cfg := r.getRequestConfig(req)
@@ -230,6 +265,7 @@ func (r *Runner) Run(ctx context.Context) (*Result, error) {
 
 	// Print header
 	r.reporter.Header("", r.loadedFiles, r.config)
+	r.reporter.Environment(r.envName, r.envVars)
 
 	// Run setup requests
 	if len(r.setupReqs) > 0 {
@@ -402,22 +438,35 @@ func (r *Runner) runVUMode(ctx context.Context) {
 	pool.Wait()
 }
 
+// requestResolver returns the resolver of a request: a copy with its @var
+// variables when it declares any, so they don't leak into other requests
+func (r *Runner) requestResolver(req *parser.Request) *env.Resolver {
+	if len(req.Variables) == 0 {
+		return r.resolver
+	}
+	scope := r.resolver.Clone()
+	for _, v := range req.Variables {
+		scope.SetVariable(v.Name, scope.Resolve(v.Value))
+	}
+	return scope
+}
+
 // hasUnresolvedVariables checks if a request has any unresolved template variables
 // Returns true and the list of unresolved variable names if any are found
-func (r *Runner) hasUnresolvedVariables(req *parser.Request) (bool, []string) {
+func (r *Runner) hasUnresolvedVariables(req *parser.Request, resolver *env.Resolver) (bool, []string) {
 	// Check URL
-	if vars := r.resolver.GetUnresolvedVariables(req.URL); len(vars) > 0 {
+	if vars := resolver.GetUnresolvedVariables(req.URL); len(vars) > 0 {
 		return true, vars
 	}
 	// Check headers
 	for _, h := range req.Headers {
-		if vars := r.resolver.GetUnresolvedVariables(h.Value); len(vars) > 0 {
+		if vars := resolver.GetUnresolvedVariables(h.Value); len(vars) > 0 {
 			return true, vars
 		}
 	}
 	// Check body
 	if req.Body != nil && req.Body.Raw != "" {
-		if vars := r.resolver.GetUnresolvedVariables(req.Body.Raw); len(vars) > 0 {
+		if vars := resolver.GetUnresolvedVariables(req.Body.Raw); len(vars) > 0 {
 			return true, vars
 		}
 	}
@@ -431,9 +480,10 @@ func (r *Runner) executeScheduledRequest(ctx context.Context, sched *ScheduledRe
 	}
 
 	reqWithDir := r.requests[sched.Index]
+	resolver := r.requestResolver(reqWithDir.request)
 
 	// Skip requests with unresolved variables instead of sending literal {{var}} strings
-	if hasUnresolved, vars := r.hasUnresolvedVariables(reqWithDir.request); hasUnresolved {
+	if hasUnresolved, vars := r.hasUnresolvedVariables(reqWithDir.request, resolver); hasUnresolved {
 		err := fmt.Errorf("unresolved variables: %v", vars)
 		r.metrics.Record(sched.Name, 0, err)
 		return err
@@ -442,7 +492,7 @@ func (r *Runner) executeScheduledRequest(ctx context.Context, sched *ScheduledRe
 	start := time.Now()
 
 	// Build HTTP request using the request's own base directory
-	httpReq := http.BuildRequestFromASTWithBaseDir(reqWithDir.request, r.resolver.Resolve, reqWithDir.baseDir)
+	httpReq := http.BuildRequestFromASTWithBaseDir(reqWithDir.request, resolver.Resolve, reqWithDir.baseDir)
 
 	// Execute request
 	resp, err := r.client.Do(httpReq)
@@ -459,28 +509,75 @@ func (r *Runner) executeScheduledRequest(ctx context.Context, sched *ScheduledRe
 		return err
 	}
 
-	// Check if response indicates an error
+	// Check if response indicates an error. On a sampled response, an
+	// expect status assertion decides the status instead of @expect-status.
+	sampled := r.config.AssertSample > 0 && rand.Float64() < r.config.AssertSample
 	var recordErr error
-	if !resp.IsSuccess() {
+	if !(sampled && assertsStatus(reqWithDir.request)) && !reqWithDir.request.Metadata.AcceptsStatus(resp.StatusCode) {
 		recordErr = fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
+	if recordErr == nil && sampled {
+		recordErr = r.checkAssertions(reqWithDir, resp, resolver)
+	}
 
 	r.metrics.Record(sched.Name, duration, recordErr)
 	return recordErr
 }
 
+// assertsStatus reports whether req has an expect status assertion
+func assertsStatus(req *parser.Request) bool {
+	for _, a := range req.Assertions {
+		if a.Subject == "status" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAssertions evaluates the assertions of a sampled response and returns
+// the first that failed. Snapshot assertions are left out, since a stress
+// test doesn't keep snapshots. The error names the assertion without its
+// values, so failures of one assertion are counted together.
+func (r *Runner) checkAssertions(reqWithDir requestWithBaseDir, resp *http.Response, resolver *env.Resolver) error {
+	var list []*parser.Assertion
+	for _, a := range reqWithDir.request.Assertions {
+		if a.Operator != parser.OpSnapshot {
+			list = append(list, a)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	if reqWithDir.request.Metadata != nil && reqWithDir.request.Metadata.NDJSON {
+		resp.NDJSON = true
+	}
+
+	results := assertions.EvaluateAllWithBaseDir(resp, assertions.Resolve(list, resolver.Resolve), reqWithDir.baseDir)
+	for _, result := range results {
+		if !result.Passed {
+			r.metrics.RecordAssertions(false)
+			return fmt.Errorf("assertion failed: %s %s", result.Subject, result.Operator)
+		}
+	}
+	r.metrics.RecordAssertions(true)
+	return nil
+}
+
 // executeRequest executes a single request (for setup/teardown)
 func (r *Runner) executeRequest(ctx context.Context, reqWithDir requestWithBaseDir) error {
-	httpReq := http.BuildRequestFromASTWithBaseDir(reqWithDir.request, r.resolver.Resolve, reqWithDir.baseDir)
+	httpReq := http.BuildRequestFromASTWithBaseDir(reqWithDir.request, r.requestResolver(reqWithDir.request).Resolve, reqWithDir.baseDir)
 
 	resp, err := r.client.Do(httpReq)
 	if err != nil {
 		return err
 	}
 
-	if !resp.IsSuccess() {
+	if !reqWithDir.request.Metadata.AcceptsStatus(resp.StatusCode) {
 		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
+	if reqWithDir.request.Metadata != nil && reqWithDir.request.Metadata.NDJSON {
+		resp.NDJSON = true
+	}
 
 	// Extract captures from setup responses so they can be used by subsequent requests
 	if len(reqWithDir.request.Captures) > 0 {
@@ -1,7 +1,9 @@
 package stress
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -130,6 +132,125 @@ expect status 200
 	assert.Equal(t, result.Summary.TotalRequests, result.Summary.ErrorCount, "all requests should be errors")
 }
 
+func TestRunnerAssertSample(t *testing.T) {
+	// Succeeds with a body that breaks the request's contract
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status": "degraded"}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	httpFile := filepath.Join(tmpDir, "test.http")
+	content := `@baseUrl = ` + server.URL + `
+@expected = ok
+
+### Health
+GET {{baseUrl}}/health
+
+>>>
+expect status 200
+expect body.status == {{expected}}
+<<<
+`
+	require.NoError(t, os.WriteFile(httpFile, []byte(content), 0644))
+
+	for _, sample := range []float64{0, 1} {
+		cfg := &Config{
+			Mode:         RateMode,
+			Duration:     500 * time.Millisecond,
+			Rate:         10,
+			MaxVUs:       5,
+			AssertSample: sample,
+		}
+		runner := NewRunner(cfg, WithReporter(NewReporter(WithWriter(io.Discard), WithNoProgress(true))))
+		require.NoError(t, runner.LoadFile(httpFile))
+
+		result, err := runner.Run(context.Background())
+		require.NoError(t, err)
+		require.Positive(t, result.Summary.TotalRequests)
+
+		if sample == 0 {
+			assert.Zero(t, result.Summary.ErrorCount, "assertions aren't checked without sampling")
+			assert.Zero(t, result.Summary.AssertionSamples)
+		} else {
+			assert.Equal(t, result.Summary.TotalRequests, result.Summary.ErrorCount, "every sampled response fails its assertions")
+			assert.Equal(t, result.Summary.TotalRequests, result.Summary.AssertionSamples)
+			assert.Equal(t, result.Summary.TotalRequests, result.Summary.AssertionFailures)
+		}
+	}
+
+	assert.Error(t, (&Config{Mode: RateMode, Duration: time.Second, Rate: 1, MaxVUs: 1, AssertSample: 1.5}).Validate())
+}
+
+func TestRunnerAssertSampleStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "not found", "id": 7}`))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	httpFile := filepath.Join(tmpDir, "test.http")
+	content := `### Missing
+GET ` + server.URL + `/missing
+
+>>>
+expect status 404
+expect body.error == "not found"
+<<<
+`
+	require.NoError(t, os.WriteFile(httpFile, []byte(content), 0644))
+
+	for _, sample := range []float64{0, 1} {
+		cfg := &Config{
+			Mode:         RateMode,
+			Duration:     500 * time.Millisecond,
+			Rate:         10,
+			MaxVUs:       5,
+			AssertSample: sample,
+		}
+		runner := NewRunner(cfg, WithReporter(NewReporter(WithWriter(io.Discard), WithNoProgress(true))))
+		require.NoError(t, runner.LoadFile(httpFile))
+
+		result, err := runner.Run(context.Background())
+		require.NoError(t, err)
+		require.Positive(t, result.Summary.TotalRequests)
+
+		if sample == 0 {
+			assert.Equal(t, result.Summary.TotalRequests, result.Summary.ErrorCount, "404 isn't accepted without @expect-status")
+		} else {
+			assert.Zero(t, result.Summary.ErrorCount, "expect status 404 decides the status of sampled responses")
+			assert.Equal(t, result.Summary.TotalRequests, result.Summary.AssertionSamples)
+		}
+	}
+}
+
+func TestRunnerAssertionErrorsAreStable(t *testing.T) {
+	tmpDir := t.TempDir()
+	httpFile := filepath.Join(tmpDir, "test.http")
+	content := `### Item
+GET http://localhost/item
+
+>>>
+expect body.id == 1
+<<<
+`
+	require.NoError(t, os.WriteFile(httpFile, []byte(content), 0644))
+
+	runner := NewRunner(&Config{Mode: RateMode, Duration: time.Second, Rate: 1, MaxVUs: 1, AssertSample: 1})
+	require.NoError(t, runner.LoadFile(httpFile))
+	req := runner.requests[0]
+
+	for _, body := range []string{`{"id": 2}`, `{"id": 3}`} {
+		resp := &hithttp.Response{StatusCode: 200, Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(body)}
+		err := runner.checkAssertions(req, resp, runner.resolver)
+		require.Error(t, err)
+		assert.Equal(t, "assertion failed: body.id ==", err.Error())
+	}
+}
+
 func TestRunnerWithThresholds(t *testing.T) {
 	// Create a fast test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -456,6 +577,46 @@ expect status 200
 	t.Logf("Success: %d, Errors: %d", result.Summary.SuccessCount, result.Summary.ErrorCount)
 }
 
+func TestRunnerBodyPartials(t *testing.T) {
+	var bodies atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies.Store(string(body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "user.json"), []byte(`{"name": "{{name}}"}`+"\n"), 0644))
+	httpFile := filepath.Join(tmpDir, "test.http")
+	content := `@baseUrl = ` + server.URL + `
+@name = ada
+
+### Create user
+POST {{baseUrl}}/users
+Content-Type: application/json
+
+{"user": {{> ./user.json}}}
+`
+	require.NoError(t, os.WriteFile(httpFile, []byte(content), 0644))
+
+	cfg := &Config{
+		Mode:     RateMode,
+		Duration: 500 * time.Millisecond,
+		Rate:     10,
+		MaxVUs:   5,
+	}
+	runner := NewRunner(cfg, WithReporter(NewReporter(WithWriter(io.Discard), WithNoProgress(true))))
+	require.NoError(t, runner.LoadFile(httpFile))
+
+	result, err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	require.Positive(t, result.Summary.TotalRequests)
+	assert.Zero(t, result.Summary.ErrorCount, "a partial isn't an unresolved variable")
+	assert.JSONEq(t, `{"user": {"name": "ada"}}`, bodies.Load().(string))
+}
+
 func TestRunnerSkipsUnresolvedVariables(t *testing.T) {
 	// Create a test server - it should not receive any requests with unresolved variables
 	var receivedRequests []string
@@ -509,3 +670,23 @@ expect status 200
 
 	t.Logf("Skipped %d requests with unresolved variables", result.Summary.ErrorCount)
 }
+
+func TestReporterEnvironmentMasksSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewReporter(WithWriter(&buf), WithNoColor(true), WithShowEnvironment(true))
+
+	reporter.Environment("staging", map[string]any{
+		"baseUrl":  "https://staging.example.com",
+		"apiToken": "super-secret",
+	})
+
+	out := buf.String()
+	assert.Contains(t, out, "Environment: staging")
+	assert.Contains(t, out, "baseUrl = https://staging.example.com")
+	assert.Contains(t, out, "apiToken = ****")
+	assert.NotContains(t, out, "super-secret")
+
+	buf.Reset()
+	NewReporter(WithWriter(&buf)).Environment("staging", map[string]any{"baseUrl": "x"})
+	assert.Empty(t, buf.String(), "environment is only printed with WithShowEnvironment")
+}